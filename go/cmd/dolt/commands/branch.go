@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions"
 	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
 	"github.com/dolthub/dolt/go/libraries/utils/argparser"
+	"github.com/dolthub/dolt/go/libraries/utils/editor"
 	"github.com/dolthub/dolt/go/libraries/utils/set"
 )
 
@@ -46,19 +48,27 @@ With a {{.EmphasisLeft}}-m{{.EmphasisRight}}, {{.LessThan}}oldbranch{{.GreaterTh
 
 The {{.EmphasisLeft}}-c{{.EmphasisRight}} options have the exact same semantics as {{.EmphasisLeft}}-m{{.EmphasisRight}}, except instead of the branch being renamed it will be copied to a new name.
 
-With a {{.EmphasisLeft}}-d{{.EmphasisRight}}, {{.LessThan}}branchname{{.GreaterThan}} will be deleted. You may specify more than one branch for deletion.`,
+With a {{.EmphasisLeft}}-d{{.EmphasisRight}}, {{.LessThan}}branchname{{.GreaterThan}} will be deleted. You may specify more than one branch for deletion, and shell patterns such as {{.EmphasisLeft}}'feature/*'{{.EmphasisRight}} to delete every branch they match; as in the shell, {{.EmphasisLeft}}*{{.EmphasisRight}} and {{.EmphasisLeft}}?{{.EmphasisRight}} don't match {{.EmphasisLeft}}/{{.EmphasisRight}}. If any of the branches can't be deleted, none are, and the others are deleted together.
+
+With {{.EmphasisLeft}}--edit-description{{.EmphasisRight}}, an editor is opened to edit the description of {{.LessThan}}branchname{{.GreaterThan}}, or of the current branch if none is given. An empty description removes it. Descriptions are shown in the {{.EmphasisLeft}}description{{.EmphasisRight}} column of the {{.EmphasisLeft}}dolt_branches{{.EmphasisRight}} system table, follow a branch when it's renamed or copied, and are removed when it's deleted. Like upstream tracking configuration, they are local to this repository and are not pushed.
+
+With {{.EmphasisLeft}}--prune-stale{{.EmphasisRight}}, every branch whose latest commit is older than its expiry age, and which is fully merged, is deleted. A branch with an upstream must be merged into it, and a branch without one into the current branch, as with {{.EmphasisLeft}}-d{{.EmphasisRight}}. The expiry age is set with {{.EmphasisLeft}}dolt config --add branch.expireafter 30d{{.EmphasisRight}}, and can be overridden for a single branch with {{.EmphasisLeft}}branch.{{.LessThan}}branchname{{.GreaterThan}}.expireafter{{.EmphasisRight}}. Ages are durations such as {{.EmphasisLeft}}36h{{.EmphasisRight}}, {{.EmphasisLeft}}30d{{.EmphasisRight}} or {{.EmphasisLeft}}2w{{.EmphasisRight}}, or {{.EmphasisLeft}}never{{.EmphasisRight}}.`,
 	Synopsis: []string{
 		`[--list] [-v] [-a] [-r]`,
 		`[-f] {{.LessThan}}branchname{{.GreaterThan}} [{{.LessThan}}start-point{{.GreaterThan}}]`,
 		`-m [-f] [{{.LessThan}}oldbranch{{.GreaterThan}}] {{.LessThan}}newbranch{{.GreaterThan}}`,
 		`-c [-f] [{{.LessThan}}oldbranch{{.GreaterThan}}] {{.LessThan}}newbranch{{.GreaterThan}}`,
 		`-d [-f] [-r] {{.LessThan}}branchname{{.GreaterThan}}...`,
+		`--edit-description [{{.LessThan}}branchname{{.GreaterThan}}]`,
+		`--prune-stale`,
 	},
 }
 
 const (
-	datasetsFlag    = "datasets"
-	showCurrentFlag = "show-current"
+	datasetsFlag        = "datasets"
+	showCurrentFlag     = "show-current"
+	editDescriptionFlag = "edit-description"
+	pruneStaleFlag      = "prune-stale"
 )
 
 var ErrUnmergedBranchDelete = errors.New("The branch '%s' is not fully merged.\nIf you are sure you want to delete it, run 'dolt branch -D %s'.")
@@ -89,6 +99,8 @@ func (cmd BranchCmd) ArgParser() *argparser.ArgParser {
 	ap.SupportsFlag(datasetsFlag, "", "List all datasets in the database")
 	ap.SupportsFlag(cli.RemoteParam, "r", "When in list mode, show only remote tracked branches. When with -d, delete a remote tracking branch.")
 	ap.SupportsFlag(showCurrentFlag, "", "Print the name of the current branch")
+	ap.SupportsFlag(editDescriptionFlag, "", "Open an editor to edit the description of the branch")
+	ap.SupportsFlag(pruneStaleFlag, "", "Delete fully merged branches older than their configured expiry age")
 	return ap
 }
 
@@ -112,6 +124,10 @@ func (cmd BranchCmd) Exec(ctx context.Context, commandStr string, args []string,
 		return deleteBranches(ctx, dEnv, apr, usage, apr.Contains(cli.ForceFlag))
 	case apr.Contains(cli.DeleteForceFlag):
 		return deleteBranches(ctx, dEnv, apr, usage, true)
+	case apr.Contains(editDescriptionFlag):
+		return editBranchDescription(ctx, dEnv, apr, usage)
+	case apr.Contains(pruneStaleFlag):
+		return pruneStaleBranches(ctx, dEnv, apr, usage)
 	case apr.Contains(cli.ListFlag):
 		return printBranches(ctx, dEnv, apr, usage)
 	case apr.Contains(showCurrentFlag):
@@ -307,28 +323,150 @@ func deleteBranches(ctx context.Context, dEnv *env.DoltEnv, apr *argparser.ArgPa
 		return 1
 	}
 
-	for i := 0; i < apr.NArg(); i++ {
-		brName := apr.Arg(i)
+	// branches that share an upstream are validated against the same remote database
+	remoteDbs := actions.NewRemoteDbCache(dEnv)
+	defer remoteDbs.Close()
+
+	// arguments may be patterns, e.g. 'feature/*', and every branch they match is validated before any is deleted
+	_, err := actions.DeleteBranches(ctx, dEnv.DbData(), apr.Args, actions.DeleteOptions{
+		Force:  force,
+		Remote: apr.Contains(cli.RemoteParam),
+	}, remoteDbs, nil)
+
+	if err != nil {
+		brName := apr.Arg(0)
+		var deleteErr actions.BranchDeleteError
+		if errors.As(err, &deleteErr) {
+			brName = deleteErr.Branch
+		}
+
+		var verr errhand.VerboseError
+		if errors.Is(err, doltdb.ErrBranchNotFound) {
+			verr = errhand.BuildDError("fatal: branch '%s' not found", brName).Build()
+		} else if errors.Is(err, actions.ErrUnmergedBranch) {
+			verr = errhand.BuildDError(ErrUnmergedBranchDelete.Error(), brName, brName).Build()
+		} else if errors.Is(err, actions.ErrCOBranchDelete) {
+			verr = errhand.BuildDError("error: Cannot delete checked out branch '%s'", brName).Build()
+		} else if errors.Is(err, actions.ErrBranchesChanged) {
+			verr = errhand.BuildDError("fatal: a branch was updated while deleting, so none were deleted; try again").Build()
+		} else {
+			bdr := errhand.BuildDError("fatal: Unexpected error deleting '%s'", brName)
+			verr = bdr.AddCause(err).Build()
+		}
+		return HandleVErrAndExitCode(verr, usage)
+	}
+
+	return HandleVErrAndExitCode(nil, usage)
+}
 
-		err := actions.DeleteBranch(ctx, dEnv.DbData(), brName, actions.DeleteOptions{
-			Force:  force,
-			Remote: apr.Contains(cli.RemoteParam),
-		}, dEnv, nil)
+func editBranchDescription(ctx context.Context, dEnv *env.DoltEnv, apr *argparser.ArgParseResults, usage cli.UsagePrinter) int {
+	if apr.NArg() > 1 {
+		usage()
+		return 1
+	}
 
+	var brName string
+	if apr.NArg() == 1 {
+		brName = apr.Arg(0)
+	} else {
+		headRef, err := dEnv.RepoStateReader().CWBHeadRef()
 		if err != nil {
-			var verr errhand.VerboseError
-			if err == doltdb.ErrBranchNotFound {
-				verr = errhand.BuildDError("fatal: branch '%s' not found", brName).Build()
-			} else if err == actions.ErrUnmergedBranch {
-				verr = errhand.BuildDError(ErrUnmergedBranchDelete.Error(), brName, brName).Build()
-			} else if err == actions.ErrCOBranchDelete {
-				verr = errhand.BuildDError("error: Cannot delete checked out branch '%s'", brName).Build()
-			} else {
-				bdr := errhand.BuildDError("fatal: Unexpected error deleting '%s'", brName)
-				verr = bdr.AddCause(err).Build()
-			}
-			return HandleVErrAndExitCode(verr, usage)
+			return HandleVErrAndExitCode(errhand.BuildDError("fatal: could not determine the current branch").AddCause(err).Build(), usage)
 		}
+		brName = headRef.GetPath()
+	}
+
+	// check before opening the editor, so that a mistyped name doesn't cost the user their edits
+	isBranch, err := actions.IsBranch(ctx, dEnv.DoltDB, brName)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: failed to read refs from db").AddCause(err).Build(), usage)
+	} else if !isBranch {
+		return HandleVErrAndExitCode(errhand.BuildDError("fatal: branch '%s' not found", brName).Build(), usage)
+	}
+
+	dbData := dEnv.DbData()
+	description, err := actions.GetBranchDescription(dbData, brName)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("fatal: unable to read the description of branch '%s'", brName).AddCause(err).Build(), usage)
+	}
+
+	description, err = getBranchDescriptionFromEditor(dEnv, brName, description)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.VerboseErrorFromError(err), usage)
+	}
+
+	err = actions.SetBranchDescription(ctx, dbData, brName, description)
+
+	var verr errhand.VerboseError
+	if err != nil {
+		if errors.Is(err, doltdb.ErrBranchNotFound) {
+			verr = errhand.BuildDError("fatal: branch '%s' not found", brName).Build()
+		} else {
+			bdr := errhand.BuildDError("fatal: Unexpected error editing the description of branch '%s'", brName)
+			verr = bdr.AddCause(err).Build()
+		}
+	}
+
+	return HandleVErrAndExitCode(verr, usage)
+}
+
+// getBranchDescriptionFromEditor opens an editor for the user to edit |description|, the current description of
+// |brName|, and returns the edited description with comment lines and surrounding whitespace removed.
+func getBranchDescriptionFromEditor(dEnv *env.DoltEnv, brName, description string) (string, error) {
+	if cli.ExecuteWithStdioRestored == nil || !checkIsTerminal() {
+		return "", errors.New("fatal: --edit-description requires a terminal to open an editor in")
+	}
+
+	if description != "" {
+		description += "\n"
+	}
+	initialMsg := fmt.Sprintf("%s# Please edit the description for the branch\n#   %s\n"+
+		"# Lines starting with '#' will be stripped.\n", description, brName)
+
+	backupEd := "vim"
+	if ed, edSet := os.LookupEnv("EDITOR"); edSet {
+		backupEd = ed
+	}
+	editorStr := dEnv.Config.GetStringOrDefault(env.DoltEditor, backupEd)
+
+	var edited string
+	var err error
+	cli.ExecuteWithStdioRestored(func() {
+		edited, err = editor.OpenCommitEditor(editorStr, initialMsg)
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to open editor: %v \n Check your `EDITOR` environment variable with `echo $EDITOR` or your dolt config with `dolt config --list` to ensure that your editor is valid", err)
+	}
+
+	return strings.TrimSpace(parseCommitMessage(edited)), nil
+}
+
+func pruneStaleBranches(ctx context.Context, dEnv *env.DoltEnv, apr *argparser.ArgParseResults, usage cli.UsagePrinter) int {
+	if apr.NArg() != 0 {
+		usage()
+		return 1
+	}
+
+	policy, err := actions.BranchExpiryPolicyFromConfig(dEnv.Config)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("fatal: unable to read the branch expiry config").AddCause(err).Build(), usage)
+	} else if policy.ExpireAfter == 0 && len(policy.Overrides) == 0 {
+		return HandleVErrAndExitCode(errhand.BuildDError("fatal: no branch expiry age is configured. Set one with 'dolt config --local --add %s 30d'", env.BranchExpireAfterKey).Build(), usage)
+	}
+
+	// branches that share an upstream are validated against the same remote database
+	remoteDbs := actions.NewRemoteDbCache(dEnv)
+	defer remoteDbs.Close()
+
+	_, err = actions.PruneStaleBranches(ctx, dEnv.DbData(), policy, actions.PruneStaleOptions{
+		Progress: func(branch string, deleted bool) {
+			if deleted {
+				cli.Printf("Deleted stale branch %s\n", branch)
+			}
+		},
+	}, remoteDbs, nil)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("fatal: Unexpected error pruning stale branches").AddCause(err).Build(), usage)
 	}
 
 	return HandleVErrAndExitCode(nil, usage)
@@ -66,6 +66,8 @@ type SqlEngineConfig struct {
 	JwksConfig              []JwksConfig
 	ClusterController       *cluster.Controller
 	BinlogReplicaController binlogreplication.BinlogReplicaController
+	// CacheConfig configures the caches of every session of the engine
+	CacheConfig dsess.CacheConfig
 }
 
 // NewSqlEngine returns a SqlEngine
@@ -172,7 +174,7 @@ func NewSqlEngine(
 		return nil, err
 	}
 
-	sessionFactory := doltSessionFactory(pro, mrEnv.Config(), bcController, config.Autocommit)
+	sessionFactory := doltSessionFactory(pro, mrEnv.Config(), bcController, config.Autocommit, config.CacheConfig)
 
 	if config.BinlogReplicaController != nil {
 		binLogSession, err := sessionFactory(sql.NewBaseSession(), pro)
@@ -287,9 +289,9 @@ func sqlContextFactory() contextFactory {
 }
 
 // doltSessionFactory returns a sessionFactory that creates a new DoltSession
-func doltSessionFactory(pro dsqle.DoltDatabaseProvider, config config.ReadWriteConfig, bc *branch_control.Controller, autocommit bool) sessionFactory {
+func doltSessionFactory(pro dsqle.DoltDatabaseProvider, config config.ReadWriteConfig, bc *branch_control.Controller, autocommit bool, cacheConfig dsess.CacheConfig) sessionFactory {
 	return func(mysqlSess *sql.BaseSession, provider sql.DatabaseProvider) (*dsess.DoltSession, error) {
-		doltSession, err := dsess.NewDoltSession(mysqlSess, pro, config, bc)
+		doltSession, err := dsess.NewDoltSession(mysqlSess, pro, config, bc, cacheConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,146 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// chunkWalkBatchSize is the number of chunks requested from the chunk store at a time when walking reachable chunks
+const chunkWalkBatchSize = 4096
+
+// UniquelyReachableChunks returns the number of chunks, and their total size in bytes, which are reachable from the
+// datasets named by |refs| but not from any other dataset in the database. These are the chunks that would become
+// garbage if all of |refs| were deleted. Refs that don't exist are ignored. This walks every chunk reachable from every
+// dataset, so it is expensive on large databases.
+func (ddb *DoltDB) UniquelyReachableChunks(ctx context.Context, refs []ref.DoltRef) (chunkCount, byteCount uint64, err error) {
+	targets := make(map[string]struct{}, len(refs))
+	for _, r := range refs {
+		targets[r.String()] = struct{}{}
+	}
+
+	dss, err := ddb.db.Datasets(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	var roots, excluded []hash.Hash
+	err = dss.IterAll(ctx, func(key string, addr hash.Hash) error {
+		if _, ok := targets[key]; ok {
+			roots = append(roots, addr)
+		} else {
+			excluded = append(excluded, addr)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cs := datas.ChunkStoreFromDatabase(ddb.db)
+	walkAddrs := types.WalkAddrsForNBF(ddb.Format())
+
+	// Everything reachable from the other datasets is visited first, without counting it. Leaf chunks have no refs, so
+	// they don't need to be loaded.
+	visited := make(hash.HashSet)
+	err = walkChunks(ctx, cs, walkAddrs, excluded, visited, false, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = walkChunks(ctx, cs, walkAddrs, roots, visited, true, func(c chunks.Chunk) {
+		chunkCount++
+		byteCount += uint64(len(c.Data()))
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return chunkCount, byteCount, nil
+}
+
+// walkChunks visits every chunk reachable from |roots| that isn't already in |visited|, adding each to |visited| and
+// calling |cb| with it. Leaf chunks are only loaded if |loadLeaves| is true. Chunks missing from |cs|, e.g. in a
+// shallow clone, are skipped.
+func walkChunks(
+	ctx context.Context,
+	cs chunks.ChunkStore,
+	walkAddrs func(chunks.Chunk, func(h hash.Hash, isleaf bool) error) error,
+	roots []hash.Hash,
+	visited hash.HashSet,
+	loadLeaves bool,
+	cb func(c chunks.Chunk),
+) error {
+	var pending []hash.Hash
+	for _, h := range roots {
+		if !visited.Has(h) {
+			visited.Insert(h)
+			pending = append(pending, h)
+		}
+	}
+
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch := pending
+		if len(batch) > chunkWalkBatchSize {
+			batch = batch[:chunkWalkBatchSize]
+		}
+		pending = pending[len(batch):]
+
+		// chunks may be delivered concurrently
+		var mu sync.Mutex
+		var next []hash.Hash
+		var walkErr error
+		err := cs.GetMany(ctx, hash.NewHashSet(batch...), func(ctx context.Context, c *chunks.Chunk) {
+			mu.Lock()
+			defer mu.Unlock()
+			if walkErr != nil {
+				return
+			}
+			if cb != nil {
+				cb(*c)
+			}
+			walkErr = walkAddrs(*c, func(h hash.Hash, isleaf bool) error {
+				if visited.Has(h) {
+					return nil
+				}
+				visited.Insert(h)
+				if isleaf && !loadLeaves {
+					return nil
+				}
+				next = append(next, h)
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if walkErr != nil {
+			return walkErr
+		}
+
+		pending = append(pending, next...)
+	}
+
+	return nil
+}
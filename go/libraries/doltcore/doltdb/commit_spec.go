@@ -15,10 +15,12 @@
 package doltdb
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
 )
 
 var hashRegex = regexp.MustCompile(`^[0-9a-v]{32}$`)
@@ -28,7 +30,67 @@ const head string = "head"
 // IsValidUserBranchName returns true if name isn't a valid commit hash, it is not named "head" and
 // it matches the regular expression `[0-9a-z]+[-_0-9a-z]*[0-9a-z]+$`
 func IsValidUserBranchName(name string) bool {
-	return name != head && !hashRegex.MatchString(name) && ref.IsValidBranchName(name)
+	return name != head && !LooksLikeCommitHash(name) && ref.IsValidBranchName(name)
+}
+
+// LooksLikeCommitHash returns true if |name| has the format and length of a commit hash. A branch with such a name
+// would be ambiguous with the commit when resolving a commit spec, which always prefers the commit.
+func LooksLikeCommitHash(name string) bool {
+	return hash.IsValid(name)
+}
+
+// SanitizeBranchName turns arbitrary text, such as a ticket title, into a name that passes IsValidUserBranchName. Path
+// segments separated by "/" are kept. Within each, characters other than ASCII letters, digits, "-", "_" and "." are
+// replaced with "-", runs of "-" and "." are collapsed, and separators are trimmed from both ends. The result is
+// deterministic. Returns an error wrapping ErrInvBranchName if no characters usable in a branch name remain.
+func SanitizeBranchName(input string) (string, error) {
+	var segments []string
+	for _, segment := range strings.Split(input, "/") {
+		segment = sanitizeBranchNameSegment(segment)
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	name := strings.Join(segments, "/")
+	if name == "" {
+		return "", fmt.Errorf("%w: no usable characters in '%s'", ErrInvBranchName, input)
+	}
+	if !IsValidUserBranchName(name) {
+		// reserved names and names that look like commit hashes
+		name = "branch-" + name
+	}
+	if !IsValidUserBranchName(name) {
+		return "", fmt.Errorf("%w: unable to sanitize '%s'", ErrInvBranchName, input)
+	}
+	return name, nil
+}
+
+func sanitizeBranchNameSegment(segment string) string {
+	var sb strings.Builder
+	var last rune
+	for _, ch := range segment {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9', ch == '_':
+		case ch == '.':
+			if last == '.' {
+				continue
+			}
+		default:
+			if last == '-' {
+				continue
+			}
+			ch = '-'
+		}
+		sb.WriteRune(ch)
+		last = ch
+	}
+
+	segment = strings.Trim(sb.String(), "-._")
+	for strings.HasSuffix(segment, ".lock") {
+		segment = strings.TrimSuffix(segment, ".lock") + "-lock"
+	}
+	return segment
 }
 
 // IsValidBranchRef validates that a BranchRef doesn't violate naming constraints.
@@ -36,6 +98,12 @@ func IsValidBranchRef(dref ref.DoltRef) bool {
 	return dref.GetType() == ref.BranchRefType && IsValidUserBranchName(dref.GetPath())
 }
 
+// isHashLikeBranchRef returns whether |dref| is a branch ref whose name is only invalid because it looks like a commit
+// hash.
+func isHashLikeBranchRef(dref ref.DoltRef) bool {
+	return dref.GetType() == ref.BranchRefType && LooksLikeCommitHash(dref.GetPath()) && ref.ValidateHashLikeBranchName(dref.GetPath()) == nil
+}
+
 // IsValidTagRef validates that a TagRef doesn't violate naming constraints.
 func IsValidTagRef(dref ref.DoltRef) bool {
 	s := dref.GetPath()
@@ -17,6 +17,9 @@ package doltdb
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/dolthub/dolt/go/libraries/utils/test"
 	"github.com/dolthub/dolt/go/store/hash"
 )
@@ -66,3 +69,41 @@ func TestNewCommitSpec(t *testing.T) {
 		}
 	}
 }
+
+func TestSanitizeBranchName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"feature", "feature"},
+		{"Fix the login bug!", "Fix-the-login-bug"},
+		{"  JIRA-123: crash on startup  ", "JIRA-123-crash-on-startup"},
+		{"team//feature/", "team/feature"},
+		{"..hidden..name..", "hidden.name"},
+		{"weird~^:?*[chars]", "weird-chars"},
+		{"résumé", "r-sum"},
+		{"config.lock", "config-lock"},
+		{"head", "branch-head"},
+		{"HEAD", "branch-HEAD"},
+		{"0123456789abcdefghijklmnopqrstuv", "branch-0123456789abcdefghijklmnopqrstuv"},
+		{"release/v1.0/@{bad}", "release/v1.0/bad"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			actual, err := SanitizeBranchName(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+			assert.True(t, IsValidUserBranchName(actual))
+
+			again, err := SanitizeBranchName(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, actual, again)
+		})
+	}
+
+	for _, input := range []string{"", "///", "!!!", "..."} {
+		_, err := SanitizeBranchName(input)
+		assert.ErrorIs(t, err, ErrInvBranchName, input)
+	}
+}
@@ -644,6 +644,35 @@ func (ddb *DoltDB) SetHead(ctx context.Context, ref ref.DoltRef, addr hash.Hash)
 	return err
 }
 
+// CompareAndSwapHead sets the head of the ref given to the commit with hash |newHead| if its head is currently
+// |expected|, and returns whether it did. The comparison and the update are made atomically by the storage layer, so of
+// several concurrent swaps from the same head, only one succeeds.
+func (ddb *DoltDB) CompareAndSwapHead(ctx context.Context, dref ref.DoltRef, expected, newHead hash.Hash, replicationStatus *ReplicationStatusController) (bool, error) {
+	ds, err := ddb.db.GetDataset(ctx, dref.String())
+	if err != nil {
+		return false, err
+	}
+	if current, _ := ds.MaybeHeadAddr(); current != expected {
+		return false, nil
+	}
+
+	commit, err := datas.LoadCommitAddr(ctx, ddb.vrw, newHead)
+	if err != nil {
+		return false, err
+	}
+
+	// WriteCommit only updates the dataset if its head is still the one in |ds|, which we checked is |expected|
+	_, err = ddb.db.withReplicationStatusController(replicationStatus).WriteCommit(ctx, ds, commit)
+	if err == datas.ErrMergeNeeded {
+		return false, nil
+	} else if err == datas.ErrAlreadyCommitted {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // CommitWithParentSpecs commits the value hash given to the branch given, using the list of parent hashes given. Returns an
 // error if the value or any parents can't be resolved, or if anything goes wrong accessing the underlying storage.
 func (ddb *DoltDB) CommitWithParentSpecs(ctx context.Context, valHash hash.Hash, dref ref.DoltRef, parentCmSpecs []*CommitSpec, cm *datas.CommitMeta) (*Commit, error) {
@@ -983,6 +1012,23 @@ func (ddb *DoltDB) GetHeadRefs(ctx context.Context) ([]ref.DoltRef, error) {
 	return ddb.GetRefsOfType(ctx, ref.HeadRefTypes)
 }
 
+// GetWorkingSetRefs returns the refs of all the working sets in this database
+func (ddb *DoltDB) GetWorkingSetRefs(ctx context.Context) ([]ref.WorkingSetRef, error) {
+	dss, err := ddb.db.Datasets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []ref.WorkingSetRef
+	err = dss.IterAll(ctx, func(key string, _ hash.Hash) error {
+		if ref.IsWorkingSet(key) {
+			refs = append(refs, ref.NewWorkingSetRef(key))
+		}
+		return nil
+	})
+	return refs, err
+}
+
 func (ddb *DoltDB) VisitRefsOfType(ctx context.Context, refTypeFilter map[ref.RefType]struct{}, visit func(r ref.DoltRef, addr hash.Hash) error) error {
 	dss, err := ddb.db.Datasets(ctx)
 	if err != nil {
@@ -1077,10 +1123,11 @@ func (ddb *DoltDB) GetRefsOfTypeByNomsRoot(ctx context.Context, refTypeFilter ma
 	return refs, err
 }
 
-// NewBranchAtCommit creates a new branch with HEAD at the commit given. Branch names must pass IsValidUserBranchName.
+// NewBranchAtCommit creates a new branch with HEAD at the commit given. Branch names must pass IsValidUserBranchName,
+// or look like a commit hash and otherwise be valid, for callers that explicitly allow such names.
 // Silently overwrites any existing branch with the same name given, if one exists.
 func (ddb *DoltDB) NewBranchAtCommit(ctx context.Context, branchRef ref.DoltRef, commit *Commit, replicationStatus *ReplicationStatusController) error {
-	if !IsValidBranchRef(branchRef) {
+	if !IsValidBranchRef(branchRef) && !isHashLikeBranchRef(branchRef) {
 		panic(fmt.Sprintf("invalid branch name %s, use IsValidUserBranchName check", branchRef.String()))
 	}
 
@@ -1163,6 +1210,38 @@ func (ddb *DoltDB) DeleteBranch(ctx context.Context, branch ref.DoltRef, replica
 	return ddb.deleteRef(ctx, branch, replicationStatus)
 }
 
+// DeleteBranchesAt deletes the branches or remote tracking branches given in a single update of the database, if each
+// is still at the matching head in |heads|, and returns whether it did. If any has moved or is gone, none is deleted.
+// Like DeleteBranch, it refuses to delete the last branch.
+func (ddb *DoltDB) DeleteBranchesAt(ctx context.Context, branches []ref.DoltRef, heads []hash.Hash, replicationStatus *ReplicationStatusController) (bool, error) {
+	toDelete := make(map[string]hash.Hash, len(branches))
+	localBranches := 0
+	for i, branch := range branches {
+		toDelete[branch.String()] = heads[i]
+		if branch.GetType() == ref.BranchRefType {
+			localBranches++
+		}
+	}
+
+	if localBranches > 0 {
+		all, err := ddb.GetBranches(ctx)
+		if err != nil {
+			return false, err
+		}
+		if localBranches >= len(all) {
+			return false, ErrCannotDeleteLastBranch
+		}
+	}
+
+	err := ddb.db.withReplicationStatusController(replicationStatus).DeleteDatasets(ctx, toDelete)
+	if err == datas.ErrMergeNeeded {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (ddb *DoltDB) deleteRef(ctx context.Context, dref ref.DoltRef, replicationStatus *ReplicationStatusController) error {
 	ds, err := ddb.db.GetDataset(ctx, dref.String())
 
@@ -1336,6 +1415,14 @@ func (ddb *DoltDB) DeleteTag(ctx context.Context, tag ref.DoltRef) error {
 	return err
 }
 
+// DeleteInternalRef deletes the internal ref given, returning ErrBranchNotFound if it doesn't exist.
+func (ddb *DoltDB) DeleteInternalRef(ctx context.Context, internalRef ref.DoltRef) error {
+	if internalRef.GetType() != ref.InternalRefType {
+		return fmt.Errorf("cannot delete ref %s: not an internal ref", internalRef.String())
+	}
+	return ddb.deleteRef(ctx, internalRef, nil)
+}
+
 // NewWorkspaceAtCommit create a new workspace at the commit given.
 func (ddb *DoltDB) NewWorkspaceAtCommit(ctx context.Context, workRef ref.DoltRef, c *Commit) error {
 	ds, err := ddb.db.GetDataset(ctx, workRef.String())
@@ -209,6 +209,46 @@ func TestEmptyInMemoryRepoCreation(t *testing.T) {
 	}
 }
 
+func TestDeleteBranchesAt(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, InMemDoltDB, filesys.LocalFS)
+	require.NoError(t, err)
+	defer ddb.Close()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse"))
+
+	mainRef := ref.NewBranchRef("main")
+	head, err := ddb.ResolveCommitRef(ctx, mainRef)
+	require.NoError(t, err)
+	h, err := head.HashOf()
+	require.NoError(t, err)
+	branches := []ref.DoltRef{ref.NewBranchRef("a"), ref.NewBranchRef("b")}
+	for _, branch := range branches {
+		require.NoError(t, ddb.NewBranchAtCommit(ctx, branch, head, nil))
+	}
+
+	// a branch that has moved keeps every branch from being deleted
+	ok, err := ddb.DeleteBranchesAt(ctx, branches, []hash.Hash{h, hash.Of([]byte("moved"))}, nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	for _, branch := range branches {
+		has, err := ddb.HasRef(ctx, branch)
+		require.NoError(t, err)
+		assert.True(t, has)
+	}
+
+	ok, err = ddb.DeleteBranchesAt(ctx, branches, []hash.Hash{h, h}, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	for _, branch := range branches {
+		has, err := ddb.HasRef(ctx, branch)
+		require.NoError(t, err)
+		assert.False(t, has)
+	}
+
+	_, err = ddb.DeleteBranchesAt(ctx, []ref.DoltRef{mainRef}, []hash.Hash{h}, nil)
+	assert.ErrorIs(t, err, ErrCannotDeleteLastBranch)
+}
+
 func TestLoadNonExistentLocalFSRepo(t *testing.T) {
 	_, err := test.ChangeToTestDir("TestLoadRepo")
 
@@ -21,6 +21,8 @@ import (
 )
 
 var ErrInvBranchName = errors.New("not a valid user branch name")
+var ErrBranchNameLooksLikeHash = errors.New("branch name looks like a commit hash")
+var ErrBranchNameCollision = errors.New("branch name is Unicode-equivalent to the name of an existing branch")
 var ErrInvWorkspaceName = errors.New("not a valid user workspace name")
 var ErrInvTagName = errors.New("not a valid user tag name")
 var ErrInvTableName = errors.New("not a valid table name")
@@ -63,7 +65,7 @@ func (e ErrClientOutOfDate) Error() string {
 
 func IsInvalidFormatErr(err error) bool {
 	switch err {
-	case ErrInvBranchName, ErrInvTableName, ErrInvHash, ErrInvalidAncestorSpec, ErrInvalidBranchOrHash:
+	case ErrInvBranchName, ErrBranchNameLooksLikeHash, ErrInvTableName, ErrInvHash, ErrInvalidAncestorSpec, ErrInvalidBranchOrHash:
 		return true
 	default:
 		return false
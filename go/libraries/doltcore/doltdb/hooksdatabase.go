@@ -179,6 +179,16 @@ func (db hooksDatabase) Delete(ctx context.Context, ds datas.Dataset) (datas.Dat
 	return ds, err
 }
 
+func (db hooksDatabase) DeleteDatasets(ctx context.Context, heads map[string]hash.Hash) error {
+	err := db.Database.DeleteDatasets(ctx, heads)
+	if err == nil {
+		for id := range heads {
+			db.ExecuteCommitHooks(ctx, datas.NewHeadlessDataset(db.Database, id), false)
+		}
+	}
+	return err
+}
+
 func (db hooksDatabase) UpdateWorkingSet(ctx context.Context, ds datas.Dataset, workingSet datas.WorkingSetSpec, prevHash hash.Hash) (datas.Dataset, error) {
 	ds, err := db.Database.UpdateWorkingSet(ctx, ds, workingSet, prevHash)
 	if err == nil {
@@ -0,0 +1,119 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// AncestryCache memoizes merge base computations between commits, so that an operation comparing many branches
+// doesn't walk the same history repeatedly. Commits are immutable, so results never go stale, but the cache grows
+// without bound and should be scoped to a single operation rather than kept around. An AncestryCache is not safe for
+// concurrent use. A nil *AncestryCache is valid and memoizes nothing.
+type AncestryCache struct {
+	mergeBases map[commitPair]mergeBaseResult
+}
+
+// commitPair is an unordered pair of commit hashes, stored with the lesser hash first
+type commitPair struct {
+	a, b hash.Hash
+}
+
+func newCommitPair(a, b hash.Hash) commitPair {
+	if b.Less(a) {
+		a, b = b, a
+	}
+	return commitPair{a: a, b: b}
+}
+
+type mergeBaseResult struct {
+	base hash.Hash
+	// found is false if the commits have no common ancestor
+	found bool
+}
+
+// NewAncestryCache returns a new, empty AncestryCache
+func NewAncestryCache() *AncestryCache {
+	return &AncestryCache{mergeBases: make(map[commitPair]mergeBaseResult)}
+}
+
+// MergeBase returns the hash of the merge base of |left| and |right|, or doltdb.ErrNoCommonAncestor if they don't
+// have one.
+func (c *AncestryCache) MergeBase(ctx context.Context, left, right *doltdb.Commit) (hash.Hash, error) {
+	leftHash, err := left.HashOf()
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	rightHash, err := right.HashOf()
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	key := newCommitPair(leftHash, rightHash)
+	if c != nil {
+		if result, ok := c.mergeBases[key]; ok {
+			if !result.found {
+				return hash.Hash{}, doltdb.ErrNoCommonAncestor
+			}
+			return result.base, nil
+		}
+	}
+
+	var result mergeBaseResult
+	mergeBase, err := doltdb.GetCommitAncestor(ctx, left, right)
+	if err == nil {
+		result.base, err = mergeBase.HashOf()
+		result.found = true
+	}
+	if err != nil && !errors.Is(err, doltdb.ErrNoCommonAncestor) {
+		return hash.Hash{}, err
+	}
+
+	if c != nil {
+		c.mergeBases[key] = result
+	}
+	if !result.found {
+		return hash.Hash{}, doltdb.ErrNoCommonAncestor
+	}
+	return result.base, nil
+}
+
+// IsAncestor returns whether |ancestor| is in the history of |descendant|, including when they are the same commit.
+func (c *AncestryCache) IsAncestor(ctx context.Context, ancestor, descendant *doltdb.Commit) (bool, error) {
+	ancestorHash, err := ancestor.HashOf()
+	if err != nil {
+		return false, err
+	}
+	descendantHash, err := descendant.HashOf()
+	if err != nil {
+		return false, err
+	}
+	if ancestorHash == descendantHash {
+		return true, nil
+	}
+
+	mergeBase, err := c.MergeBase(ctx, ancestor, descendant)
+	if errors.Is(err, doltdb.ErrNoCommonAncestor) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return mergeBase == ancestorHash, nil
+}
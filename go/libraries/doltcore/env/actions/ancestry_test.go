@@ -0,0 +1,66 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+)
+
+func TestAncestryCache(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	mainCommits := createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	featureCommits := createTestCommits(t, dEnv, "feature", 1)
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	main := createTestCommits(t, dEnv, env.DefaultInitBranch, 0)[0]
+	feature := featureCommits[1]
+
+	for _, ancestry := range []*AncestryCache{nil, NewAncestryCache()} {
+		for i := 0; i < 2; i++ {
+			mergeBase, err := ancestry.MergeBase(ctx, main, feature)
+			require.NoError(t, err)
+			assert.Equal(t, mustHashOf(t, mainCommits[1]), mergeBase.String())
+
+			// merge bases are symmetric, and share a cache entry
+			mergeBase, err = ancestry.MergeBase(ctx, feature, main)
+			require.NoError(t, err)
+			assert.Equal(t, mustHashOf(t, mainCommits[1]), mergeBase.String())
+
+			isAnc, err := ancestry.IsAncestor(ctx, mainCommits[0], feature)
+			require.NoError(t, err)
+			assert.True(t, isAnc)
+			isAnc, err = ancestry.IsAncestor(ctx, main, feature)
+			require.NoError(t, err)
+			assert.False(t, isAnc)
+			isAnc, err = ancestry.IsAncestor(ctx, main, main)
+			require.NoError(t, err)
+			assert.True(t, isAnc)
+		}
+
+		if ancestry != nil {
+			assert.Len(t, ancestry.mergeBases, 2)
+		}
+	}
+}
@@ -18,330 +18,1678 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/branch_control"
 	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
 	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/datas"
 	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
 )
 
 var ErrAlreadyExists = errors.New("already exists")
 var ErrCOBranchDelete = errors.New("attempted to delete checked out branch")
 var ErrUnmergedBranch = errors.New("branch is not fully merged")
 var ErrWorkingSetsOnBothBranches = errors.New("checkout would overwrite uncommitted changes on target branch")
+var ErrDeletedBranchNotFound = errors.New("no record of deleted branch")
+var ErrNotFastForward = errors.New("branch head is not an ancestor of the target commit")
+var ErrUncommittedChanges = errors.New("branch has uncommitted changes")
+var ErrDefaultBranchNotFound = errors.New("default branch not found")
+var ErrUpstreamNotRemovable = errors.New("branch upstream can't be removed from this database")
+
+// deletedBranchRefPrefix is the prefix of the internal refs that record the last head of each deleted branch
+const deletedBranchRefPrefix = "deleted-branches/"
 
 func RenameBranch(ctx context.Context, dbData env.DbData, oldBranch, newBranch string, remoteDbPro env.RemoteDbProvider, force bool, rsc *doltdb.ReplicationStatusController) error {
+	return RenameBranchWithOptions(ctx, dbData, oldBranch, newBranch, remoteDbPro, RenameOptions{Force: force}, rsc)
+}
+
+type RenameOptions struct {
+	// Force allows renaming onto an existing branch, replacing it
+	Force bool
+	// DiscardTargetChanges allows a forced rename onto an existing branch whose working set has uncommitted changes,
+	// which are lost. Without it, such a rename fails with ErrWorkingSetsOnBothBranches.
+	DiscardTargetChanges bool
+}
+
+// RenameBranchWithOptions renames |oldBranch| to |newBranch|, moving its working set along with it.
+func RenameBranchWithOptions(ctx context.Context, dbData env.DbData, oldBranch, newBranch string, remoteDbPro env.RemoteDbProvider, opts RenameOptions, rsc *doltdb.ReplicationStatusController) (err error) {
 	oldRef := ref.NewBranchRef(oldBranch)
 	newRef := ref.NewBranchRef(newBranch)
 
+	if opts.Force && !opts.DiscardTargetChanges && oldBranch != newBranch {
+		err := validateRenameTargetIsClean(ctx, dbData.Ddb, newRef)
+		if err != nil {
+			return err
+		}
+	}
+
 	// TODO: This function smears the branch updates across multiple commits of the datas.Database.
 
-	err := CopyBranchOnDB(ctx, dbData.Ddb, oldBranch, newBranch, force, rsc)
+	createdTarget := true
+	if opts.Force {
+		exists, err := dbData.Ddb.HasRef(ctx, newRef)
+		if err != nil {
+			return err
+		}
+		createdTarget = !exists
+	}
+
+	err = copyBranchOnDB(ctx, dbData.Ddb, oldBranch, newBranch, opts.Force, true, rsc)
+	if err != nil {
+		return err
+	}
+
+	// Every step from here on leaves |oldBranch| and its working set untouched until it's deleted, last. If a step
+	// fails, the steps before it are undone, so that the rename can be retried.
+	movedHead, deletedOld := false, false
+	defer func() {
+		if err == nil || deletedOld {
+			return
+		}
+		undoErr := undoPartialRename(ctx, dbData, oldRef, newRef, createdTarget, movedHead, rsc)
+		if undoErr != nil {
+			err = fmt.Errorf("%w; branch '%s' and its working set are unchanged, but the partial rename could not be undone: %v", err, oldBranch, undoErr)
+		} else {
+			err = fmt.Errorf("%w; branch '%s' and its working set are unchanged, and the rename can be retried", err, oldBranch)
+		}
+	}()
+
+	err = copyWorkingSetForRename(ctx, dbData.Ddb, oldRef, newRef)
+	if err != nil {
+		return err
+	}
+	movedHead, err = moveCWBHeadRef(ctx, dbData, oldRef, newRef)
+	if err != nil {
+		return err
+	}
+	// The current working branch was moved off of |oldBranch| above, so its ref can be deleted directly, without
+	// DeleteBranch's checks, a deletion event, or a record for RecoverDeletedBranch, since its head lives on as
+	// |newBranch|. Its working set is deleted after the branch, so that the branch is never left without one.
+	err = dbData.Ddb.DeleteBranch(ctx, oldRef, rsc)
+	if err != nil {
+		return err
+	}
+	deletedOld = true
+
+	if wsRef, err := ref.WorkingSetRefForHead(oldRef); err == nil {
+		err = dbData.Ddb.DeleteWorkingSet(ctx, wsRef)
+		if err != nil && err != doltdb.ErrWorkingSetNotFound {
+			logrus.Warnf("unable to delete working set of renamed branch %s: %v", oldBranch, err)
+		}
+	}
+	if err := moveBranchDescription(dbData, oldBranch, newBranch); err != nil {
+		logrus.Warnf("unable to move the description of renamed branch %s: %v", oldBranch, err)
+	}
+
+	if listener := getBranchEventListener(); listener != nil {
+		// the rename is done by now, so a failure to build the event is only logged, like a failure of the listener
+		_, head, err := LookupBranch(ctx, dbData.Ddb, newBranch)
+		if err != nil {
+			logrus.Warnf("unable to notify branch event listener of rename of branch %s to %s: %v", oldBranch, newBranch, err)
+		} else {
+			notifyBranchRenamed(ctx, listener, BranchEvent{Branch: newBranch, OldBranch: oldBranch, Head: head, Force: opts.Force})
+		}
+	}
+
+	return nil
+}
+
+// copyWorkingSetForRename copies the working set of |oldRef| to |newRef|, if the database supports working sets
+func copyWorkingSetForRename(ctx context.Context, ddb *doltdb.DoltDB, oldRef, newRef ref.BranchRef) error {
+	fromWSRef, err := ref.WorkingSetRefForHead(oldRef)
+	if err != nil {
+		if errors.Is(err, ref.ErrWorkingSetUnsupported) {
+			return nil
+		}
+		return err
+	}
+	toWSRef, err := ref.WorkingSetRefForHead(newRef)
 	if err != nil {
 		return err
 	}
+	// We always `force` here, because CopyBranchOnDB created the new branch and it will have a working set.
+	return ddb.CopyWorkingSet(ctx, fromWSRef, toWSRef, true /* force */)
+}
 
+// moveCWBHeadRef points the current working branch at |to| if it's |from|, and returns whether it did
+func moveCWBHeadRef(ctx context.Context, dbData env.DbData, from, to ref.BranchRef) (bool, error) {
 	headRef, err := dbData.Rsr.CWBHeadRef()
+	if err != nil {
+		return false, err
+	}
+	if !ref.Equals(headRef, from) {
+		return false, nil
+	}
+	err = dbData.Rsw.SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: to})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// undoPartialRename undoes the steps of a rename from |oldRef| to |newRef| that failed before |oldRef| was deleted. The
+// new branch is only deleted if the rename created it, since a branch replaced by a forced rename can't be restored.
+func undoPartialRename(ctx context.Context, dbData env.DbData, oldRef, newRef ref.BranchRef, createdTarget, movedHead bool, rsc *doltdb.ReplicationStatusController) error {
+	if movedHead {
+		err := dbData.Rsw.SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: oldRef})
+		if err != nil {
+			return err
+		}
+	}
+	if !createdTarget {
+		return nil
+	}
+
+	if wsRef, err := ref.WorkingSetRefForHead(newRef); err == nil {
+		err = dbData.Ddb.DeleteWorkingSet(ctx, wsRef)
+		if err != nil && err != doltdb.ErrWorkingSetNotFound {
+			return err
+		}
+	}
+	return dbData.Ddb.DeleteBranch(ctx, newRef, rsc)
+}
+
+// validateRenameTargetIsClean returns ErrWorkingSetsOnBothBranches if |target| exists and has uncommitted changes
+// that renaming another branch onto it would overwrite.
+func validateRenameTargetIsClean(ctx context.Context, ddb *doltdb.DoltDB, target ref.BranchRef) error {
+	exists, err := ddb.HasRef(ctx, target)
+	if err != nil || !exists {
+		return err
+	}
+
+	head, err := ddb.ResolveCommitRef(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	dirty, err := branchHasUncommittedChanges(ctx, ddb, target, head)
+	if err != nil {
+		return err
+	} else if dirty {
+		return ErrWorkingSetsOnBothBranches
+	}
+
+	return nil
+}
+
+// RenameBranches renames each branch in |mapping| from its key to its value. Every rename is validated before any
+// branch is changed. If a rename fails partway through, the renames already performed are reverted, along with the
+// branches that forced renames replaced, and the returned error describes any that could not be.
+func RenameBranches(ctx context.Context, dbData env.DbData, mapping map[string]string, remoteDbPro env.RemoteDbProvider, force bool, rsc *doltdb.ReplicationStatusController) error {
+	oldBranches := make([]string, 0, len(mapping))
+	for oldBranch := range mapping {
+		oldBranches = append(oldBranches, oldBranch)
+	}
+	sort.Strings(oldBranches)
+
+	err := validateRenameBranches(ctx, dbData.Ddb, oldBranches, mapping, force)
+	if err != nil {
+		return err
+	}
+
+	trackedBranches, err := dbData.Rsr.GetBranches()
+	if err != nil {
+		return err
+	}
+
+	replaced := make(map[string]replacedBranch)
+	if force {
+		for _, oldBranch := range oldBranches {
+			saved, ok, err := saveReplacedBranch(ctx, dbData, mapping[oldBranch])
+			if err != nil {
+				return err
+			} else if ok {
+				replaced[mapping[oldBranch]] = saved
+			}
+		}
+	}
+
+	for i, oldBranch := range oldBranches {
+		newBranch := mapping[oldBranch]
+		err = renameBranchWithUpstream(ctx, dbData, oldBranch, newBranch, trackedBranches, remoteDbPro, force, rsc)
+		if err == nil {
+			continue
+		}
+
+		renameErr := fmt.Errorf("error renaming branch '%s' to '%s': %w", oldBranch, newBranch, err)
+		// a failed rename leaves |oldBranch| as it was, but may already have replaced |newBranch|
+		if saved, ok := replaced[newBranch]; ok {
+			restoreErr := restoreReplacedBranch(ctx, dbData, newBranch, saved, trackedBranches, rsc)
+			if restoreErr != nil {
+				return fmt.Errorf("%w; additionally failed to restore branch '%s', which the rename replaced: %v", renameErr, newBranch, restoreErr)
+			}
+		}
+		for j := i - 1; j >= 0; j-- {
+			renamed := oldBranches[j]
+			// the upstream, if any, was moved to the new name, so move it back from there
+			movedUpstream := make(map[string]env.BranchConfig)
+			if upstream, ok := trackedBranches[renamed]; ok {
+				movedUpstream[mapping[renamed]] = upstream
+			}
+			rollbackErr := renameBranchWithUpstream(ctx, dbData, mapping[renamed], renamed, movedUpstream, remoteDbPro, true, rsc)
+			if rollbackErr == nil {
+				if saved, ok := replaced[mapping[renamed]]; ok {
+					rollbackErr = restoreReplacedBranch(ctx, dbData, mapping[renamed], saved, trackedBranches, rsc)
+				}
+			}
+			if rollbackErr != nil {
+				return fmt.Errorf("%w; additionally failed to revert renames, branches %v have been renamed: %v", renameErr, oldBranches[:j+1], rollbackErr)
+			}
+		}
+		return renameErr
+	}
+
+	return nil
+}
+
+// replacedBranch is the state of a branch that a forced rename replaces, saved to restore the branch if the rename is
+// reverted
+type replacedBranch struct {
+	head        *doltdb.Commit
+	ws          *doltdb.WorkingSet
+	description string
+}
+
+// saveReplacedBranch returns the state of the local branch named, and whether it exists
+func saveReplacedBranch(ctx context.Context, dbData env.DbData, branch string) (replacedBranch, bool, error) {
+	branchRef := ref.NewBranchRef(branch)
+	hasRef, err := dbData.Ddb.HasRef(ctx, branchRef)
+	if err != nil || !hasRef {
+		return replacedBranch{}, false, err
+	}
+
+	var saved replacedBranch
+	saved.head, err = dbData.Ddb.ResolveCommitRef(ctx, branchRef)
+	if err != nil {
+		return replacedBranch{}, false, err
+	}
+	if wsRef, err := ref.WorkingSetRefForHead(branchRef); err == nil {
+		saved.ws, err = dbData.Ddb.ResolveWorkingSet(ctx, wsRef)
+		if err != nil && !errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+			return replacedBranch{}, false, err
+		}
+	}
+	saved.description, err = GetBranchDescription(dbData, branch)
+	if err != nil {
+		return replacedBranch{}, false, err
+	}
+	return saved, true, nil
+}
+
+// restoreReplacedBranch restores the local branch named, along with its working set, upstream and description, to the
+// state |saved| before a forced rename replaced it
+func restoreReplacedBranch(ctx context.Context, dbData env.DbData, branch string, saved replacedBranch, trackedBranches map[string]env.BranchConfig, rsc *doltdb.ReplicationStatusController) error {
+	ddb := dbData.Ddb
+	branchRef := ref.NewBranchRef(branch)
+	err := ddb.SetHeadToCommit(ctx, branchRef, saved.head)
 	if err != nil {
 		return err
 	}
-	if ref.Equals(headRef, oldRef) {
-		err = dbData.Rsw.SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: newRef})
+
+	if saved.ws != nil {
+		wsRef, err := ref.WorkingSetRefForHead(branchRef)
+		if err != nil {
+			return err
+		}
+		var prevHash hash.Hash
+		current, err := ddb.ResolveWorkingSet(ctx, wsRef)
+		if err == nil {
+			prevHash, err = current.HashOf()
+			if err != nil {
+				return err
+			}
+		} else if !errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+			return err
+		}
+		err = ddb.UpdateWorkingSet(ctx, wsRef, saved.ws, prevHash, doltdb.TodoWorkingSetMeta(), rsc)
+		if err != nil {
+			return err
+		}
+	}
+
+	if upstream, ok := trackedBranches[branch]; ok {
+		err = copyBranchUpstream(ctx, dbData, branch, upstream)
 		if err != nil {
 			return err
 		}
 	}
+	if saved.description != "" {
+		return SetBranchDescription(ctx, dbData, branch, saved.description)
+	}
+	return nil
+}
 
-	fromWSRef, err := ref.WorkingSetRefForHead(oldRef)
+// validateRenameBranches checks that every rename in |mapping| can be performed
+func validateRenameBranches(ctx context.Context, ddb *doltdb.DoltDB, oldBranches []string, mapping map[string]string, force bool) error {
+	newBranches := make(map[string]string, len(mapping))
+	for _, oldBranch := range oldBranches {
+		newBranch := mapping[oldBranch]
+		if other, ok := newBranches[newBranch]; ok {
+			return fmt.Errorf("cannot rename both '%s' and '%s' to '%s'", other, oldBranch, newBranch)
+		}
+		newBranches[newBranch] = oldBranch
+
+		if _, ok := mapping[newBranch]; ok {
+			return fmt.Errorf("cannot rename '%s' to '%s': '%s' is also being renamed", oldBranch, newBranch, newBranch)
+		}
+		if !doltdb.IsValidUserBranchName(newBranch) {
+			return fmt.Errorf("%w: '%s'", doltdb.ErrInvBranchName, newBranch)
+		}
+		if err := checkUnicodeEquivalentBranch(ctx, ddb, newBranch, oldBranch); err != nil {
+			return err
+		}
+
+		hasOld, err := ddb.HasRef(ctx, ref.NewBranchRef(oldBranch))
+		if err != nil {
+			return err
+		} else if !hasOld {
+			return fmt.Errorf("%w: '%s'", doltdb.ErrBranchNotFound, oldBranch)
+		}
+
+		hasNew, err := ddb.HasRef(ctx, ref.NewBranchRef(newBranch))
+		if err != nil {
+			return err
+		} else if hasNew && !force {
+			return fmt.Errorf("%w: '%s'", ErrAlreadyExists, newBranch)
+		}
+	}
+
+	return nil
+}
+
+// renameBranchWithUpstream renames a branch with RenameBranch and moves its upstream tracking config, if any, from
+// the old branch name to the new one.
+func renameBranchWithUpstream(ctx context.Context, dbData env.DbData, oldBranch, newBranch string, trackedBranches map[string]env.BranchConfig, remoteDbPro env.RemoteDbProvider, force bool, rsc *doltdb.ReplicationStatusController) error {
+	err := RenameBranch(ctx, dbData, oldBranch, newBranch, remoteDbPro, force, rsc)
 	if err != nil {
-		if !errors.Is(err, ref.ErrWorkingSetUnsupported) {
+		return err
+	}
+
+	if upstream, ok := trackedBranches[oldBranch]; ok {
+		err = copyBranchUpstream(ctx, dbData, newBranch, upstream)
+		if err != nil {
 			return err
 		}
-	} else {
-		toWSRef, err := ref.WorkingSetRefForHead(newRef)
+		err = UnsetBranchUpstream(ctx, dbData, oldBranch)
+		if errors.Is(err, ErrUpstreamNotRemovable) {
+			logrus.Warnf("unable to remove the upstream of renamed branch %s", oldBranch)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// CopyBranch copies |oldBranch| to |newBranch|, along with its description. CopyBranchOnDB copies only the branch;
+// use CopyBranchDescription to copy the description as well.
+func CopyBranch(ctx context.Context, dEnv *env.DoltEnv, oldBranch, newBranch string, force bool) error {
+	err := CopyBranchOnDB(ctx, dEnv.DoltDB, oldBranch, newBranch, force, nil)
+	if err != nil {
+		return err
+	}
+	return CopyBranchDescription(dEnv.DbData(), oldBranch, newBranch)
+}
+
+// CopyBranchOnDB creates |newBranch| at the head of |oldBranch|. A copy within a database shares all of its chunks
+// with the original branch, so only the new branch's ref is written, however long its history.
+func CopyBranchOnDB(ctx context.Context, ddb *doltdb.DoltDB, oldBranch, newBranch string, force bool, rsc *doltdb.ReplicationStatusController) error {
+	return copyBranchOnDB(ctx, ddb, oldBranch, newBranch, force, false, rsc)
+}
+
+// copyBranchOnDB is CopyBranchOnDB. If |renaming|, |oldBranch| is about to be deleted, so |newBranch| may be a
+// Unicode-equivalent spelling of it.
+func copyBranchOnDB(ctx context.Context, ddb *doltdb.DoltDB, oldBranch, newBranch string, force, renaming bool, rsc *doltdb.ReplicationStatusController) error {
+	oldRef := ref.NewBranchRef(oldBranch)
+	newRef := ref.NewBranchRef(newBranch)
+
+	hasOld, oldErr := ddb.HasRef(ctx, oldRef)
+
+	if oldErr != nil {
+		return oldErr
+	}
+
+	hasNew, newErr := ddb.HasRef(ctx, newRef)
+
+	if newErr != nil {
+		return newErr
+	}
+
+	if !hasOld {
+		return doltdb.ErrBranchNotFound
+	} else if !force && hasNew {
+		return ErrAlreadyExists
+	} else if !doltdb.IsValidUserBranchName(newBranch) {
+		return doltdb.ErrInvBranchName
+	}
+
+	replaced := ""
+	if renaming {
+		replaced = oldBranch
+	}
+	if err := checkUnicodeEquivalentBranch(ctx, ddb, newBranch, replaced); err != nil {
+		return err
+	}
+
+	// the branch is resolved by its ref, since a commit spec of a hash-like name would resolve the commit instead
+	cm, err := ddb.ResolveCommitRef(ctx, oldRef)
+
+	if err != nil {
+		return err
+	}
+
+	return ddb.NewBranchAtCommit(ctx, newRef, cm, rsc)
+}
+
+// maxUniqueBranchNameAttempts bounds the number of suffixed names CopyBranchUnique tries
+const maxUniqueBranchNameAttempts = 100
+
+// CopyBranchUnique copies |oldBranch| to a new branch named |desiredName|. If a branch with that name already exists,
+// a numeric suffix (e.g. "-1", "-2") is appended until a free name is found. Returns the name of the branch created.
+func CopyBranchUnique(ctx context.Context, ddb *doltdb.DoltDB, oldBranch, desiredName string, rsc *doltdb.ReplicationStatusController) (string, error) {
+	candidate := desiredName
+	for i := 1; i <= maxUniqueBranchNameAttempts; i++ {
+		err := CopyBranchOnDB(ctx, ddb, oldBranch, candidate, false, rsc)
+		if err == nil {
+			return candidate, nil
+		} else if err != ErrAlreadyExists {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%d", desiredName, i)
+	}
+
+	return "", fmt.Errorf("%w: no free branch name found for '%s' after %d attempts", ErrAlreadyExists, desiredName, maxUniqueBranchNameAttempts)
+}
+
+type DeleteOptions struct {
+	Force  bool
+	Remote bool
+	// KeepWorkingSet leaves the branch's working set, and its description, in place when the branch is deleted, so that
+	// it can be inspected with DoltDB.ResolveWorkingSet, or reattached along with the branch by RecoverDeletedBranch.
+	// Creating a new branch with the same name instead resets the kept working set to the new branch's head. Until then,
+	// the orphaned working set is unreachable from any branch and its storage will not be reclaimed by GC.
+	KeepWorkingSet bool
+	// Ancestry, if non-nil, memoizes the check that the branch is merged. Share one across the deletes of a single
+	// operation that deletes many branches.
+	Ancestry *AncestryCache
+	// Destroy deletes the branch no matter what state it's in, for cleaning up damaged branches. It implies Force, and
+	// a failure to delete the working set is logged rather than returned. The checked out branch still can't be
+	// deleted.
+	Destroy bool
+	// RemoteRetry is how requests to the branch's upstream remote are retried when checking that the branch is merged
+	// into its upstream. By default, they aren't, and the remote's error is returned. With retries, a
+	// RemoteUnreachableError is returned once they're used up.
+	RemoteRetry RetryPolicy
+	// MergedInto, if set, is the branch that the branch being deleted must be merged into, in place of its upstream
+	// or the current branch
+	MergedInto ref.DoltRef
+	// CurrentHead holds the head commit of the current branch once it's resolved for checking that the branch is
+	// merged into the current branch. Share one across an operation that deletes many branches. May be nil.
+	CurrentHead *CurrentHeadCache
+}
+
+// RetryPolicy configures how failed requests to a remote are retried. The zero value fails on the first error.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times a request is made. Values less than 2 disable retries.
+	Attempts int
+	// Backoff is the delay before the first retry, which grows exponentially with each retry after that
+	Backoff time.Duration
+}
+
+// do calls |fn| until it succeeds, it returns a permanent error, or the policy's attempts are used up, and returns its
+// last error. A permanent error is one wrapped with backoff.Permanent.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	if p.Attempts < 2 {
+		return fn()
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = p.Backoff
+	b.MaxElapsedTime = 0
+	return backoff.Retry(fn, backoff.WithContext(backoff.WithMaxRetries(b, uint64(p.Attempts-1)), ctx))
+}
+
+// closeRemoteDb closes |db|, which was opened by |pro| for a single request, and logs a failure to close it. The
+// databases returned by a RemoteDbCache are shared and closed by the cache, so they're left open.
+func closeRemoteDb(pro env.RemoteDbProvider, db *doltdb.DoltDB) {
+	if _, ok := pro.(*RemoteDbCache); ok {
+		return
+	}
+	if err := db.Close(); err != nil {
+		logrus.Warnf("error closing remote database: %v", err)
+	}
+}
+
+// RemoteUnreachableError is returned when a branch's upstream remote couldn't be read, as opposed to ErrUnmergedBranch
+// when it could be read and the branch isn't merged into it
+type RemoteUnreachableError struct {
+	Remote string
+	Err    error
+}
+
+func (e RemoteUnreachableError) Error() string {
+	return fmt.Sprintf("remote '%s' is unreachable: %v", e.Remote, e.Err)
+}
+
+func (e RemoteUnreachableError) Unwrap() error {
+	return e.Err
+}
+
+func DeleteBranch(ctx context.Context, dbData env.DbData, brName string, opts DeleteOptions, remoteDbPro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) error {
+	var branchRef ref.DoltRef
+	if opts.Remote {
+		var err error
+		branchRef, err = ref.NewRemoteRefFromPathStr(brName)
 		if err != nil {
 			return err
 		}
-		// We always `force` here, because the CopyBranch up
-		// above created a new branch and it will have a
-		// working set.
-		err = dbData.Ddb.CopyWorkingSet(ctx, fromWSRef, toWSRef, true /* force */)
+	} else {
+		branchRef = ref.NewBranchRef(brName)
+		headRef, err := dbData.Rsr.CWBHeadRef()
 		if err != nil {
 			return err
 		}
+		if ref.Equals(headRef, branchRef) {
+			return ErrCOBranchDelete
+		}
+	}
+
+	return DeleteBranchOnDB(ctx, dbData, branchRef, opts, remoteDbPro, rsc)
+}
+
+// CanDeleteLocally returns whether |branch| could be deleted without force, judging only by whether it's merged into
+// the head of the current branch, and a reason describing why. Unlike DeleteBranch, it never contacts the branch's
+// upstream remote, so it works offline.
+func CanDeleteLocally(ctx context.Context, dbData env.DbData, branch ref.DoltRef) (bool, string, error) {
+	headRef, err := dbData.Rsr.CWBHeadRef()
+	if err != nil {
+		return false, "", err
+	}
+	if ref.Equals(headRef, branch) {
+		return false, fmt.Sprintf("branch '%s' is checked out", branch.GetPath()), nil
+	}
+
+	err = validateBranchMergedIntoCurrentWorkingBranch(ctx, dbData, branch, nil)
+	if err == ErrUnmergedBranch {
+		return false, fmt.Sprintf("branch '%s' is not fully merged into '%s'", branch.GetPath(), headRef.GetPath()), nil
+	} else if err != nil {
+		return false, "", err
+	}
+	return true, fmt.Sprintf("branch '%s' is fully merged into '%s'", branch.GetPath(), headRef.GetPath()), nil
+}
+
+func DeleteBranchOnDB(ctx context.Context, dbdata env.DbData, branchRef ref.DoltRef, opts DeleteOptions, pro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) error {
+	listener := getBranchEventListener()
+	if listener == nil || branchRef.GetType() != ref.BranchRefType {
+		return deleteBranchOnDB(ctx, dbdata, branchRef, opts, pro, rsc)
+	}
+
+	// the head is only known before the branch is deleted
+	_, head, err := LookupBranch(ctx, dbdata.Ddb, branchRef.GetPath())
+	if err != nil {
+		return err
+	}
+
+	err = deleteBranchOnDB(ctx, dbdata, branchRef, opts, pro, rsc)
+	if err != nil {
+		return err
+	}
+
+	notifyBranchDeleted(ctx, listener, BranchEvent{Branch: branchRef.GetPath(), Head: head, Force: opts.Force || opts.Destroy})
+	return nil
+}
+
+func deleteBranchOnDB(ctx context.Context, dbdata env.DbData, branchRef ref.DoltRef, opts DeleteOptions, pro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) error {
+	ddb := dbdata.Ddb
+	err := validateBranchDeletion(ctx, dbdata, branchRef, opts, pro)
+	if err != nil {
+		return err
+	}
+
+	if !opts.KeepWorkingSet {
+		wsRef, err := ref.WorkingSetRefForHead(branchRef)
+		if err != nil {
+			if !errors.Is(err, ref.ErrWorkingSetUnsupported) {
+				return err
+			}
+		} else {
+			err = ddb.DeleteWorkingSet(ctx, wsRef)
+			if err != nil && opts.Destroy {
+				logrus.Warnf("unable to delete working set of destroyed branch %s: %v", branchRef.GetPath(), err)
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	if branchRef.GetType() == ref.BranchRefType {
+		err = recordDeletedBranch(ctx, ddb, branchRef)
+		if err != nil {
+			logrus.Warnf("unable to record head of deleted branch %s, it will not be recoverable: %v", branchRef.GetPath(), err)
+		}
+	}
+
+	err = ddb.DeleteBranch(ctx, branchRef, rsc)
+	if err != nil {
+		return err
+	}
+
+	if !opts.KeepWorkingSet {
+		deleteOrphanedBranchDescription(dbdata, branchRef)
+	}
+	return nil
+}
+
+// validateBranchDeletion returns an error if |branchRef| can't be deleted with the options given: if it doesn't exist,
+// if it's the last branch, or, unless forced, if it isn't merged.
+func validateBranchDeletion(ctx context.Context, dbdata env.DbData, branchRef ref.DoltRef, opts DeleteOptions, pro env.RemoteDbProvider) error {
+	ddb := dbdata.Ddb
+	hasRef, err := ddb.HasRef(ctx, branchRef)
+
+	if err != nil {
+		return err
+	} else if !hasRef {
+		return doltdb.ErrBranchNotFound
+	}
+
+	if branchRef.GetType() == ref.BranchRefType {
+		// DoltDB refuses to delete the last branch regardless of options, but by then we would already have deleted
+		// its working set, so check first.
+		branches, err := ddb.GetBranches(ctx)
+		if err != nil {
+			return err
+		}
+		if len(branches) == 1 {
+			return doltdb.ErrCannotDeleteLastBranch
+		}
+	}
+
+	if !opts.Force && !opts.Destroy && !opts.Remote {
+		// check to see if the branch is fully merged into its parent
+		trackedBranches, err := dbdata.Rsr.GetBranches()
+		if err != nil {
+			return err
+		}
+
+		trackedBranch, hasUpstream := trackedBranches[branchRef.GetPath()]
+		if opts.MergedInto != nil {
+			err = validateBranchMergedIntoTarget(ctx, dbdata, branchRef, opts.MergedInto, opts.Ancestry)
+			if err != nil {
+				return err
+			}
+		} else if hasUpstream {
+			err = validateBranchMergedIntoUpstream(ctx, dbdata, branchRef, trackedBranch.Remote, pro, opts.RemoteRetry)
+			if errors.Is(err, env.ErrRemoteNotFound) {
+				// The upstream's remote was removed without cleaning up the tracking config, so there's nothing to
+				// compare against remotely. Fall back to the same check we use for branches without an upstream.
+				err = validateBranchMergedIntoCurrentHead(ctx, dbdata, branchRef, opts)
+			}
+			if err != nil {
+				return err
+			}
+		} else {
+			err = validateBranchMergedIntoCurrentHead(ctx, dbdata, branchRef, opts)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func deletedBranchRef(branchName string) ref.DoltRef {
+	return ref.NewInternalRef(deletedBranchRefPrefix + branchName)
+}
+
+// recordDeletedBranch saves the current head of the branch given so that it can be restored with RecoverDeletedBranch
+// after the branch is deleted.
+func recordDeletedBranch(ctx context.Context, ddb *doltdb.DoltDB, branchRef ref.DoltRef) error {
+	head, err := ddb.ResolveCommitRef(ctx, branchRef)
+	if err != nil {
+		return err
+	}
+	return ddb.SetHeadToCommit(ctx, deletedBranchRef(branchRef.GetPath()), head)
+}
+
+// RecoverDeletedBranch recreates the branch named at the head it had when it was last deleted. If the branch was
+// deleted with KeepWorkingSet, its working set is reattached as it is; otherwise the branch gets a clean working set.
+// Returns ErrDeletedBranchNotFound if there is no record of a deletion for the branch.
+func RecoverDeletedBranch(ctx context.Context, dbData env.DbData, branchName string) error {
+	ddb := dbData.Ddb
+	recordRef := deletedBranchRef(branchName)
+	hasRecord, err := ddb.HasRef(ctx, recordRef)
+	if err != nil {
+		return err
+	} else if !hasRecord {
+		return ErrDeletedBranchNotFound
+	}
+
+	branchRef := ref.NewBranchRef(branchName)
+	hasBranch, err := ddb.HasRef(ctx, branchRef)
+	if err != nil {
+		return err
+	} else if hasBranch {
+		return ErrAlreadyExists
+	}
+
+	head, err := ddb.ResolveCommitRef(ctx, recordRef)
+	if err != nil {
+		return err
+	}
+
+	keptWorkingSet := false
+	if wsRef, err := ref.WorkingSetRefForHead(branchRef); err == nil {
+		_, err = ddb.ResolveWorkingSet(ctx, wsRef)
+		if err == nil {
+			keptWorkingSet = true
+		} else if !errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+			return err
+		}
+	}
+
+	if keptWorkingSet {
+		// NewBranchAtCommit would reset the working set to |head|
+		err = ddb.SetHeadToCommit(ctx, branchRef, head)
+	} else {
+		err = ddb.NewBranchAtCommit(ctx, branchRef, head, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ddb.DeleteInternalRef(ctx, recordRef)
+}
+
+// DeletedBranches returns the names of the deleted branches that can be restored with RecoverDeletedBranch, in name
+// order.
+func DeletedBranches(ctx context.Context, ddb *doltdb.DoltDB) ([]string, error) {
+	refs, err := ddb.GetRefsOfType(ctx, map[ref.RefType]struct{}{ref.InternalRefType: {}})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, r := range refs {
+		if strings.HasPrefix(r.GetPath(), deletedBranchRefPrefix) {
+			names = append(names, strings.TrimPrefix(r.GetPath(), deletedBranchRefPrefix))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// PurgeDeletedBranches discards the records kept for RecoverDeletedBranch of the deleted branches named, or of every
+// deleted branch if none are named, and returns the names of the branches whose records were discarded. A record keeps
+// every chunk reachable from the deleted branch's head from being collected by GC, so purging records is what
+// eventually frees the storage of deleted branches. Names without a record are ignored.
+func PurgeDeletedBranches(ctx context.Context, ddb *doltdb.DoltDB, branchNames ...string) ([]string, error) {
+	recorded, err := DeletedBranches(ctx, ddb)
+	if err != nil {
+		return nil, err
+	}
+
+	toPurge := recorded
+	if len(branchNames) > 0 {
+		named := make(map[string]struct{}, len(branchNames))
+		for _, name := range branchNames {
+			named[name] = struct{}{}
+		}
+		toPurge = nil
+		for _, name := range recorded {
+			if _, ok := named[name]; ok {
+				toPurge = append(toPurge, name)
+			}
+		}
+	}
+
+	var purged []string
+	for _, name := range toPurge {
+		err = ddb.DeleteInternalRef(ctx, deletedBranchRef(name))
+		if err != nil {
+			return purged, err
+		}
+		purged = append(purged, name)
+	}
+	return purged, nil
+}
+
+// validateBranchMergedIntoCurrentWorkingBranch returns an error if the given branch is not fully merged into the HEAD of the current branch.
+// If |ctx| is canceled while history is being walked, its error is returned. |ancestry| may be nil.
+func validateBranchMergedIntoCurrentWorkingBranch(ctx context.Context, dbdata env.DbData, branch ref.DoltRef, ancestry *AncestryCache) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cwbHead, err := ResolveCurrentHead(ctx, dbdata)
+	if err != nil {
+		return err
+	}
+
+	return validateBranchMergedIntoCommit(ctx, dbdata, branch, cwbHead, ancestry)
+}
+
+// validateBranchMergedIntoCurrentHead is validateBranchMergedIntoCurrentWorkingBranch, using the head commit of the
+// current branch held by |opts|
+func validateBranchMergedIntoCurrentHead(ctx context.Context, dbdata env.DbData, branch ref.DoltRef, opts DeleteOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cwbHead, err := opts.CurrentHead.Resolve(ctx, dbdata)
+	if err != nil {
+		return err
+	}
+	return validateBranchMergedIntoCommit(ctx, dbdata, branch, cwbHead, opts.Ancestry)
+}
+
+// CurrentHeadCache holds the head commit of the current branch, resolving it the first time it's needed. An operation
+// that checks many branches against the current branch resolves it at most once, and not at all if none of the
+// branches need it. The current branch isn't expected to move during the operation. A CurrentHeadCache is not safe for
+// concurrent use. A nil *CurrentHeadCache is valid and resolves the head every time.
+type CurrentHeadCache struct {
+	head *doltdb.Commit
+	// resolves counts the times the head was resolved
+	resolves int
+}
+
+// NewCurrentHeadCache returns a CurrentHeadCache holding |head|, or an empty one that resolves the head when it's first
+// needed if |head| is nil
+func NewCurrentHeadCache(head *doltdb.Commit) *CurrentHeadCache {
+	return &CurrentHeadCache{head: head}
+}
+
+// Resolve returns the head commit of the current branch of |dbData|, resolving it if it isn't held yet
+func (c *CurrentHeadCache) Resolve(ctx context.Context, dbData env.DbData) (*doltdb.Commit, error) {
+	if c == nil {
+		return ResolveCurrentHead(ctx, dbData)
+	}
+	if c.head == nil {
+		head, err := ResolveCurrentHead(ctx, dbData)
+		if err != nil {
+			return nil, err
+		}
+		c.head = head
+		c.resolves++
+	}
+	return c.head, nil
+}
+
+// ResolveCurrentHead returns the head commit of the current branch of |dbData|
+func ResolveCurrentHead(ctx context.Context, dbData env.DbData) (*doltdb.Commit, error) {
+	cwbCs, err := doltdb.NewCommitSpec("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	headRef, err := dbData.Rsr.CWBHeadRef()
+	if err != nil {
+		return nil, err
+	}
+	return dbData.Ddb.Resolve(ctx, cwbCs, headRef)
+}
+
+// validateBranchMergedIntoTarget returns an error if the given branch is not fully merged into the head of |target|.
+// |ancestry| may be nil.
+func validateBranchMergedIntoTarget(ctx context.Context, dbdata env.DbData, branch, target ref.DoltRef, ancestry *AncestryCache) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	targetHead, err := dbdata.Ddb.ResolveCommitRef(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	return validateBranchMergedIntoCommit(ctx, dbdata, branch, targetHead, ancestry)
+}
+
+// validateBranchMergedIntoCommit returns ErrUnmergedBranch if the head of the given branch isn't an ancestor of
+// |commit|
+func validateBranchMergedIntoCommit(ctx context.Context, dbdata env.DbData, branch ref.DoltRef, commit *doltdb.Commit, ancestry *AncestryCache) error {
+	branchHead, err := dbdata.Ddb.ResolveCommitRef(ctx, branch)
+	if err != nil {
+		return err
+	}
+
+	isMerged, err := ancestry.IsAncestor(ctx, branchHead, commit)
+	if err != nil {
+		return err
+	}
+
+	if !isMerged {
+		return ErrUnmergedBranch
+	}
+
+	return nil
+}
+
+// RemoteDefaultBranch returns the name of the default branch of the database at |remote|, e.g. to choose the branch a
+// new local branch should track. Remote databases don't record a default branch, so it's chosen from the remote's
+// branches the same way clone chooses the branch to check out: main, then master, then the first branch by name.
+// Returns doltdb.ErrBranchNotFound if the remote has no branches.
+func RemoteDefaultBranch(ctx context.Context, pro env.RemoteDbProvider, remote env.Remote, format *types.NomsBinFormat) (string, error) {
+	remoteDb, err := pro.GetRemoteDB(ctx, format, remote, false)
+	if err != nil {
+		return "", RemoteUnreachableError{Remote: remote.Name, Err: err}
+	}
+
+	branches, err := remoteDb.GetBranches(ctx)
+	if err != nil {
+		return "", err
+	} else if len(branches) == 0 {
+		return "", fmt.Errorf("%w: remote '%s' has no branches", doltdb.ErrBranchNotFound, remote.Name)
+	}
+
+	return env.DefaultBranchOf(branches, env.DefaultInitBranch), nil
+}
+
+// validateBranchMergedIntoUpstream returns an error if the branch provided is not fully merged into its upstream. If
+// |ctx| is canceled while history is being walked, its error is returned. Returns an error wrapping
+// env.ErrRemoteNotFound if the upstream's remote is not configured. If |retry| allows retries, a RemoteUnreachableError
+// is returned if the remote still can't be read after them; otherwise the remote's error is returned as is.
+func validateBranchMergedIntoUpstream(ctx context.Context, dbdata env.DbData, branch ref.DoltRef, remoteName string, pro env.RemoteDbProvider, retry RetryPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	remotes, err := dbdata.Rsr.GetRemotes()
+	if err != nil {
+		return err
+	}
+	remote, ok := remotes[remoteName]
+	if !ok {
+		return fmt.Errorf("%w: '%s'", env.ErrRemoteNotFound, remoteName)
+	}
+
+	// branches are resolved by their refs, since a commit spec of a hash-like name would resolve the commit instead
+	var remoteBranchHead *doltdb.Commit
+	err = retry.do(ctx, func() error {
+		remoteDb, err := pro.GetRemoteDB(ctx, dbdata.Ddb.ValueReadWriter().Format(), remote, false)
+		if err != nil {
+			return err
+		}
+		defer closeRemoteDb(pro, remoteDb)
+		remoteBranchHead, err = remoteDb.ResolveCommitRef(ctx, branch)
+		if errors.Is(err, doltdb.ErrBranchNotFound) {
+			return backoff.Permanent(err)
+		}
+		return err
+	})
+	if err != nil && retry.Attempts >= 2 && !errors.Is(err, doltdb.ErrBranchNotFound) && ctx.Err() == nil {
+		return RemoteUnreachableError{Remote: remoteName, Err: err}
+	} else if err != nil {
+		return err
+	}
+
+	localBranchHead, err := dbdata.Ddb.ResolveCommitRef(ctx, branch)
+	if err != nil {
+		return err
+	}
+
+	canFF, err := localBranchHead.CanFastForwardTo(ctx, remoteBranchHead)
+	if err != nil {
+		if errors.Is(err, doltdb.ErrUpToDate) {
+			return nil
+		}
+		if errors.Is(err, doltdb.ErrIsAhead) {
+			return ErrUnmergedBranch
+		}
+		return err
+	}
+
+	if !canFF {
+		return ErrUnmergedBranch
+	}
+
+	return nil
+}
+
+func CreateBranchWithStartPt(ctx context.Context, dbData env.DbData, newBranch, startPt string, force bool, rsc *doltdb.ReplicationStatusController) error {
+	return CreateBranchWithOptions(ctx, dbData, newBranch, startPt, CreateBranchOptions{Force: force}, rsc)
+}
+
+// CreateBranchWithOptions creates a branch named |newBranch| at |startPt|, and gives the current user admin permissions
+// on it. If |opts| has an Upstream, the branch is then configured to track it, and if that fails, e.g. because the
+// remote doesn't exist, the branch is still created and the error is returned.
+func CreateBranchWithOptions(ctx context.Context, dbData env.DbData, newBranch, startPt string, opts CreateBranchOptions, rsc *doltdb.ReplicationStatusController) error {
+	upstream := opts.Upstream
+	opts.Upstream = nil
+	err := createBranch(ctx, dbData, newBranch, startPt, opts, rsc)
+
+	if err != nil {
+		if err == ErrAlreadyExists {
+			return fmt.Errorf("fatal: A branch named '%s' already exists.", newBranch)
+		} else if err == doltdb.ErrInvBranchName {
+			if reason := ref.ValidateBranchName(newBranch); reason != nil {
+				return fmt.Errorf("fatal: '%s' is an invalid branch name: %v", newBranch, reason)
+			}
+			return fmt.Errorf("fatal: '%s' is an invalid branch name.", newBranch)
+		} else if errors.Is(err, doltdb.ErrBranchNameCollision) {
+			return fmt.Errorf("fatal: %w", err)
+		} else if err == doltdb.ErrBranchNameLooksLikeHash {
+			return fmt.Errorf("fatal: '%s' is an invalid branch name: it looks like a commit hash, which would make it ambiguous", newBranch)
+		} else if err == doltdb.ErrInvHash || doltdb.IsNotACommit(err) {
+			return fmt.Errorf("fatal: '%s' is not a commit and a branch '%s' cannot be created from it", startPt, newBranch)
+		} else if errors.Is(err, doltdb.ErrInvalidAncestorSpec) {
+			return fmt.Errorf("fatal: '%s' refers to an ancestor past the root commit and a branch '%s' cannot be created from it", startPt, newBranch)
+		} else {
+			return fmt.Errorf("fatal: Unexpected error creating branch '%s' : %v", newBranch, err)
+		}
+	}
+	err = branch_control.AddAdminForContext(ctx, newBranch)
+	if err != nil {
+		return err
+	}
+
+	if upstream != nil {
+		err = SetBranchUpstream(ctx, dbData, newBranch, upstream.Remote, upstream.Branch)
+		if err != nil {
+			return fmt.Errorf("branch '%s' created, but its upstream could not be set: %w", newBranch, err)
+		}
+	}
+
+	return nil
+}
+
+// CreateOrphanBranch creates a branch named |newBranch| with no history, like git checkout --orphan. Dolt branches
+// always point at a commit, so the branch points at a new commit of an empty root with no parents, and its working
+// set is empty. The commit is attributed to the author of the head commit of the current working branch.
+func CreateOrphanBranch(ctx context.Context, dbData env.DbData, newBranch string, rsc *doltdb.ReplicationStatusController) error {
+	if !doltdb.IsValidUserBranchName(newBranch) {
+		return fmt.Errorf("%w: '%s'", doltdb.ErrInvBranchName, newBranch)
+	}
+
+	ddb := dbData.Ddb
+	branchRef := ref.NewBranchRef(newBranch)
+	hasRef, err := ddb.HasRef(ctx, branchRef)
+	if err != nil {
+		return err
+	}
+	if hasRef {
+		return ErrAlreadyExists
+	}
+	if err = checkUnicodeEquivalentBranch(ctx, ddb, newBranch, ""); err != nil {
+		return err
+	}
+
+	headRef, err := newBranchHeadRef(ctx, dbData)
+	if err != nil {
+		return err
+	}
+	headCm, err := ddb.ResolveCommitRef(ctx, headRef)
+	if err != nil {
+		return err
+	}
+	headMeta, err := headCm.GetCommitMeta(ctx)
+	if err != nil {
+		return err
+	}
+	meta, err := datas.NewCommitMeta(headMeta.Name, headMeta.Email, fmt.Sprintf("Create orphan branch '%s'", newBranch))
+	if err != nil {
+		return err
+	}
+
+	root, err := doltdb.EmptyRootValue(ctx, ddb.ValueReadWriter(), ddb.NodeStore())
+	if err != nil {
+		return err
+	}
+	_, rootHash, err := ddb.WriteRootValue(ctx, root)
+	if err != nil {
+		return err
+	}
+	cm, err := ddb.CommitDanglingWithParentCommits(ctx, rootHash, nil, meta)
+	if err != nil {
+		return err
+	}
+
+	err = ddb.NewBranchAtCommit(ctx, branchRef, cm, rsc)
+	if err != nil {
+		return err
+	}
+
+	err = branch_control.AddAdminForContext(ctx, newBranch)
+	if err != nil {
+		return err
+	}
+
+	if listener := getBranchEventListener(); listener != nil {
+		head, err := cm.HashOf()
+		if err != nil {
+			return err
+		}
+		notifyBranchCreated(ctx, listener, BranchEvent{Branch: newBranch, Head: head})
+	}
+
+	return nil
+}
+
+// BranchUpstream identifies the remote branch a local branch tracks
+type BranchUpstream struct {
+	Remote string
+	Branch string
+}
+
+// SetBranchUpstream configures the local branch named to track the branch |remoteBranch| of |remote|. Returns an
+// error wrapping env.ErrRemoteNotFound if the remote doesn't exist.
+func SetBranchUpstream(ctx context.Context, dbData env.DbData, branchName, remote, remoteBranch string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	remotes, err := dbData.Rsr.GetRemotes()
+	if err != nil {
+		return err
+	}
+	if _, ok := remotes[remote]; !ok {
+		return fmt.Errorf("%w: '%s'", env.ErrRemoteNotFound, remote)
+	}
+
+	refSpec, err := ref.ParseRefSpecForRemote(remote, remoteBranch)
+	if err != nil {
+		return err
+	}
+
+	return env.SetRemoteUpstreamForRefSpec(dbData.Rsw, refSpec, remote, ref.NewBranchRef(branchName))
+}
+
+// copyBranchUpstream gives the local branch named the upstream tracking config |upstream|, as read from another
+// branch, with SetBranchUpstream. If the config's remote has since been removed, SetBranchUpstream would refuse it, so
+// it's copied as it is, to be handled wherever the branch's upstream is used.
+func copyBranchUpstream(ctx context.Context, dbData env.DbData, branchName string, upstream env.BranchConfig) error {
+	if upstream.Merge.Ref != nil && upstream.Merge.Ref.GetType() == ref.BranchRefType {
+		err := SetBranchUpstream(ctx, dbData, branchName, upstream.Remote, upstream.Merge.Ref.GetPath())
+		if !errors.Is(err, env.ErrRemoteNotFound) {
+			return err
+		}
+	}
+	return dbData.Rsw.UpdateBranch(branchName, upstream)
+}
+
+// UnsetBranchUpstream removes the upstream tracking config of the local branch named, if it has one. Returns
+// ErrUpstreamNotRemovable if the repo state of |dbData| doesn't support removing it.
+func UnsetBranchUpstream(ctx context.Context, dbData env.DbData, branchName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	branches, err := dbData.Rsr.GetBranches()
+	if err != nil {
+		return err
+	}
+	if _, ok := branches[branchName]; !ok {
+		return nil
 	}
 
-	return DeleteBranch(ctx, dbData, oldBranch, DeleteOptions{Force: true}, remoteDbPro, rsc)
+	remover, ok := dbData.Rsw.(env.BranchConfigRemover)
+	if !ok {
+		return fmt.Errorf("%w: '%s'", ErrUpstreamNotRemovable, branchName)
+	}
+	return remover.RemoveBranchConfig(branchName)
 }
 
-func CopyBranch(ctx context.Context, dEnv *env.DoltEnv, oldBranch, newBranch string, force bool) error {
-	return CopyBranchOnDB(ctx, dEnv.DoltDB, oldBranch, newBranch, force, nil)
+func CreateBranchOnDB(ctx context.Context, ddb *doltdb.DoltDB, newBranch, startingPoint string, force bool, headRef ref.DoltRef, rsc *doltdb.ReplicationStatusController) error {
+	return CreateBranchOnDBWithOptions(ctx, ddb, newBranch, startingPoint, headRef, CreateBranchOptions{Force: force}, rsc)
 }
 
-func CopyBranchOnDB(ctx context.Context, ddb *doltdb.DoltDB, oldBranch, newBranch string, force bool, rsc *doltdb.ReplicationStatusController) error {
-	oldRef := ref.NewBranchRef(oldBranch)
-	newRef := ref.NewBranchRef(newBranch)
+type CreateBranchOptions struct {
+	// Force allows creating a branch over an existing one, replacing it
+	Force bool
+	// AllowHashLikeName allows creating a branch whose name looks like a commit hash. Such a branch can't be resolved
+	// by name, since commit specs prefer the commit. Without it, such names fail with doltdb.ErrBranchNameLooksLikeHash.
+	AllowHashLikeName bool
+	// Upstream, if non-nil, is the remote branch the new branch tracks. The remote must exist, but the remote branch
+	// does not need to exist yet. Tracking config is part of the repo state, so only CreateBranchWithOptions can set
+	// it; CreateBranchOnDBWithOptions refuses it.
+	Upstream *BranchUpstream
+}
 
-	hasOld, oldErr := ddb.HasRef(ctx, oldRef)
+var errUpstreamNeedsRepoState = errors.New("a branch's upstream can only be set along with the repo state; use CreateBranchWithOptions")
 
-	if oldErr != nil {
-		return oldErr
+// CreateBranchOnDBWithOptions creates a branch named |newBranch| at |startingPoint|.
+func CreateBranchOnDBWithOptions(ctx context.Context, ddb *doltdb.DoltDB, newBranch, startingPoint string, headRef ref.DoltRef, opts CreateBranchOptions, rsc *doltdb.ReplicationStatusController) error {
+	if opts.Upstream != nil {
+		return errUpstreamNeedsRepoState
 	}
 
-	hasNew, newErr := ddb.HasRef(ctx, newRef)
+	cm, err := resolveNewBranchStartPt(ctx, ddb, newBranch, startingPoint, opts, headRef)
+	if err != nil {
+		return err
+	}
 
-	if newErr != nil {
-		return newErr
+	err = ddb.NewBranchAtCommit(ctx, ref.NewBranchRef(newBranch), cm, rsc)
+	if err != nil {
+		return err
 	}
 
-	if !hasOld {
-		return doltdb.ErrBranchNotFound
-	} else if !force && hasNew {
-		return ErrAlreadyExists
-	} else if !doltdb.IsValidUserBranchName(newBranch) {
-		return doltdb.ErrInvBranchName
+	if listener := getBranchEventListener(); listener != nil {
+		head, err := cm.HashOf()
+		if err != nil {
+			return err
+		}
+		notifyBranchCreated(ctx, listener, BranchEvent{Branch: newBranch, Head: head, Force: opts.Force})
 	}
 
-	cs, _ := doltdb.NewCommitSpec(oldBranch)
-	cm, err := ddb.Resolve(ctx, cs, nil)
+	return nil
+}
 
+// ValidateCreateBranch runs the same checks that CreateBranchWithStartPt would run for the branch name and start point
+// given, without creating the branch. It returns the same errors that CreateBranchOnDB would return.
+func ValidateCreateBranch(ctx context.Context, dbData env.DbData, newBranch, startPt string) error {
+	headRef, err := newBranchHeadRef(ctx, dbData)
 	if err != nil {
 		return err
 	}
-
-	return ddb.NewBranchAtCommit(ctx, newRef, cm, rsc)
+	_, err = resolveNewBranchStartPt(ctx, dbData.Ddb, newBranch, startPt, CreateBranchOptions{}, headRef)
+	return err
 }
 
-type DeleteOptions struct {
-	Force  bool
-	Remote bool
-}
+// resolveNewBranchStartPt validates that a branch named |newBranch| can be created and returns the commit that
+// |startingPoint| resolves to.
+func resolveNewBranchStartPt(ctx context.Context, ddb *doltdb.DoltDB, newBranch, startingPoint string, opts CreateBranchOptions, headRef ref.DoltRef) (*doltdb.Commit, error) {
+	branchRef := ref.NewBranchRef(newBranch)
+	hasRef, err := ddb.HasRef(ctx, branchRef)
+	if err != nil {
+		return nil, err
+	}
 
-func DeleteBranch(ctx context.Context, dbData env.DbData, brName string, opts DeleteOptions, remoteDbPro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) error {
-	var branchRef ref.DoltRef
-	if opts.Remote {
-		var err error
-		branchRef, err = ref.NewRemoteRefFromPathStr(brName)
-		if err != nil {
-			return err
-		}
-	} else {
-		branchRef = ref.NewBranchRef(brName)
-		headRef, err := dbData.Rsr.CWBHeadRef()
-		if err != nil {
-			return err
-		}
-		if ref.Equals(headRef, branchRef) {
-			return ErrCOBranchDelete
+	if !opts.Force && hasRef {
+		return nil, ErrAlreadyExists
+	}
+
+	if doltdb.LooksLikeCommitHash(newBranch) {
+		if !opts.AllowHashLikeName {
+			return nil, doltdb.ErrBranchNameLooksLikeHash
 		}
+	} else if !doltdb.IsValidUserBranchName(newBranch) {
+		return nil, doltdb.ErrInvBranchName
+	}
+	if err = checkUnicodeEquivalentBranch(ctx, ddb, newBranch, ""); err != nil {
+		return nil, err
 	}
 
-	return DeleteBranchOnDB(ctx, dbData, branchRef, opts, remoteDbPro, rsc)
-}
+	cs, err := doltdb.NewCommitSpec(startingPoint)
+	if err != nil {
+		return nil, err
+	}
 
-func DeleteBranchOnDB(ctx context.Context, dbdata env.DbData, branchRef ref.DoltRef, opts DeleteOptions, pro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) error {
-	ddb := dbdata.Ddb
-	hasRef, err := ddb.HasRef(ctx, branchRef)
+	cm, err := ddb.Resolve(ctx, cs, headRef)
+	if errors.Is(err, doltdb.ErrInvalidAncestorSpec) {
+		return nil, describeAncestorWalkError(ctx, ddb, startingPoint, headRef, err)
+	}
+	return cm, err
+}
 
+// checkUnicodeEquivalentBranch returns an error wrapping doltdb.ErrBranchNameCollision if an existing branch other than
+// |newBranch| and |replaced| has a name that's Unicode-equivalent to |newBranch|, e.g. "café" spelled with a
+// precomposed "é" (NFC) and with "e" followed by a combining accent (NFD). Such names are different bytes, so they'd be
+// two branches that look identical. Names are compared in normalization form NFC, but are stored as given.
+func checkUnicodeEquivalentBranch(ctx context.Context, ddb *doltdb.DoltDB, newBranch, replaced string) error {
+	branches, err := ddb.GetBranches(ctx)
 	if err != nil {
 		return err
-	} else if !hasRef {
-		return doltdb.ErrBranchNotFound
 	}
 
-	if !opts.Force && !opts.Remote {
-		// check to see if the branch is fully merged into its parent
-		trackedBranches, err := dbdata.Rsr.GetBranches()
-		if err != nil {
-			return err
+	normalized := norm.NFC.String(newBranch)
+	for _, branch := range branches {
+		name := branch.GetPath()
+		if name != newBranch && name != replaced && norm.NFC.String(name) == normalized {
+			return fmt.Errorf("%w: '%s' and '%s'", doltdb.ErrBranchNameCollision, newBranch, name)
 		}
+	}
+	return nil
+}
 
-		trackedBranch, hasUpstream := trackedBranches[branchRef.GetPath()]
-		if hasUpstream {
-			err = validateBranchMergedIntoUpstream(ctx, dbdata, branchRef, trackedBranch.Remote, pro)
-			if err != nil {
-				return err
-			}
-		} else {
-			err = validateBranchMergedIntoCurrentWorkingBranch(ctx, dbdata, branchRef)
-			if err != nil {
-				return err
-			}
-		}
+// describeAncestorWalkError returns |err|, which resolving |startingPoint| failed with because its ancestor spec walks
+// past the commits it can reach, wrapped with how many steps of the walk succeeded. E.g. for v1.0.0~10, the tag v1.0.0
+// is resolved to its commit, and then the walk is retried from there. If the walk can't be retried, |err| is returned
+// as it is.
+func describeAncestorWalkError(ctx context.Context, ddb *doltdb.DoltDB, startingPoint string, headRef ref.DoltRef, err error) error {
+	name, as, splitErr := doltdb.SplitAncestorSpec(strings.TrimSpace(startingPoint))
+	if splitErr != nil || as == nil {
+		return err
+	}
+	baseSpec, csErr := doltdb.NewCommitSpec(name)
+	if csErr != nil {
+		return err
+	}
+	cur, resolveErr := ddb.Resolve(ctx, baseSpec, headRef)
+	if resolveErr != nil {
+		return err
 	}
 
-	wsRef, err := ref.WorkingSetRefForHead(branchRef)
-	if err != nil {
-		if !errors.Is(err, ref.ErrWorkingSetUnsupported) {
-			return err
+	for i, inst := range as.Instructions {
+		if inst >= cur.NumParents() {
+			return fmt.Errorf("%w: the history of '%s' ends after %d of the %d ancestor steps in '%s'", err, name, i, len(as.Instructions), startingPoint)
 		}
-	} else {
-		err = ddb.DeleteWorkingSet(ctx, wsRef)
-		if err != nil {
+		cur, resolveErr = cur.GetParent(ctx, inst)
+		if resolveErr != nil || cur == nil {
 			return err
 		}
 	}
-
-	return ddb.DeleteBranch(ctx, branchRef, rsc)
+	return err
 }
 
-// validateBranchMergedIntoCurrentWorkingBranch returns an error if the given branch is not fully merged into the HEAD of the current branch.
-func validateBranchMergedIntoCurrentWorkingBranch(ctx context.Context, dbdata env.DbData, branch ref.DoltRef) error {
-	branchSpec, err := doltdb.NewCommitSpec(branch.GetPath())
+func createBranch(ctx context.Context, dbData env.DbData, newBranch, startingPoint string, opts CreateBranchOptions, rsc *doltdb.ReplicationStatusController) error {
+	headRef, err := newBranchHeadRef(ctx, dbData)
 	if err != nil {
 		return err
 	}
+	return CreateBranchOnDBWithOptions(ctx, dbData.Ddb, newBranch, startingPoint, headRef, opts, rsc)
+}
 
-	branchHead, err := dbdata.Ddb.Resolve(ctx, branchSpec, nil)
+// newBranchHeadRef returns the ref that HEAD refers to in the start point of a new branch. That's the current working
+// branch. The one exception is a current working branch that was never created, as right after a clone whose remote
+// has no branch with the name the clone guessed: then HEAD refers to the default branch, if that exists. A current
+// working branch that existed and was deleted is an error.
+func newBranchHeadRef(ctx context.Context, dbData env.DbData) (ref.DoltRef, error) {
+	headRef, err := dbData.Rsr.CWBHeadRef()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	cwbCs, err := doltdb.NewCommitSpec("HEAD")
+	exists, err := dbData.Ddb.HasRef(ctx, headRef)
 	if err != nil {
-		return err
+		return nil, err
+	} else if exists {
+		return headRef, nil
 	}
 
-	headRef, err := dbdata.Rsr.CWBHeadRef()
+	wasDeleted, err := dbData.Ddb.HasRef(ctx, deletedBranchRef(headRef.GetPath()))
 	if err != nil {
-		return err
+		return nil, err
+	} else if wasDeleted {
+		return nil, fmt.Errorf("%w: the current branch '%s' was deleted", doltdb.ErrBranchNotFound, headRef.GetPath())
 	}
-	cwbHead, err := dbdata.Ddb.Resolve(ctx, cwbCs, headRef)
-	if err != nil {
-		return err
+
+	defaultRef, err := DefaultBranch(ctx, dbData)
+	if errors.Is(err, ErrDefaultBranchNotFound) {
+		return nil, fmt.Errorf("%w: the current branch '%s' doesn't exist, and %s", doltdb.ErrBranchNotFound, headRef.GetPath(), err.Error())
+	} else if err != nil {
+		return nil, err
 	}
+	return defaultRef, nil
+}
 
-	isMerged, err := branchHead.CanFastForwardTo(ctx, cwbHead)
-	if err != nil {
-		if errors.Is(err, doltdb.ErrUpToDate) {
-			return nil
+// DefaultBranch returns the repository's default branch. That's the default branch configured for the database, if
+// |dbData|'s RepoStateReader supports one (see env.DefaultBranchReader) and it's configured, and env.DefaultInitBranch
+// otherwise. Returns ErrDefaultBranchNotFound if the branch doesn't exist.
+func DefaultBranch(ctx context.Context, dbData env.DbData) (ref.DoltRef, error) {
+	name := env.DefaultInitBranch
+	if dbr, ok := dbData.Rsr.(env.DefaultBranchReader); ok {
+		configured, err := dbr.GetDefaultBranch()
+		if err != nil {
+			return nil, err
 		}
-		if errors.Is(err, doltdb.ErrIsAhead) {
-			return ErrUnmergedBranch
+		if configured != "" {
+			name = configured
 		}
-
-		return err
 	}
 
-	if !isMerged {
-		return ErrUnmergedBranch
+	exists, _, err := LookupBranch(ctx, dbData.Ddb, name)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("%w: '%s'", ErrDefaultBranchNotFound, name)
 	}
-
-	return nil
+	return ref.NewBranchRef(name), nil
 }
 
-// validateBranchMergedIntoUpstream returns an error if the branch provided is not fully merged into its upstream
-func validateBranchMergedIntoUpstream(ctx context.Context, dbdata env.DbData, branch ref.DoltRef, remoteName string, pro env.RemoteDbProvider) error {
-	remotes, err := dbdata.Rsr.GetRemotes()
+// FastForwardBranch moves the head of |branch| to |toCommit| if that is a fast-forward. Returns doltdb.ErrIsAhead if
+// the branch already contains |toCommit|, and ErrNotFastForward if the histories have diverged. The branch's working
+// set is moved along with its head, so the update is refused with ErrUncommittedChanges if the branch has any
+// uncommitted changes.
+func FastForwardBranch(ctx context.Context, dbData env.DbData, branch ref.DoltRef, toCommit *doltdb.Commit, rsc *doltdb.ReplicationStatusController) error {
+	ddb := dbData.Ddb
+	head, err := ddb.ResolveCommitRef(ctx, branch)
 	if err != nil {
 		return err
 	}
-	remote, ok := remotes[remoteName]
-	if !ok {
-		// TODO: skip error?
-		return fmt.Errorf("remote %s not found", remoteName)
-	}
 
-	remoteDb, err := pro.GetRemoteDB(ctx, dbdata.Ddb.ValueReadWriter().Format(), remote, false)
-	if err != nil {
+	canFF, err := head.CanFastForwardTo(ctx, toCommit)
+	if errors.Is(err, doltdb.ErrUpToDate) {
+		return nil
+	} else if errors.Is(err, doltdb.ErrNoCommonAncestor) {
+		return ErrNotFastForward
+	} else if err != nil {
 		return err
+	} else if !canFF {
+		return ErrNotFastForward
 	}
 
-	cs, err := doltdb.NewCommitSpec(branch.GetPath())
+	dirty, err := branchHasUncommittedChanges(ctx, ddb, branch, head)
 	if err != nil {
 		return err
+	} else if dirty {
+		return ErrUncommittedChanges
 	}
 
-	remoteBranchHead, err := remoteDb.Resolve(ctx, cs, nil)
+	return ddb.NewBranchAtCommit(ctx, branch, toCommit, rsc)
+}
+
+// AdvanceBranchTo moves the head of |branch| to the commit with hash |commit|, e.g. one made in a detached state, if
+// that commit descends from the branch's current head. It's FastForwardBranch for a commit known only by its hash, and
+// returns the same errors: doltdb.ErrIsAhead if the branch already contains the commit, and ErrNotFastForward if their
+// histories have diverged. Callers that want to move the branch anyway can use SetBranchHead.
+func AdvanceBranchTo(ctx context.Context, dbData env.DbData, branch ref.DoltRef, commit hash.Hash, rsc *doltdb.ReplicationStatusController) error {
+	toCommit, err := dbData.Ddb.ReadCommit(ctx, commit)
 	if err != nil {
 		return err
 	}
+	return FastForwardBranch(ctx, dbData, branch, toCommit, rsc)
+}
 
-	localBranchHead, err := dbdata.Ddb.Resolve(ctx, cs, nil)
+// CompareAndSwapBranch moves the head of |branch| from the commit with hash |expected| to the one with hash |newHead|,
+// and returns whether it did. If the branch's head isn't |expected| when the update is made, e.g. because a concurrent
+// writer moved it first, the branch is left as it is and false is returned. The branch's working set isn't changed, as
+// with SetBranchHead without updateWorkingSet. Returns doltdb.ErrBranchNotFound if the branch doesn't exist.
+func CompareAndSwapBranch(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef, expected, newHead hash.Hash, rsc *doltdb.ReplicationStatusController) (bool, error) {
+	hasRef, err := ddb.HasRef(ctx, branch)
 	if err != nil {
-		return err
+		return false, err
+	} else if !hasRef {
+		return false, fmt.Errorf("%w: %s", doltdb.ErrBranchNotFound, branch.GetPath())
 	}
+	return ddb.CompareAndSwapHead(ctx, branch, expected, newHead, rsc)
+}
 
-	canFF, err := localBranchHead.CanFastForwardTo(ctx, remoteBranchHead)
+// SetBranchHead moves the head of the existing branch |branch| to |toCommit|, which need not be related to its current
+// head. If |updateWorkingSet| is true, the branch's working and staged roots are reset to the root of |toCommit|, as
+// with `reset --hard`; this is refused with ErrUncommittedChanges if the branch has uncommitted changes, unless |force|
+// is true. Otherwise the working set is left as is.
+func SetBranchHead(ctx context.Context, dbData env.DbData, branch ref.DoltRef, toCommit *doltdb.Commit, updateWorkingSet, force bool, rsc *doltdb.ReplicationStatusController) error {
+	ddb := dbData.Ddb
+	head, err := ddb.ResolveCommitRef(ctx, branch)
 	if err != nil {
-		if errors.Is(err, doltdb.ErrUpToDate) {
-			return nil
-		}
-		if errors.Is(err, doltdb.ErrIsAhead) {
-			return ErrUnmergedBranch
-		}
 		return err
 	}
 
-	if !canFF {
-		return ErrUnmergedBranch
+	if !updateWorkingSet {
+		return ddb.SetHeadToCommit(ctx, branch, toCommit)
 	}
 
-	return nil
+	if !force {
+		dirty, err := branchHasUncommittedChanges(ctx, ddb, branch, head)
+		if err != nil {
+			return err
+		} else if dirty {
+			return ErrUncommittedChanges
+		}
+	}
+
+	return ddb.NewBranchAtCommit(ctx, branch, toCommit, rsc)
 }
 
-func CreateBranchWithStartPt(ctx context.Context, dbData env.DbData, newBranch, startPt string, force bool, rsc *doltdb.ReplicationStatusController) error {
-	err := createBranch(ctx, dbData, newBranch, startPt, force, rsc)
+// branchHasUncommittedChanges returns whether the working set of |branch| has a working or staged root that differs
+// from the root of |head|. Branches without a working set have no uncommitted changes.
+func branchHasUncommittedChanges(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef, head *doltdb.Commit) (bool, error) {
+	wsRef, err := ref.WorkingSetRefForHead(branch)
+	if err != nil {
+		return false, err
+	}
 
+	ws, err := ddb.ResolveWorkingSet(ctx, wsRef)
+	if errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	headRoot, err := head.GetRootValue(ctx)
 	if err != nil {
-		if err == ErrAlreadyExists {
-			return fmt.Errorf("fatal: A branch named '%s' already exists.", newBranch)
-		} else if err == doltdb.ErrInvBranchName {
-			return fmt.Errorf("fatal: '%s' is an invalid branch name.", newBranch)
-		} else if err == doltdb.ErrInvHash || doltdb.IsNotACommit(err) {
-			return fmt.Errorf("fatal: '%s' is not a commit and a branch '%s' cannot be created from it", startPt, newBranch)
-		} else {
-			return fmt.Errorf("fatal: Unexpected error creating branch '%s' : %v", newBranch, err)
-		}
+		return false, err
 	}
-	err = branch_control.AddAdminForContext(ctx, newBranch)
+	headHash, err := headRoot.HashOf()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	for _, root := range []*doltdb.RootValue{ws.WorkingRoot(), ws.StagedRoot()} {
+		h, err := root.HashOf()
+		if err != nil {
+			return false, err
+		}
+		if h != headHash {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
-func CreateBranchOnDB(ctx context.Context, ddb *doltdb.DoltDB, newBranch, startingPoint string, force bool, headRef ref.DoltRef, rsc *doltdb.ReplicationStatusController) error {
-	branchRef := ref.NewBranchRef(newBranch)
-	hasRef, err := ddb.HasRef(ctx, branchRef)
+// BranchHeadError describes a branch whose head could not be resolved to a commit
+type BranchHeadError struct {
+	Branch ref.DoltRef
+	Err    error
+}
+
+func (e BranchHeadError) Error() string {
+	return fmt.Sprintf("branch '%s' does not resolve to a valid commit: %v", e.Branch.GetPath(), e.Err)
+}
+
+func (e BranchHeadError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyBranchHeads checks that the head of every branch in |ddb| resolves to a valid commit, and returns an error for
+// each branch whose head doesn't. The returned error is non-nil only if the branches themselves can't be listed.
+func VerifyBranchHeads(ctx context.Context, ddb *doltdb.DoltDB) ([]BranchHeadError, error) {
+	branches, err := ddb.GetBranches(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if !force && hasRef {
-		return ErrAlreadyExists
-	}
+	headErrs := []BranchHeadError{}
+	for _, branch := range branches {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-	if !doltdb.IsValidUserBranchName(newBranch) {
-		return doltdb.ErrInvBranchName
+		_, err := ddb.ResolveCommitRef(ctx, branch)
+		if err != nil {
+			headErrs = append(headErrs, BranchHeadError{Branch: branch, Err: err})
+		}
 	}
 
-	cs, err := doltdb.NewCommitSpec(startingPoint)
+	return headErrs, nil
+}
+
+// BranchesContaining returns the branches in |ddb| whose history includes the commit with hash |target|.
+func BranchesContaining(ctx context.Context, ddb *doltdb.DoltDB, target hash.Hash) ([]ref.DoltRef, error) {
+	targetCommit, err := ddb.ReadCommit(ctx, target)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	cm, err := ddb.Resolve(ctx, cs, headRef)
+	branches, err := ddb.GetBranches(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = ddb.NewBranchAtCommit(ctx, branchRef, cm, rsc)
-	if err != nil {
-		return err
+	ancestry := NewAncestryCache()
+	var containing []ref.DoltRef
+	for _, branch := range branches {
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		if err != nil {
+			return nil, err
+		}
+
+		contains, err := ancestry.IsAncestor(ctx, targetCommit, head)
+		if err != nil {
+			return nil, err
+		}
+		if contains {
+			containing = append(containing, branch)
+		}
 	}
 
-	return nil
+	return containing, nil
 }
 
-func createBranch(ctx context.Context, dbData env.DbData, newBranch, startingPoint string, force bool, rsc *doltdb.ReplicationStatusController) error {
+// PruneMergedBranches deletes every branch other than the current working branch whose head is in the history of the
+// current working branch, and returns the names of the branches deleted. Deleted branches can be restored with
+// RecoverDeletedBranch. Branches are evaluated in name order, and |progress|, if non-nil, is called after each one with
+// whether it was deleted. If |ctx| is canceled, pruning stops before the next branch and the branches already deleted
+// are returned along with the context's error.
+func PruneMergedBranches(ctx context.Context, dbData env.DbData, progress func(branch string, deleted bool), rsc *doltdb.ReplicationStatusController) ([]string, error) {
+	ddb := dbData.Ddb
 	headRef, err := dbData.Rsr.CWBHeadRef()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	cwbHead, err := ddb.ResolveCommitRef(ctx, headRef)
+	if err != nil {
+		return nil, err
+	}
+
+	branches, err := ddb.GetBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].GetPath() < branches[j].GetPath()
+	})
+
+	ancestry := NewAncestryCache()
+	var deleted []string
+	for _, branch := range branches {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		if ref.Equals(branch, headRef) {
+			continue
+		}
+
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		if err != nil {
+			return deleted, err
+		}
+		merged, err := ancestry.IsAncestor(ctx, head, cwbHead)
+		if err != nil {
+			return deleted, err
+		}
+
+		if merged {
+			err = DeleteBranch(ctx, dbData, branch.GetPath(), DeleteOptions{Force: true}, nil, rsc)
+			if err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, branch.GetPath())
+		}
+
+		if progress != nil {
+			progress(branch.GetPath(), merged)
+		}
 	}
-	return CreateBranchOnDB(ctx, dbData.Ddb, newBranch, startingPoint, force, headRef, rsc)
+
+	return deleted, nil
 }
 
 var emptyHash = hash.Hash{}
@@ -355,6 +1703,22 @@ func IsBranchOnDB(ctx context.Context, ddb *doltdb.DoltDB, str string) (bool, er
 	return ddb.HasRef(ctx, dref)
 }
 
+// LookupBranch returns whether the branch named exists in |ddb| and, if it does, the hash of its head commit.
+func LookupBranch(ctx context.Context, ddb *doltdb.DoltDB, name string) (bool, hash.Hash, error) {
+	if !ref.IsValidBranchName(name) {
+		return false, hash.Hash{}, nil
+	}
+
+	head, err := ddb.GetHashForRefStr(ctx, ref.NewBranchRef(name).String())
+	if errors.Is(err, doltdb.ErrBranchNotFound) {
+		return false, hash.Hash{}, nil
+	} else if err != nil {
+		return false, hash.Hash{}, err
+	}
+
+	return true, *head, nil
+}
+
 func MaybeGetCommit(ctx context.Context, dEnv *env.DoltEnv, str string) (*doltdb.Commit, error) {
 	cs, err := doltdb.NewCommitSpec(str)
 
@@ -383,3 +1747,53 @@ func MaybeGetCommit(ctx context.Context, dEnv *env.DoltEnv, str string) (*doltdb
 
 	return nil, nil
 }
+
+// IsAncestor returns whether the commit |ancestorSpec| resolves to is in the history of the commit |descendantSpec|
+// resolves to. A commit is its own ancestor. Relative specs like HEAD~ are resolved against the current working
+// branch.
+func IsAncestor(ctx context.Context, dEnv *env.DoltEnv, ancestorSpec, descendantSpec string) (bool, error) {
+	headRef, err := dEnv.RepoStateReader().CWBHeadRef()
+	if err != nil {
+		return false, err
+	}
+
+	var commits [2]*doltdb.Commit
+	for i, spec := range []string{ancestorSpec, descendantSpec} {
+		cs, err := doltdb.NewCommitSpec(spec)
+		if err != nil {
+			return false, err
+		}
+		commits[i], err = dEnv.DoltDB.Resolve(ctx, cs, headRef)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var ancestry *AncestryCache
+	return ancestry.IsAncestor(ctx, commits[0], commits[1])
+}
+
+// EstimateReclaimableChunks returns the total size in bytes of the chunks reachable only from |branches|, their
+// working sets and their deleted branch records, and from no other ref. This is an estimate of the storage that would
+// be freed by deleting the branches and running garbage collection; note that DeleteBranch keeps a record of a deleted
+// branch's head for RecoverDeletedBranch, so nothing reachable from the head is freed until that record is recovered or
+// discarded with PurgeDeletedBranches.
+// Sizes are of uncompressed chunk data, so the estimate is an upper bound on the space freed on disk by the chunks
+// counted, but garbage that is already unreferenced isn't counted at all. Every chunk in the database is walked, so
+// this is expensive on large databases.
+func EstimateReclaimableChunks(ctx context.Context, ddb *doltdb.DoltDB, branches []ref.DoltRef) (uint64, error) {
+	refs := make([]ref.DoltRef, 0, 3*len(branches))
+	for _, branch := range branches {
+		wsRef, err := ref.WorkingSetRefForHead(branch)
+		if err != nil {
+			return 0, err
+		}
+		refs = append(refs, branch, wsRef)
+		if branch.GetType() == ref.BranchRefType {
+			refs = append(refs, deletedBranchRef(branch.GetPath()))
+		}
+	}
+
+	_, size, err := ddb.UniquelyReachableChunks(ctx, refs)
+	return size, err
+}
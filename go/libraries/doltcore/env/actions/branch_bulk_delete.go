@@ -0,0 +1,184 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// ErrBranchesChanged is returned by DeleteBranches when a branch it validated was updated before it could be deleted
+var ErrBranchesChanged = errors.New("a branch was updated while the branches were being deleted")
+
+// BranchDeleteError is returned by DeleteBranches when one of the branches it matched can't be deleted, or when one of
+// its patterns matches no branch
+type BranchDeleteError struct {
+	// Branch is the name of the branch that can't be deleted, or the pattern that matched no branch
+	Branch string
+	Err    error
+}
+
+func (e BranchDeleteError) Error() string {
+	return fmt.Sprintf("unable to delete branch '%s': %v", e.Branch, e.Err)
+}
+
+func (e BranchDeleteError) Unwrap() error {
+	return e.Err
+}
+
+// DeleteBranches deletes every branch whose name matches one of |patterns|, which are shell patterns as accepted by
+// path.Match, e.g. feature/*, and returns the names of the branches deleted, sorted. With opts.Remote, the patterns
+// are matched against remote tracking branches, e.g. origin/feature/*. A pattern that matches no branch is a
+// BranchDeleteError wrapping doltdb.ErrBranchNotFound, so that a mistyped name is never silently ignored. A plain
+// branch name is a pattern that matches only itself.
+//
+// Every branch is validated, as DeleteBranch does, and then all of them are deleted in a single update of the ref
+// store, so either every branch is deleted or none is. If one of them was updated after it was validated, none is
+// deleted and ErrBranchesChanged is returned. Records for RecoverDeletedBranch, working sets and descriptions are
+// written and removed after the branches are deleted.
+func DeleteBranches(ctx context.Context, dbData env.DbData, patterns []string, opts DeleteOptions, remoteDbPro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) ([]string, error) {
+	ddb := dbData.Ddb
+	matched, err := matchBranchPatterns(ctx, ddb, patterns, opts.Remote)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Remote {
+		headRef, err := dbData.Rsr.CWBHeadRef()
+		if err != nil {
+			return nil, err
+		}
+		for _, branchRef := range matched {
+			if ref.Equals(headRef, branchRef) {
+				return nil, BranchDeleteError{Branch: branchRef.GetPath(), Err: ErrCOBranchDelete}
+			}
+		}
+
+		if opts.CurrentHead == nil {
+			opts.CurrentHead = NewCurrentHeadCache(nil)
+		}
+	}
+	if opts.Ancestry == nil {
+		opts.Ancestry = NewAncestryCache()
+	}
+
+	heads := make([]hash.Hash, len(matched))
+	for i, branchRef := range matched {
+		err = validateBranchDeletion(ctx, dbData, branchRef, opts, remoteDbPro)
+		if err != nil {
+			return nil, BranchDeleteError{Branch: branchRef.GetPath(), Err: err}
+		}
+		head, err := ddb.GetHashForRefStr(ctx, branchRef.String())
+		if err != nil {
+			return nil, err
+		}
+		heads[i] = *head
+	}
+
+	ok, err := ddb.DeleteBranchesAt(ctx, matched, heads, rsc)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrBranchesChanged
+	}
+
+	names := make([]string, len(matched))
+	listener := getBranchEventListener()
+	for i, branchRef := range matched {
+		names[i] = branchRef.GetPath()
+		if branchRef.GetType() == ref.BranchRefType {
+			err = ddb.SetHead(ctx, deletedBranchRef(names[i]), heads[i])
+			if err != nil {
+				logrus.Warnf("unable to record head of deleted branch %s, it will not be recoverable: %v", names[i], err)
+			}
+		}
+		if !opts.KeepWorkingSet {
+			deleteOrphanedWorkingSet(ctx, ddb, branchRef)
+			deleteOrphanedBranchDescription(dbData, branchRef)
+		}
+		if listener != nil && branchRef.GetType() == ref.BranchRefType {
+			notifyBranchDeleted(ctx, listener, BranchEvent{Branch: names[i], Head: heads[i], Force: opts.Force || opts.Destroy})
+		}
+	}
+	return names, nil
+}
+
+// matchBranchPatterns returns the local branches, or the remote tracking branches if |remote| is true, whose names
+// match any of |patterns|, sorted by name
+func matchBranchPatterns(ctx context.Context, ddb *doltdb.DoltDB, patterns []string, remote bool) ([]ref.DoltRef, error) {
+	var refs []ref.DoltRef
+	var err error
+	if remote {
+		refs, err = ddb.GetRemoteRefs(ctx)
+	} else {
+		refs, err = ddb.GetBranches(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]ref.DoltRef)
+	for _, pattern := range patterns {
+		found := false
+		for _, r := range refs {
+			ok, err := path.Match(pattern, r.GetPath())
+			if err != nil {
+				return nil, fmt.Errorf("invalid branch pattern '%s': %w", pattern, err)
+			}
+			if ok {
+				matched[r.GetPath()] = r
+				found = true
+			}
+		}
+		if !found {
+			return nil, BranchDeleteError{Branch: pattern, Err: doltdb.ErrBranchNotFound}
+		}
+	}
+
+	result := make([]ref.DoltRef, 0, len(matched))
+	for _, r := range matched {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetPath() < result[j].GetPath()
+	})
+	return result, nil
+}
+
+// deleteOrphanedWorkingSet deletes the working set of the deleted branch given, logging any failure. The branch is
+// already gone, so a leftover working set is only unreachable storage.
+func deleteOrphanedWorkingSet(ctx context.Context, ddb *doltdb.DoltDB, branchRef ref.DoltRef) {
+	wsRef, err := ref.WorkingSetRefForHead(branchRef)
+	if errors.Is(err, ref.ErrWorkingSetUnsupported) {
+		return
+	} else if err != nil {
+		logrus.Warnf("unable to delete working set of deleted branch %s: %v", branchRef.GetPath(), err)
+		return
+	}
+	err = ddb.DeleteWorkingSet(ctx, wsRef)
+	if err != nil && err != doltdb.ErrWorkingSetNotFound {
+		logrus.Warnf("unable to delete working set of deleted branch %s: %v", branchRef.GetPath(), err)
+	}
+}
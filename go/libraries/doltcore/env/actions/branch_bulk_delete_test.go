@@ -0,0 +1,101 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+)
+
+func TestDeleteBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	dbData := dEnv.DbData()
+
+	for _, name := range []string{"feature/a", "feature/b", "feature/unmerged", "fix/c", "release"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dbData, name, "main", false, nil))
+	}
+	createTestCommits(t, dEnv, "feature/unmerged", 1)
+
+	assertBranches := func(expected ...string) {
+		branches, err := dEnv.DoltDB.GetBranches(ctx)
+		require.NoError(t, err)
+		var names []string
+		for _, b := range branches {
+			names = append(names, b.GetPath())
+		}
+		assert.ElementsMatch(t, expected, names)
+	}
+	all := []string{"main", "feature/a", "feature/b", "feature/unmerged", "fix/c", "release"}
+
+	// one unmerged branch keeps every match from being deleted
+	_, err := DeleteBranches(ctx, dbData, []string{"feature/*"}, DeleteOptions{}, nil, nil)
+	assert.ErrorIs(t, err, ErrUnmergedBranch)
+	var deleteErr BranchDeleteError
+	require.True(t, errors.As(err, &deleteErr))
+	assert.Equal(t, "feature/unmerged", deleteErr.Branch)
+	assertBranches(all...)
+
+	_, err = DeleteBranches(ctx, dbData, []string{"fix/*", "hotfix/*"}, DeleteOptions{}, nil, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+	assertBranches(all...)
+
+	_, err = DeleteBranches(ctx, dbData, []string{"ma?n"}, DeleteOptions{Force: true}, nil, nil)
+	assert.ErrorIs(t, err, ErrCOBranchDelete)
+	assertBranches(all...)
+
+	deleted, err := DeleteBranches(ctx, dbData, []string{"feature/[ab]", "fix/*"}, DeleteOptions{}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature/a", "feature/b", "fix/c"}, deleted)
+	assertBranches("main", "feature/unmerged", "release")
+
+	deleted, err = DeleteBranches(ctx, dbData, []string{"feature/*", "release"}, DeleteOptions{Force: true}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature/unmerged", "release"}, deleted)
+	assertBranches("main")
+}
+
+func TestDeleteBranchesRecordsDeletions(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	dbData := dEnv.DbData()
+
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dbData, name, "main", false, nil))
+	}
+	createTestCommits(t, dEnv, "a", 1)
+	head := branchHeadHash(t, dEnv, "a")
+
+	deleted, err := DeleteBranches(ctx, dbData, []string{"a", "b"}, DeleteOptions{Force: true}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, deleted)
+
+	recorded, err := DeletedBranches(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Contains(t, recorded, "a")
+	assert.Contains(t, recorded, "b")
+
+	require.NoError(t, RecoverDeletedBranch(ctx, dbData, "a"))
+	assert.Equal(t, head, branchHeadHash(t, dEnv, "a"))
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+var ErrBranchDescriptionUnsupported = errors.New("branch descriptions can't be stored in this database")
+
+// GetBranchDescriptions returns the description of each local branch that has one, keyed by branch name. Returns an
+// empty map if the repo state of |dbData| can't store descriptions (see env.BranchDescriber).
+func GetBranchDescriptions(dbData env.DbData) (map[string]string, error) {
+	describer, ok := dbData.Rsr.(env.BranchDescriber)
+	if !ok {
+		return map[string]string{}, nil
+	}
+	descriptions, err := describer.GetBranchDescriptions()
+	if err != nil {
+		return nil, err
+	}
+	if descriptions == nil {
+		descriptions = map[string]string{}
+	}
+	return descriptions, nil
+}
+
+// GetBranchDescription returns the description of the local branch named, or the empty string if it has none
+func GetBranchDescription(dbData env.DbData, branchName string) (string, error) {
+	descriptions, err := GetBranchDescriptions(dbData)
+	if err != nil {
+		return "", err
+	}
+	return descriptions[branchName], nil
+}
+
+// SetBranchDescription sets the description of the existing local branch named, removing it if |description| is
+// empty. Descriptions are stored with the repo state rather than in the database, so, like upstream configs, they
+// aren't pushed or cloned. Returns ErrBranchDescriptionUnsupported if the repo state of |dbData| can't store them.
+func SetBranchDescription(ctx context.Context, dbData env.DbData, branchName, description string) error {
+	hasRef, err := dbData.Ddb.HasRef(ctx, ref.NewBranchRef(branchName))
+	if err != nil {
+		return err
+	} else if !hasRef {
+		return fmt.Errorf("%w: '%s'", doltdb.ErrBranchNotFound, branchName)
+	}
+
+	describer, ok := dbData.Rsw.(env.BranchDescriber)
+	if !ok {
+		return ErrBranchDescriptionUnsupported
+	}
+	return describer.SetBranchDescription(branchName, description)
+}
+
+// CopyBranchDescription gives |newBranch| the description of |oldBranch|, replacing any description it had, or
+// removing it if |oldBranch| has none. Does nothing if the repo state of |dbData| can't store descriptions.
+func CopyBranchDescription(dbData env.DbData, oldBranch, newBranch string) error {
+	describer, ok := dbData.Rsw.(env.BranchDescriber)
+	if !ok || oldBranch == newBranch {
+		return nil
+	}
+
+	description, err := GetBranchDescription(dbData, oldBranch)
+	if err != nil {
+		return err
+	}
+	return describer.SetBranchDescription(newBranch, description)
+}
+
+// moveBranchDescription moves the description of renamed branch |oldBranch| to |newBranch|
+func moveBranchDescription(dbData env.DbData, oldBranch, newBranch string) error {
+	if oldBranch == newBranch {
+		return nil
+	}
+	err := CopyBranchDescription(dbData, oldBranch, newBranch)
+	if err != nil {
+		return err
+	}
+	return removeBranchDescription(dbData, oldBranch)
+}
+
+// removeBranchDescription removes the description of the branch named, if it has one
+func removeBranchDescription(dbData env.DbData, branchName string) error {
+	describer, ok := dbData.Rsw.(env.BranchDescriber)
+	if !ok {
+		return nil
+	}
+	return describer.SetBranchDescription(branchName, "")
+}
+
+// deleteOrphanedBranchDescription removes the description of the deleted branch given, logging any failure. The
+// branch is already gone, so a leftover description is only clutter, until a branch with the same name inherits it.
+func deleteOrphanedBranchDescription(dbData env.DbData, branchRef ref.DoltRef) {
+	if branchRef.GetType() != ref.BranchRefType {
+		return
+	}
+	err := removeBranchDescription(dbData, branchRef.GetPath())
+	if err != nil {
+		logrus.Warnf("unable to delete description of deleted branch %s: %v", branchRef.GetPath(), err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+)
+
+func TestBranchDescriptions(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	dbData := dEnv.DbData()
+
+	assertDescription := func(branch, expected string) {
+		description, err := GetBranchDescription(dbData, branch)
+		require.NoError(t, err)
+		assert.Equal(t, expected, description, "description of %s", branch)
+	}
+
+	err := SetBranchDescription(ctx, dbData, "missing", "nope")
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dbData, "a", "main", false, nil))
+	require.NoError(t, SetBranchDescription(ctx, dbData, "a", "adds the widgets table"))
+	assertDescription("a", "adds the widgets table")
+	assertDescription("main", "")
+
+	// descriptions are saved with the repo state
+	repoState, err := env.LoadRepoState(dEnv.FS)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "adds the widgets table"}, repoState.BranchDescriptions)
+
+	require.NoError(t, CopyBranch(ctx, dEnv, "a", "b", false))
+	assertDescription("a", "adds the widgets table")
+	assertDescription("b", "adds the widgets table")
+
+	// a forced copy replaces the description of the branch it overwrites
+	require.NoError(t, SetBranchDescription(ctx, dbData, "b", "something else"))
+	require.NoError(t, CopyBranch(ctx, dEnv, "main", "b", true))
+	assertDescription("b", "")
+
+	require.NoError(t, RenameBranch(ctx, dbData, "a", "c", nil, false, nil))
+	assertDescription("a", "")
+	assertDescription("c", "adds the widgets table")
+
+	// a branch deleted with its working set kept can be reattached, so its description is kept too
+	require.NoError(t, DeleteBranch(ctx, dbData, "c", DeleteOptions{Force: true, KeepWorkingSet: true}, nil, nil))
+	assertDescription("c", "adds the widgets table")
+	require.NoError(t, RecoverDeletedBranch(ctx, dbData, "c"))
+
+	require.NoError(t, DeleteBranch(ctx, dbData, "c", DeleteOptions{Force: true}, nil, nil))
+	assertDescription("c", "")
+
+	require.NoError(t, SetBranchDescription(ctx, dbData, "b", "short lived"))
+	require.NoError(t, SetBranchDescription(ctx, dbData, "b", ""))
+	descriptions, err := GetBranchDescriptions(dbData)
+	require.NoError(t, err)
+	assert.Empty(t, descriptions)
+}
+
+func TestDeleteBranchesRemovesDescriptions(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	dbData := dEnv.DbData()
+
+	for _, name := range []string{"feature/a", "feature/b", "fix/c"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dbData, name, "main", false, nil))
+		require.NoError(t, SetBranchDescription(ctx, dbData, name, "about "+name))
+	}
+
+	_, err := DeleteBranches(ctx, dbData, []string{"feature/*"}, DeleteOptions{}, nil, nil)
+	require.NoError(t, err)
+
+	descriptions, err := GetBranchDescriptions(dbData)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"fix/c": "about fix/c"}, descriptions)
+}
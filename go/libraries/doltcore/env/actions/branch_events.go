@@ -0,0 +1,81 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// BranchEvent describes a change to a branch that has been made.
+type BranchEvent struct {
+	// Branch is the name of the branch created or deleted, or the new name of a renamed branch
+	Branch string
+	// OldBranch is the old name of a renamed branch, and empty for other events
+	OldBranch string
+	// Head is the hash of the branch's head commit: the new head of a created or renamed branch, or the last head of a
+	// deleted branch
+	Head hash.Hash
+	// Force is whether the change was forced
+	Force bool
+}
+
+// BranchEventListener is notified of branches being created, deleted and renamed, e.g. to record them in an audit log.
+// Its methods are called after the change has been made, so errors they return are logged and otherwise ignored.
+// Methods may be called concurrently.
+type BranchEventListener interface {
+	OnCreate(ctx context.Context, event BranchEvent) error
+	OnDelete(ctx context.Context, event BranchEvent) error
+	OnRename(ctx context.Context, event BranchEvent) error
+}
+
+var branchEventListenerMu sync.RWMutex
+var branchEventListener BranchEventListener
+
+// SetBranchEventListener sets the listener notified of branch changes made by CreateBranchOnDB, DeleteBranchOnDB and
+// RenameBranch. A nil listener, the default, disables notifications.
+func SetBranchEventListener(listener BranchEventListener) {
+	branchEventListenerMu.Lock()
+	defer branchEventListenerMu.Unlock()
+	branchEventListener = listener
+}
+
+func getBranchEventListener() BranchEventListener {
+	branchEventListenerMu.RLock()
+	defer branchEventListenerMu.RUnlock()
+	return branchEventListener
+}
+
+func notifyBranchCreated(ctx context.Context, listener BranchEventListener, event BranchEvent) {
+	if err := listener.OnCreate(ctx, event); err != nil {
+		logrus.Warnf("branch event listener failed on creation of branch %s: %v", event.Branch, err)
+	}
+}
+
+func notifyBranchDeleted(ctx context.Context, listener BranchEventListener, event BranchEvent) {
+	if err := listener.OnDelete(ctx, event); err != nil {
+		logrus.Warnf("branch event listener failed on deletion of branch %s: %v", event.Branch, err)
+	}
+}
+
+func notifyBranchRenamed(ctx context.Context, listener BranchEventListener, event BranchEvent) {
+	if err := listener.OnRename(ctx, event); err != nil {
+		logrus.Warnf("branch event listener failed on rename of branch %s to %s: %v", event.OldBranch, event.Branch, err)
+	}
+}
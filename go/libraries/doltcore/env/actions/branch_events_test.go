@@ -0,0 +1,83 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+)
+
+type recordingBranchListener struct {
+	events []string
+	last   BranchEvent
+	err    error
+}
+
+func (l *recordingBranchListener) record(kind string, event BranchEvent) error {
+	l.events = append(l.events, kind)
+	l.last = event
+	return l.err
+}
+
+func (l *recordingBranchListener) OnCreate(_ context.Context, event BranchEvent) error {
+	return l.record("create", event)
+}
+
+func (l *recordingBranchListener) OnDelete(_ context.Context, event BranchEvent) error {
+	return l.record("delete", event)
+}
+
+func (l *recordingBranchListener) OnRename(_ context.Context, event BranchEvent) error {
+	return l.record("rename", event)
+}
+
+func TestBranchEventListener(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	commits := createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	head, err := commits[1].HashOf()
+	require.NoError(t, err)
+
+	listener := &recordingBranchListener{}
+	SetBranchEventListener(listener)
+	defer SetBranchEventListener(nil)
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "b1", "main", false, nil))
+	assert.Equal(t, []string{"create"}, listener.events)
+	assert.Equal(t, BranchEvent{Branch: "b1", Head: head}, listener.last)
+
+	require.NoError(t, RenameBranch(ctx, dEnv.DbData(), "b1", "b2", dEnv, false, nil))
+	assert.Equal(t, []string{"create", "rename"}, listener.events)
+	assert.Equal(t, BranchEvent{Branch: "b2", OldBranch: "b1", Head: head}, listener.last)
+
+	// listener failures don't fail the operation
+	listener.err = errors.New("audit log unavailable")
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "b2", DeleteOptions{Force: true}, dEnv, nil))
+	assert.Equal(t, []string{"create", "rename", "delete"}, listener.events)
+	assert.Equal(t, BranchEvent{Branch: "b2", Head: head, Force: true}, listener.last)
+
+	// failed operations aren't reported
+	assert.Error(t, DeleteBranch(ctx, dEnv.DbData(), "b2", DeleteOptions{}, dEnv, nil))
+	assert.Len(t, listener.events, 3)
+}
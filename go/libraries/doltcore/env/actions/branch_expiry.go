@@ -0,0 +1,251 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+)
+
+// branchExpireAfterSuffix is the suffix of the config keys that override env.BranchExpireAfterKey for a single branch,
+// as in branch.<name>.expireafter
+const branchExpireAfterSuffix = ".expireafter"
+
+// BranchExpiryPolicy decides how long after its last commit a branch is deleted by PruneStaleBranches
+type BranchExpiryPolicy struct {
+	// ExpireAfter is the age at which branches expire. Zero means that branches never expire, unless overridden.
+	ExpireAfter time.Duration
+	// Overrides holds the age at which individual branches expire, keyed by lower case branch name, replacing
+	// ExpireAfter. An override of zero means that the branch never expires.
+	Overrides map[string]time.Duration
+}
+
+// ExpireAfterFor returns the age at which |branch| expires, or zero if it never does
+func (p BranchExpiryPolicy) ExpireAfterFor(branch string) time.Duration {
+	if ttl, ok := p.Overrides[strings.ToLower(branch)]; ok {
+		return ttl
+	}
+	return p.ExpireAfter
+}
+
+// BranchExpiryPolicyFromConfig reads the branch expiry policy from |cfg|: the default from env.BranchExpireAfterKey,
+// and the override for each branch from branch.<name>.expireafter. Config keys are stored in lower case, so overrides
+// match branch names case-insensitively. Values are parsed with ParseBranchExpireAfter.
+func BranchExpiryPolicyFromConfig(cfg config.ReadableConfig) (BranchExpiryPolicy, error) {
+	var policy BranchExpiryPolicy
+	if val, err := cfg.GetString(env.BranchExpireAfterKey); err == nil {
+		policy.ExpireAfter, err = ParseBranchExpireAfter(val)
+		if err != nil {
+			return BranchExpiryPolicy{}, fmt.Errorf("invalid %s: %w", env.BranchExpireAfterKey, err)
+		}
+	}
+
+	// A config hierarchy iterates over the keys of each of its configs, qualified with the config's name, so collect
+	// the unqualified keys and get their values from |cfg|, which takes precedence between configs into account
+	overrideKeys := make(map[string]struct{})
+	cfg.Iter(func(key, _ string) (stop bool) {
+		if i := strings.LastIndex(key, "::"); i >= 0 {
+			key = key[i+2:]
+		}
+		if key != env.BranchExpireAfterKey && strings.HasPrefix(key, "branch.") && strings.HasSuffix(key, branchExpireAfterSuffix) {
+			overrideKeys[key] = struct{}{}
+		}
+		return false
+	})
+
+	for key := range overrideKeys {
+		branch := strings.TrimSuffix(strings.TrimPrefix(key, "branch."), branchExpireAfterSuffix)
+		val, err := cfg.GetString(key)
+		if err != nil {
+			return BranchExpiryPolicy{}, err
+		}
+		ttl, err := ParseBranchExpireAfter(val)
+		if err != nil {
+			return BranchExpiryPolicy{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		if policy.Overrides == nil {
+			policy.Overrides = make(map[string]time.Duration)
+		}
+		policy.Overrides[branch] = ttl
+	}
+
+	return policy, nil
+}
+
+// ParseBranchExpireAfter parses a branch expiry age. It accepts anything time.ParseDuration does, e.g. 36h, as well as
+// a whole number of days or weeks, e.g. 30d or 2w. "never" and "0" mean that the branch never expires, and are
+// returned as zero.
+func ParseBranchExpireAfter(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "never" || s == "0" {
+		return 0, nil
+	}
+
+	var ttl time.Duration
+	var err error
+	if strings.HasSuffix(s, "d") {
+		var n int64
+		n, err = strconv.ParseInt(strings.TrimSuffix(s, "d"), 10, 64)
+		ttl = time.Duration(n) * 24 * time.Hour
+	} else if strings.HasSuffix(s, "w") {
+		var n int64
+		n, err = strconv.ParseInt(strings.TrimSuffix(s, "w"), 10, 64)
+		ttl = time.Duration(n) * 7 * 24 * time.Hour
+	} else {
+		ttl, err = time.ParseDuration(s)
+	}
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("'%s' is not a positive duration such as 36h, 30d or 2w, or never", s)
+	}
+	return ttl, nil
+}
+
+// PruneStaleOptions are the options of PruneStaleBranches
+type PruneStaleOptions struct {
+	// Now is the time that branch ages are measured at. Defaults to the current time.
+	Now time.Time
+	// Keep, if non-nil, is called with each expired branch before it's deleted, and the branch is kept if it returns
+	// true, e.g. because the caller isn't permitted to delete it.
+	Keep func(branch string) bool
+	// Progress, if non-nil, is called after each branch is evaluated with whether it was deleted
+	Progress func(branch string, deleted bool)
+	// RemoteRetry is how requests to a branch's upstream remote are retried before the branch is kept as unreachable.
+	// Defaults to DefaultPruneRemoteRetry if it doesn't allow retries.
+	RemoteRetry RetryPolicy
+}
+
+// DefaultPruneRemoteRetry is the retry policy PruneStaleBranches uses for upstream remotes if none is given, so that a
+// briefly unavailable remote doesn't abort a prune
+var DefaultPruneRemoteRetry = RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond}
+
+// PruneStaleBranches deletes every branch other than the current working branch whose head commit is older than the
+// age at which |policy| says it expires, and which is fully merged, and returns the names of the branches deleted.
+// Like DeleteBranch without force, a branch with an upstream must be merged into it, and a branch without one into the
+// current working branch. Branches whose upstream can't be reached after retrying it are kept, and a warning is
+// logged. Branches with uncommitted changes are kept too, since deleting them would discard those changes.
+//
+// The head of a deleted branch can be restored with RecoverDeletedBranch, but its upstream config and description are
+// removed along with it and aren't restored.
+//
+// Branches are evaluated in name order. If |ctx| is canceled, pruning stops before the next branch and the branches
+// already deleted are returned along with the context's error.
+func PruneStaleBranches(ctx context.Context, dbData env.DbData, policy BranchExpiryPolicy, opts PruneStaleOptions, remoteDbPro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) ([]string, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	ddb := dbData.Ddb
+	headRef, err := dbData.Rsr.CWBHeadRef()
+	if err != nil {
+		return nil, err
+	}
+	cwbHead, err := ddb.ResolveCommitRef(ctx, headRef)
+	if err != nil {
+		return nil, err
+	}
+
+	branches, err := ddb.GetBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].GetPath() < branches[j].GetPath()
+	})
+
+	retry := opts.RemoteRetry
+	if retry.Attempts < 2 {
+		retry = DefaultPruneRemoteRetry
+	}
+
+	deleteOpts := DeleteOptions{CurrentHead: NewCurrentHeadCache(cwbHead), Ancestry: NewAncestryCache(), RemoteRetry: retry}
+	var deleted []string
+	for _, branch := range branches {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		if ref.Equals(branch, headRef) {
+			continue
+		}
+
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		if err != nil {
+			return deleted, err
+		}
+		expired, err := branchExpired(ctx, head, policy.ExpireAfterFor(branch.GetPath()), now)
+		if err != nil {
+			return deleted, err
+		}
+
+		pruned := false
+		if expired && (opts.Keep == nil || !opts.Keep(branch.GetPath())) {
+			dirty, err := branchHasUncommittedChanges(ctx, ddb, branch, head)
+			if err != nil && !errors.Is(err, ref.ErrWorkingSetUnsupported) {
+				return deleted, err
+			}
+			if dirty {
+				logrus.Warnf("keeping stale branch %s, since it has uncommitted changes", branch.GetPath())
+				if opts.Progress != nil {
+					opts.Progress(branch.GetPath(), false)
+				}
+				continue
+			}
+
+			err = DeleteBranch(ctx, dbData, branch.GetPath(), deleteOpts, remoteDbPro, rsc)
+			var unreachableErr RemoteUnreachableError
+			if errors.As(err, &unreachableErr) {
+				logrus.Warnf("keeping stale branch %s, since it can't be checked against its upstream: %v", branch.GetPath(), err)
+			} else if err != nil && !errors.Is(err, ErrUnmergedBranch) {
+				return deleted, err
+			}
+			pruned = err == nil
+		}
+		if pruned {
+			deleted = append(deleted, branch.GetPath())
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(branch.GetPath(), pruned)
+		}
+	}
+
+	return deleted, nil
+}
+
+// branchExpired returns whether the branch head commit |head| is older than |ttl| as of |now|. A |ttl| of zero never
+// expires.
+func branchExpired(ctx context.Context, head *doltdb.Commit, ttl time.Duration, now time.Time) (bool, error) {
+	if ttl <= 0 {
+		return false, nil
+	}
+
+	meta, err := head.GetCommitMeta(ctx)
+	if err != nil {
+		return false, err
+	}
+	return meta.Time().Before(now.Add(-ttl)), nil
+}
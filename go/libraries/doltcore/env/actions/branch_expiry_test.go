@@ -0,0 +1,157 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+	"github.com/dolthub/dolt/go/store/datas"
+)
+
+func TestParseBranchExpireAfter(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		val      string
+		expected time.Duration
+		err      bool
+	}{
+		{"36h", 36 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"30d", 30 * day, false},
+		{"2w", 14 * day, false},
+		{" 2W ", 14 * day, false},
+		{"never", 0, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"d", 0, true},
+		{"-1d", 0, true},
+		{"0h", 0, true},
+		{"a week", 0, true},
+		{"1.5d", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.val, func(t *testing.T) {
+			ttl, err := ParseBranchExpireAfter(test.val)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ttl)
+		})
+	}
+}
+
+func TestBranchExpiryPolicyFromConfig(t *testing.T) {
+	day := 24 * time.Hour
+
+	policy, err := BranchExpiryPolicyFromConfig(config.NewEmptyMapConfig())
+	require.NoError(t, err)
+	assert.Equal(t, BranchExpiryPolicy{}, policy)
+
+	// local config takes precedence over global config, as it does for every other key
+	ch := config.NewConfigHierarchy()
+	ch.AddConfig("local", config.NewMapConfig(map[string]string{
+		"branch.expireafter":              "30d",
+		"branch.release/v1.2.expireafter": "never",
+	}))
+	ch.AddConfig("global", config.NewMapConfig(map[string]string{
+		"branch.expireafter":              "2w",
+		"branch.release/v1.2.expireafter": "1d",
+		"branch.scratch.expireafter":      "36h",
+		"branch.autosetupmerge":           "false",
+	}))
+	policy, err = BranchExpiryPolicyFromConfig(ch)
+	require.NoError(t, err)
+	assert.Equal(t, 30*day, policy.ExpireAfter)
+	assert.Equal(t, map[string]time.Duration{"release/v1.2": 0, "scratch": 36 * time.Hour}, policy.Overrides)
+
+	assert.Equal(t, 30*day, policy.ExpireAfterFor("feature"))
+	assert.Equal(t, time.Duration(0), policy.ExpireAfterFor("release/v1.2"))
+	assert.Equal(t, 36*time.Hour, policy.ExpireAfterFor("Scratch"))
+
+	_, err = BranchExpiryPolicyFromConfig(config.NewMapConfig(map[string]string{"branch.expireafter": "soon"}))
+	assert.Error(t, err)
+	_, err = BranchExpiryPolicyFromConfig(config.NewMapConfig(map[string]string{"branch.scratch.expireafter": "soon"}))
+	assert.Error(t, err)
+}
+
+func TestPruneStaleBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	dbData := dEnv.DbData()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer func() { datas.CommitNowFunc = time.Now }()
+	datas.CommitNowFunc = func() time.Time { return start }
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	for _, name := range []string{"old-merged", "old-unmerged", "old-kept", "old-forever", "old-dirty"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dbData, name, "main", false, nil))
+	}
+	createTestCommits(t, dEnv, "old-unmerged", 1)
+	makeBranchDirty(t, dEnv, "old-dirty")
+
+	datas.CommitNowFunc = func() time.Time { return start.Add(30 * 24 * time.Hour) }
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dbData, "recent-merged", "main", false, nil))
+
+	policy := BranchExpiryPolicy{
+		ExpireAfter: 7 * 24 * time.Hour,
+		Overrides:   map[string]time.Duration{"old-forever": 0},
+	}
+	progress := make(map[string]bool)
+	deleted, err := PruneStaleBranches(ctx, dbData, policy, PruneStaleOptions{
+		Now: start.Add(31 * 24 * time.Hour),
+		Keep: func(branch string) bool {
+			return branch == "old-kept"
+		},
+		Progress: func(branch string, deleted bool) {
+			progress[branch] = deleted
+		},
+	}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old-merged"}, deleted)
+	assert.Equal(t, map[string]bool{
+		"old-dirty":     false,
+		"old-forever":   false,
+		"old-kept":      false,
+		"old-merged":    true,
+		"old-unmerged":  false,
+		"recent-merged": false,
+	}, progress)
+
+	branches, err := dEnv.DoltDB.GetBranches(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, b := range branches {
+		names = append(names, b.GetPath())
+	}
+	assert.ElementsMatch(t, []string{"main", "old-unmerged", "old-kept", "old-forever", "old-dirty", "recent-merged"}, names)
+	assert.True(t, hasDirtyTable(t, dEnv, "old-dirty"))
+
+	// the heads of deleted branches can be recovered, as they can after any other delete
+	require.NoError(t, RecoverDeletedBranch(ctx, dbData, "old-merged"))
+}
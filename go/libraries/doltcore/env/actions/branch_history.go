@@ -0,0 +1,352 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/commitwalk"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var ErrNoParentBranch = errors.New("no other branch shares history with the branch")
+
+// BranchMergeGraph returns, for every branch in |ddb|, the names of the other branches whose heads are in its history,
+// i.e. the branches that are fully merged into it. Branches with the same head are merged into each other.
+//
+// Each branch head is resolved once, and the history of each distinct head is walked at most once: heads are
+// processed from oldest to newest, and the walk stops at any head that has already been processed, reusing its result.
+func BranchMergeGraph(ctx context.Context, ddb *doltdb.DoltDB) (map[string][]string, error) {
+	branches, err := ddb.GetBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	branchesByHead := make(map[hash.Hash][]string)
+	heads := make(map[hash.Hash]*doltdb.Commit)
+	heights := make(map[hash.Hash]uint64)
+	for _, branch := range branches {
+		cm, err := ddb.ResolveCommitRef(ctx, branch)
+		if err != nil {
+			return nil, err
+		}
+		h, err := cm.HashOf()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := heads[h]; !ok {
+			heads[h] = cm
+			heights[h], err = cm.Height()
+			if err != nil {
+				return nil, err
+			}
+		}
+		branchesByHead[h] = append(branchesByHead[h], branch.GetPath())
+	}
+
+	ordered := make([]hash.Hash, 0, len(heads))
+	for h := range heads {
+		ordered = append(ordered, h)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return heights[ordered[i]] < heights[ordered[j]]
+	})
+
+	// ancestorHeads records, for each head, the other heads in its history
+	ancestorHeads := make(map[hash.Hash]map[hash.Hash]struct{}, len(heads))
+	for _, h := range ordered {
+		ancestors, err := findAncestorHeads(ctx, heads[h], heads, ancestorHeads)
+		if err != nil {
+			return nil, err
+		}
+		ancestorHeads[h] = ancestors
+	}
+
+	graph := make(map[string][]string, len(branches))
+	for h, names := range branchesByHead {
+		var ancestorBranches []string
+		for ancestor := range ancestorHeads[h] {
+			ancestorBranches = append(ancestorBranches, branchesByHead[ancestor]...)
+		}
+		for _, name := range names {
+			merged := append([]string{}, ancestorBranches...)
+			for _, other := range names {
+				if other != name {
+					merged = append(merged, other)
+				}
+			}
+			sort.Strings(merged)
+			graph[name] = merged
+		}
+	}
+
+	return graph, nil
+}
+
+// findAncestorHeads walks the history of |start| and returns the members of |heads| found in it, other than |start|
+// itself. The walk doesn't continue past any head with an entry in |known|, whose ancestor heads are used instead.
+func findAncestorHeads(ctx context.Context, start *doltdb.Commit, heads map[hash.Hash]*doltdb.Commit, known map[hash.Hash]map[hash.Hash]struct{}) (map[hash.Hash]struct{}, error) {
+	found := make(map[hash.Hash]struct{})
+	visited := make(map[hash.Hash]struct{})
+	queue := []*doltdb.Commit{start}
+	first := true
+
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		cm := queue[0]
+		queue = queue[1:]
+		h, err := cm.HashOf()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := visited[h]; ok {
+			continue
+		}
+		visited[h] = struct{}{}
+
+		if !first {
+			if _, ok := heads[h]; ok {
+				found[h] = struct{}{}
+				if ancestors, ok := known[h]; ok {
+					for a := range ancestors {
+						found[a] = struct{}{}
+					}
+					continue
+				}
+			}
+		}
+		first = false
+
+		for i := 0; i < cm.NumParents(); i++ {
+			parent, err := cm.GetParent(ctx, i)
+			if err != nil {
+				return nil, err
+			}
+			queue = append(queue, parent)
+		}
+	}
+
+	return found, nil
+}
+
+// CommitCount returns the number of commits in the history of |branch|, including its head. If |since| is not empty,
+// commits in the history of the commit |since| are not counted, so that the result is the number of commits made on
+// the branch after it. History is walked one commit at a time, and the walk stops early if |ctx| is canceled.
+func CommitCount(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef, since hash.Hash) (uint64, error) {
+	head, err := ddb.ResolveCommitRef(ctx, branch)
+	if err != nil {
+		return 0, err
+	}
+	headHash, err := head.HashOf()
+	if err != nil {
+		return 0, err
+	}
+
+	return countCommits(ctx, ddb, headHash, since)
+}
+
+// countCommits returns the number of commits in the history of |head| that aren't in the history of |excluded|, or
+// all commits in the history of |head| if |excluded| is empty
+func countCommits(ctx context.Context, ddb *doltdb.DoltDB, head, excluded hash.Hash) (uint64, error) {
+	var itr doltdb.CommitItr
+	var err error
+	if excluded.IsEmpty() {
+		itr, err = commitwalk.GetTopologicalOrderIterator(ctx, ddb, []hash.Hash{head}, nil)
+	} else {
+		itr, err = commitwalk.GetDotDotRevisionsIterator(ctx, ddb, []hash.Hash{head}, ddb, []hash.Hash{excluded}, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		_, _, err := itr.Next(ctx)
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
+
+// CommitRange returns the hashes of the commits reachable from |include| but not from |exclude|, like `git log
+// exclude..include`, children before their parents. See CommitRangeIterator to process large ranges without holding
+// them all in memory.
+func CommitRange(ctx context.Context, ddb *doltdb.DoltDB, include, exclude ref.DoltRef) ([]hash.Hash, error) {
+	itr, err := CommitRangeIterator(ctx, ddb, include, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []hash.Hash
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		h, _, err := itr.Next(ctx)
+		if err == io.EOF {
+			return hashes, nil
+		} else if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, h)
+	}
+}
+
+// CommitRangeIterator returns an iterator over the commits reachable from |include| but not from |exclude|, children
+// before their parents. History is walked as the iterator is advanced.
+func CommitRangeIterator(ctx context.Context, ddb *doltdb.DoltDB, include, exclude ref.DoltRef) (doltdb.CommitItr, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var heads [2]hash.Hash
+	for i, r := range []ref.DoltRef{include, exclude} {
+		cm, err := ddb.ResolveCommitRef(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		heads[i], err = cm.HashOf()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return commitwalk.GetDotDotRevisionsIterator(ctx, ddb, []hash.Hash{heads[0]}, ddb, []hash.Hash{heads[1]}, nil)
+}
+
+// LikelyParentBranch guesses which branch |feature| was created from: of all the other branches that share history with
+// it, the one whose merge base with |feature| has the fewest commits between it and |feature|'s head. Returns that
+// branch and the merge base. Ties are broken in favor of the default branch, and then by branch name. Note that a
+// branch which |feature| has been merged into has a merge base at |feature|'s head, so it's preferred over the branch
+// |feature| was actually created from. Returns ErrNoParentBranch if no other branch shares history with |feature|.
+func LikelyParentBranch(ctx context.Context, ddb *doltdb.DoltDB, feature ref.DoltRef) (ref.DoltRef, hash.Hash, error) {
+	featureHead, err := ddb.ResolveCommitRef(ctx, feature)
+	if err != nil {
+		return nil, hash.Hash{}, err
+	}
+	featureHash, err := featureHead.HashOf()
+	if err != nil {
+		return nil, hash.Hash{}, err
+	}
+
+	branches, err := ddb.GetBranches(ctx)
+	if err != nil {
+		return nil, hash.Hash{}, err
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].GetPath() < branches[j].GetPath()
+	})
+
+	ancestry := NewAncestryCache()
+	distances := make(map[hash.Hash]uint64)
+	var best ref.DoltRef
+	var bestBase hash.Hash
+	var bestDistance uint64
+	for _, branch := range branches {
+		if err := ctx.Err(); err != nil {
+			return nil, hash.Hash{}, err
+		}
+		if ref.Equals(branch, feature) {
+			continue
+		}
+
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		if err != nil {
+			return nil, hash.Hash{}, err
+		}
+		mergeBase, err := ancestry.MergeBase(ctx, featureHead, head)
+		if errors.Is(err, doltdb.ErrNoCommonAncestor) {
+			continue
+		} else if err != nil {
+			return nil, hash.Hash{}, err
+		}
+
+		distance, ok := distances[mergeBase]
+		if !ok {
+			distance, err = countCommits(ctx, ddb, featureHash, mergeBase)
+			if err != nil {
+				return nil, hash.Hash{}, err
+			}
+			distances[mergeBase] = distance
+		}
+
+		// branches are visited in name order, so only the default branch can win a tie
+		if best == nil || distance < bestDistance || (distance == bestDistance && branch.GetPath() == env.DefaultInitBranch) {
+			best, bestBase, bestDistance = branch, mergeBase, distance
+		}
+	}
+
+	if best == nil {
+		return nil, hash.Hash{}, ErrNoParentBranch
+	}
+	return best, bestBase, nil
+}
+
+// BranchesModifiedSince returns the branches whose head commits are newer than |since|, sorted by name. Commits are
+// compared by the timestamps in their metadata, which are set by whoever made them, not by when they were written to
+// this database. So a commit made on a machine with a skewed clock, or with an explicit date, may be reported or
+// skipped regardless of when it arrived, and callers syncing incrementally should allow some slack in |since|.
+//
+// Each distinct head is resolved once, even when several branches share it.
+func BranchesModifiedSince(ctx context.Context, ddb *doltdb.DoltDB, since time.Time) ([]ref.DoltRef, error) {
+	branches, err := ddb.GetBranchesWithHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	modified := make(map[hash.Hash]bool)
+	var refs []ref.DoltRef
+	for _, branch := range branches {
+		isModified, ok := modified[branch.Hash]
+		if !ok {
+			cm, err := ddb.ReadCommit(ctx, branch.Hash)
+			if err != nil {
+				return nil, err
+			}
+			meta, err := cm.GetCommitMeta(ctx)
+			if err != nil {
+				return nil, err
+			}
+			isModified = meta.Time().After(since)
+			modified[branch.Hash] = isModified
+		}
+		if isModified {
+			refs = append(refs, branch.Ref)
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].GetPath() < refs[j].GetPath()
+	})
+	return refs, nil
+}
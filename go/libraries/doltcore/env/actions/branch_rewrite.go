@@ -0,0 +1,164 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/commitwalk"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var ErrHistoryTooLong = errors.New("branch history is longer than the limit given")
+
+// RewriteCommitMetaFn returns the metadata to use for the rewritten copy of a commit with metadata |meta|. It may
+// modify and return |meta|, which is a copy.
+type RewriteCommitMetaFn func(meta *datas.CommitMeta) (*datas.CommitMeta, error)
+
+// CopyBranchRewriting creates |newBranch| with a copy of the entire history of |oldBranch| in which the metadata of
+// every commit has been replaced using |rewrite|, e.g. to anonymize the committers. The copied commits have the same
+// root values and the same shape of history as the originals. Since every commit is rewritten, histories of more than
+// |maxCommits| commits are refused with ErrHistoryTooLong before anything is written. |progress|, if non-nil, is called
+// after each commit is rewritten with the number of commits rewritten so far and the total.
+func CopyBranchRewriting(
+	ctx context.Context,
+	ddb *doltdb.DoltDB,
+	oldBranch, newBranch string,
+	rewrite RewriteCommitMetaFn,
+	maxCommits int,
+	progress func(done, total int),
+	rsc *doltdb.ReplicationStatusController,
+) error {
+	if !doltdb.IsValidUserBranchName(newBranch) {
+		return doltdb.ErrInvBranchName
+	}
+	newRef := ref.NewBranchRef(newBranch)
+	hasNew, err := ddb.HasRef(ctx, newRef)
+	if err != nil {
+		return err
+	} else if hasNew {
+		return ErrAlreadyExists
+	}
+
+	head, err := ddb.ResolveCommitRef(ctx, ref.NewBranchRef(oldBranch))
+	if err != nil {
+		return err
+	}
+
+	history, err := historyOldestFirst(ctx, ddb, head, maxCommits)
+	if err != nil {
+		return err
+	}
+
+	rewritten := make(map[hash.Hash]*doltdb.Commit, len(history))
+	var newHead *doltdb.Commit
+	for i, cm := range history {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		newHead, err = rewriteCommit(ctx, ddb, cm, rewritten, rewrite)
+		if err != nil {
+			return err
+		}
+		h, err := cm.HashOf()
+		if err != nil {
+			return err
+		}
+		rewritten[h] = newHead
+
+		if progress != nil {
+			progress(i+1, len(history))
+		}
+	}
+
+	return ddb.NewBranchAtCommit(ctx, newRef, newHead, rsc)
+}
+
+// historyOldestFirst returns the history of |head| in an order in which every commit comes after its parents, or
+// ErrHistoryTooLong if it has more than |maxCommits| commits
+func historyOldestFirst(ctx context.Context, ddb *doltdb.DoltDB, head *doltdb.Commit, maxCommits int) ([]*doltdb.Commit, error) {
+	headHash, err := head.HashOf()
+	if err != nil {
+		return nil, err
+	}
+	itr, err := commitwalk.GetTopologicalOrderIterator(ctx, ddb, []hash.Hash{headHash}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []*doltdb.Commit
+	for {
+		_, cm, err := itr.Next(ctx)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(history) == maxCommits {
+			return nil, fmt.Errorf("%w: %d", ErrHistoryTooLong, maxCommits)
+		}
+		history = append(history, cm)
+	}
+
+	// topological order puts children before their parents
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// rewriteCommit writes a copy of |cm| with rewritten metadata, whose parents are the rewritten copies of its parents
+func rewriteCommit(ctx context.Context, ddb *doltdb.DoltDB, cm *doltdb.Commit, rewritten map[hash.Hash]*doltdb.Commit, rewrite RewriteCommitMetaFn) (*doltdb.Commit, error) {
+	parentHashes, err := cm.ParentHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parents := make([]*doltdb.Commit, len(parentHashes))
+	for i, h := range parentHashes {
+		parent, ok := rewritten[h]
+		if !ok {
+			return nil, fmt.Errorf("parent %s of commit was not rewritten before it", h.String())
+		}
+		parents[i] = parent
+	}
+
+	meta, err := cm.GetCommitMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metaCopy := *meta
+	newMeta, err := rewrite(&metaCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := cm.GetRootValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, rootHash, err := ddb.WriteRootValue(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.CommitDanglingWithParentCommits(ctx, rootHash, parents, newMeta)
+}
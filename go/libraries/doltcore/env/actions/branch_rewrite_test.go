@@ -0,0 +1,94 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/datas"
+)
+
+func TestCopyBranchRewriting(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 3)
+	anonymize := func(meta *datas.CommitMeta) (*datas.CommitMeta, error) {
+		meta.Name = "anonymous"
+		meta.Email = "anonymous@example.com"
+		return meta, nil
+	}
+
+	err := CopyBranchRewriting(ctx, ddb, env.DefaultInitBranch, "too-long", anonymize, 3, nil, nil)
+	assert.ErrorIs(t, err, ErrHistoryTooLong)
+	ok, err := IsBranch(ctx, ddb, "too-long")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	var progress []int
+	err = CopyBranchRewriting(ctx, ddb, env.DefaultInitBranch, "anon", anonymize, 100, func(done, total int) {
+		assert.Equal(t, 4, total)
+		progress = append(progress, done)
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, progress)
+
+	original, err := ddb.ResolveCommitRef(ctx, ref.NewBranchRef(env.DefaultInitBranch))
+	require.NoError(t, err)
+	copied, err := ddb.ResolveCommitRef(ctx, ref.NewBranchRef("anon"))
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		assert.NotEqual(t, mustHashOf(t, original), mustHashOf(t, copied))
+
+		origMeta, err := original.GetCommitMeta(ctx)
+		require.NoError(t, err)
+		copiedMeta, err := copied.GetCommitMeta(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "anonymous", copiedMeta.Name)
+		assert.Equal(t, origMeta.Description, copiedMeta.Description)
+		assert.Equal(t, origMeta.UserTimestamp, copiedMeta.UserTimestamp)
+
+		origRoot, err := original.GetRootValue(ctx)
+		require.NoError(t, err)
+		copiedRoot, err := copied.GetRootValue(ctx)
+		require.NoError(t, err)
+		origRootHash, err := origRoot.HashOf()
+		require.NoError(t, err)
+		copiedRootHash, err := copiedRoot.HashOf()
+		require.NoError(t, err)
+		assert.Equal(t, origRootHash, copiedRootHash)
+
+		require.Equal(t, original.NumParents(), copied.NumParents())
+		if original.NumParents() == 0 {
+			break
+		}
+		original, err = original.GetParent(ctx, 0)
+		require.NoError(t, err)
+		copied, err = copied.GetParent(ctx, 0)
+		require.NoError(t, err)
+	}
+
+	err = CopyBranchRewriting(ctx, ddb, env.DefaultInitBranch, "anon", anonymize, 100, nil, nil)
+	assert.Equal(t, ErrAlreadyExists, err)
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+// swapBranchPrefix is the prefix of the temporary branch that holds the original state of the first branch while
+// SwapBranches is running
+const swapBranchPrefix = "swap-tmp-"
+
+// SwapBranches exchanges the heads, working sets and upstream configs of branches |a| and |b|. Branch refs can only be
+// updated one at a time, so |a| is first copied to a temporary branch named with swapBranchPrefix. If the swap fails
+// part way, both branches are restored from it and the temporary branch is deleted. If that restore fails too, the
+// temporary branch is left behind holding the original state of |a|, and the error returned names it.
+//
+// The current working branch keeps its name, so if it's one of the two branches, it has the other branch's head and
+// working set after the swap.
+func SwapBranches(ctx context.Context, dbData env.DbData, a, b string, remoteDbPro env.RemoteDbProvider, rsc *doltdb.ReplicationStatusController) error {
+	if a == b {
+		return nil
+	}
+
+	ddb := dbData.Ddb
+	for _, name := range []string{a, b} {
+		hasRef, err := ddb.HasRef(ctx, ref.NewBranchRef(name))
+		if err != nil {
+			return err
+		} else if !hasRef {
+			return fmt.Errorf("%w: %s", doltdb.ErrBranchNotFound, name)
+		}
+	}
+
+	tmp, err := CopyBranchUnique(ctx, ddb, a, swapBranchPrefix+a, rsc)
+	if err != nil {
+		return err
+	}
+	err = copyWorkingSet(ctx, ddb, a, tmp)
+	if err != nil {
+		cleanUpSwapBranch(ctx, ddb, tmp, rsc)
+		return err
+	}
+
+	// |a| gets the original state of |b|, and then |b| the original state of |a| from the temporary branch
+	err = copyBranchState(ctx, ddb, b, a, rsc)
+	if err != nil {
+		return abortSwap(ctx, ddb, a, b, tmp, false, err, rsc)
+	}
+	err = copyBranchState(ctx, ddb, tmp, b, rsc)
+	if err != nil {
+		return abortSwap(ctx, ddb, a, b, tmp, true, err, rsc)
+	}
+
+	cleanUpSwapBranch(ctx, ddb, tmp, rsc)
+	return swapUpstreams(ctx, dbData, a, b)
+}
+
+// abortSwap restores branches |a| and |b| after SwapBranches failed with |err|, and returns the error to report.
+// |overwroteB| is whether |b| may have been overwritten, even partially, by the time the swap failed.
+func abortSwap(ctx context.Context, ddb *doltdb.DoltDB, a, b, tmp string, overwroteB bool, err error, rsc *doltdb.ReplicationStatusController) error {
+	restoreErr := restoreSwappedBranches(ctx, ddb, a, b, tmp, overwroteB, rsc)
+	if restoreErr != nil {
+		return fmt.Errorf("%w; unable to restore branches, the original state of '%s' is in branch '%s': %v", err, a, tmp, restoreErr)
+	}
+	cleanUpSwapBranch(ctx, ddb, tmp, rsc)
+	return err
+}
+
+// restoreSwappedBranches restores the original states of |a| and |b| from |tmp| and, if |overwroteB|, from |a|, which
+// holds the original state of |b| by the time |b| is overwritten
+func restoreSwappedBranches(ctx context.Context, ddb *doltdb.DoltDB, a, b, tmp string, overwroteB bool, rsc *doltdb.ReplicationStatusController) error {
+	if overwroteB {
+		err := copyBranchState(ctx, ddb, a, b, rsc)
+		if err != nil {
+			return err
+		}
+	}
+	return copyBranchState(ctx, ddb, tmp, a, rsc)
+}
+
+// copyBranchState overwrites the head and working set of branch |to| with those of branch |from|
+func copyBranchState(ctx context.Context, ddb *doltdb.DoltDB, from, to string, rsc *doltdb.ReplicationStatusController) error {
+	err := CopyBranchOnDB(ctx, ddb, from, to, true, rsc)
+	if err != nil {
+		return err
+	}
+	return copyWorkingSet(ctx, ddb, from, to)
+}
+
+func copyWorkingSet(ctx context.Context, ddb *doltdb.DoltDB, from, to string) error {
+	fromWSRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef(from))
+	if err != nil {
+		return err
+	}
+	toWSRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef(to))
+	if err != nil {
+		return err
+	}
+	return ddb.CopyWorkingSet(ctx, fromWSRef, toWSRef, true)
+}
+
+// cleanUpSwapBranch deletes the temporary branch of SwapBranches, logging any failure. The branches being swapped are
+// consistent by the time it's called, so a leftover temporary branch is only clutter.
+func cleanUpSwapBranch(ctx context.Context, ddb *doltdb.DoltDB, tmp string, rsc *doltdb.ReplicationStatusController) {
+	err := deleteSwapBranch(ctx, ddb, tmp, rsc)
+	if err != nil {
+		logrus.Warnf("unable to delete temporary branch %s: %v", tmp, err)
+	}
+}
+
+// deleteSwapBranch deletes the temporary branch of SwapBranches. Unlike a user branch, it isn't recorded for
+// RecoverDeletedBranch.
+func deleteSwapBranch(ctx context.Context, ddb *doltdb.DoltDB, tmp string, rsc *doltdb.ReplicationStatusController) error {
+	tmpRef := ref.NewBranchRef(tmp)
+	wsRef, err := ref.WorkingSetRefForHead(tmpRef)
+	if err != nil {
+		return err
+	}
+	err = ddb.DeleteWorkingSet(ctx, wsRef)
+	if err != nil && err != doltdb.ErrWorkingSetNotFound {
+		return err
+	}
+	return ddb.DeleteBranch(ctx, tmpRef, rsc)
+}
+
+// swapUpstreams exchanges the upstream configs of branches |a| and |b|
+func swapUpstreams(ctx context.Context, dbData env.DbData, a, b string) error {
+	branches, err := dbData.Rsr.GetBranches()
+	if err != nil {
+		return err
+	}
+	upstreamA, hasA := branches[a]
+	upstreamB, hasB := branches[b]
+
+	for _, u := range []struct {
+		name        string
+		upstream    env.BranchConfig
+		hasUpstream bool
+	}{{a, upstreamB, hasB}, {b, upstreamA, hasA}} {
+		if u.hasUpstream {
+			err = copyBranchUpstream(ctx, dbData, u.name, u.upstream)
+		} else {
+			err = UnsetBranchUpstream(ctx, dbData, u.name)
+		}
+		if errors.Is(err, ErrUpstreamNotRemovable) {
+			logrus.Warnf("unable to remove the upstream of branch %s after swapping it", u.name)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+func branchHeadHash(t *testing.T, dEnv *env.DoltEnv, branch string) string {
+	cm, err := dEnv.DoltDB.ResolveCommitRef(context.Background(), ref.NewBranchRef(branch))
+	require.NoError(t, err)
+	return mustHashOf(t, cm)
+}
+
+func hasDirtyTable(t *testing.T, dEnv *env.DoltEnv, branch string) bool {
+	ctx := context.Background()
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef(branch))
+	require.NoError(t, err)
+	ws, err := dEnv.DoltDB.ResolveWorkingSet(ctx, wsRef)
+	require.NoError(t, err)
+	has, err := ws.WorkingRoot().HasTable(ctx, "dirty")
+	require.NoError(t, err)
+	return has
+}
+
+func assertNoSwapBranches(t *testing.T, dEnv *env.DoltEnv) {
+	branches, err := dEnv.DoltDB.GetBranches(context.Background())
+	require.NoError(t, err)
+	for _, br := range branches {
+		assert.False(t, strings.HasPrefix(br.GetPath(), swapBranchPrefix), br.GetPath())
+	}
+}
+
+func TestSwapBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "staging", "main", false, nil))
+	createTestCommits(t, dEnv, "staging", 1)
+	makeBranchDirty(t, dEnv, "staging")
+	upstream := env.BranchConfig{Merge: ref.MarshalableRef{Ref: ref.NewBranchRef("main")}, Remote: "origin"}
+	require.NoError(t, dEnv.RepoStateWriter().UpdateBranch("main", upstream))
+
+	mainHead, stagingHead := branchHeadHash(t, dEnv, "main"), branchHeadHash(t, dEnv, "staging")
+
+	require.NoError(t, SwapBranches(ctx, dEnv.DbData(), "main", "staging", nil, nil))
+
+	assert.Equal(t, stagingHead, branchHeadHash(t, dEnv, "main"))
+	assert.Equal(t, mainHead, branchHeadHash(t, dEnv, "staging"))
+	assert.True(t, hasDirtyTable(t, dEnv, "main"))
+	assert.False(t, hasDirtyTable(t, dEnv, "staging"))
+
+	branches, err := dEnv.RepoStateReader().GetBranches()
+	require.NoError(t, err)
+	assert.NotContains(t, branches, "main")
+	assert.Equal(t, upstream, branches["staging"])
+
+	headRef, err := dEnv.RepoStateReader().CWBHeadRef()
+	require.NoError(t, err)
+	assert.Equal(t, "main", headRef.GetPath())
+	assertNoSwapBranches(t, dEnv)
+
+	err = SwapBranches(ctx, dEnv.DbData(), "main", "missing", nil, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+}
+
+func TestSwapBranchesInterrupted(t *testing.T) {
+	// the state of the branches when each step of the swap fails, after overwriting the branch it was copying to
+	for _, overwroteB := range []bool{false, true} {
+		ctx := context.Background()
+		dEnv := dtestutils.CreateTestEnv()
+		ddb := dEnv.DoltDB
+
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "staging", "main", false, nil))
+		createTestCommits(t, dEnv, "staging", 1)
+		makeBranchDirty(t, dEnv, "staging")
+		mainHead, stagingHead := branchHeadHash(t, dEnv, "main"), branchHeadHash(t, dEnv, "staging")
+
+		tmp, err := CopyBranchUnique(ctx, ddb, "main", swapBranchPrefix+"main", nil)
+		require.NoError(t, err)
+		require.NoError(t, copyWorkingSet(ctx, ddb, "main", tmp))
+		require.NoError(t, copyBranchState(ctx, ddb, "staging", "main", nil))
+		if overwroteB {
+			require.NoError(t, copyBranchState(ctx, ddb, tmp, "staging", nil))
+		}
+
+		interrupted := errors.New("interrupted")
+		err = abortSwap(ctx, ddb, "main", "staging", tmp, overwroteB, interrupted, nil)
+		assert.Equal(t, interrupted, err)
+
+		assert.Equal(t, mainHead, branchHeadHash(t, dEnv, "main"), "overwroteB %t", overwroteB)
+		assert.Equal(t, stagingHead, branchHeadHash(t, dEnv, "staging"), "overwroteB %t", overwroteB)
+		assert.False(t, hasDirtyTable(t, dEnv, "main"), "overwroteB %t", overwroteB)
+		assert.True(t, hasDirtyTable(t, dEnv, "staging"), "overwroteB %t", overwroteB)
+		assertNoSwapBranches(t, dEnv)
+
+		dEnv.DoltDB.Close()
+	}
+}
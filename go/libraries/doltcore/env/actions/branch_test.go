@@ -0,0 +1,1467 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// createTestCommits creates |n| empty commits on top of the branch named and returns the resulting branch history,
+// with the branch head last.
+func createTestCommits(t *testing.T, dEnv *env.DoltEnv, branch string, n int) []*doltdb.Commit {
+	ctx := context.Background()
+	ddb := dEnv.DoltDB
+
+	cs, err := doltdb.NewCommitSpec(branch)
+	require.NoError(t, err)
+	head, err := ddb.Resolve(ctx, cs, nil)
+	require.NoError(t, err)
+	root, err := head.GetRootValue(ctx)
+	require.NoError(t, err)
+	_, rootHash, err := ddb.WriteRootValue(ctx, root)
+	require.NoError(t, err)
+
+	commits := []*doltdb.Commit{head}
+	for i := 0; i < n; i++ {
+		meta, err := datas.NewCommitMeta("billy bob", "bigbillieb@fake.horse", "test commit")
+		require.NoError(t, err)
+		cm, err := ddb.Commit(ctx, rootHash, ref.NewBranchRef(branch), meta)
+		require.NoError(t, err)
+		commits = append(commits, cm)
+	}
+	return commits
+}
+
+func mustHashOf(t *testing.T, cm *doltdb.Commit) string {
+	h, err := cm.HashOf()
+	require.NoError(t, err)
+	return h.String()
+}
+
+func TestCreateBranchWithRelativeStartPt(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	commits := createTestCommits(t, dEnv, env.DefaultInitBranch, 5)
+
+	tests := []struct {
+		name     string
+		startPt  string
+		expected *doltdb.Commit
+	}{
+		{name: "zero", startPt: "main~0", expected: commits[5]},
+		{name: "parent", startPt: "main^", expected: commits[4]},
+		{name: "root", startPt: "main~5", expected: commits[0]},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CreateBranchWithStartPt(ctx, dEnv.DbData(), test.name, test.startPt, false, nil)
+			require.NoError(t, err)
+
+			cm, err := dEnv.DoltDB.ResolveCommitRef(ctx, ref.NewBranchRef(test.name))
+			require.NoError(t, err)
+			assert.Equal(t, mustHashOf(t, test.expected), mustHashOf(t, cm))
+		})
+	}
+
+	t.Run("past root", func(t *testing.T) {
+		err := CreateBranchWithStartPt(ctx, dEnv.DbData(), "overwalk", "main~6", false, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "past the root commit")
+
+		ok, err := IsBranch(ctx, dEnv.DoltDB, "overwalk")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestValidateCreateBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 2)
+
+	assert.NoError(t, ValidateCreateBranch(ctx, dEnv.DbData(), "feature", "main~1"))
+	ok, err := IsBranch(ctx, dEnv.DoltDB, "feature")
+	require.NoError(t, err)
+	assert.False(t, ok, "validation must not create the branch")
+
+	assert.Equal(t, ErrAlreadyExists, ValidateCreateBranch(ctx, dEnv.DbData(), env.DefaultInitBranch, "main"))
+	assert.Equal(t, doltdb.ErrInvBranchName, ValidateCreateBranch(ctx, dEnv.DbData(), "head", "main"))
+	assert.ErrorIs(t, ValidateCreateBranch(ctx, dEnv.DbData(), "feature", "main~3"), doltdb.ErrInvalidAncestorSpec)
+	assert.True(t, doltdb.IsNotACommit(ValidateCreateBranch(ctx, dEnv.DbData(), "feature", "nosuchbranch")))
+}
+
+func TestCreateOrphanBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	sch, err := dtestutils.Schema()
+	require.NoError(t, err)
+	commitEmptyTable(t, dEnv, env.DefaultInitBranch, "people", sch)
+
+	require.NoError(t, CreateOrphanBranch(ctx, dEnv.DbData(), "orphan", nil))
+
+	cm, err := dEnv.DoltDB.ResolveCommitRef(ctx, ref.NewBranchRef("orphan"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, cm.NumParents())
+	root, err := cm.GetRootValue(ctx)
+	require.NoError(t, err)
+	tables, err := root.GetTableNames(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("orphan"))
+	require.NoError(t, err)
+	ws, err := dEnv.DoltDB.ResolveWorkingSet(ctx, wsRef)
+	require.NoError(t, err)
+	tables, err = ws.WorkingRoot().GetTableNames(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+
+	// the orphan shares no history with the branch it was created from
+	mainCm, err := dEnv.DoltDB.ResolveCommitRef(ctx, ref.NewBranchRef(env.DefaultInitBranch))
+	require.NoError(t, err)
+	_, err = doltdb.GetCommitAncestor(ctx, cm, mainCm)
+	assert.ErrorIs(t, err, doltdb.ErrNoCommonAncestor)
+
+	assert.Equal(t, ErrAlreadyExists, CreateOrphanBranch(ctx, dEnv.DbData(), "orphan", nil))
+	assert.ErrorIs(t, CreateOrphanBranch(ctx, dEnv.DbData(), "head", nil), doltdb.ErrInvBranchName)
+}
+
+func TestCreateBranchNamedLikeHash(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	cms := createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	hashName := mustHashOf(t, cms[1])
+
+	err := CreateBranchOnDB(ctx, dEnv.DoltDB, hashName, env.DefaultInitBranch, false, nil, nil)
+	assert.Equal(t, doltdb.ErrBranchNameLooksLikeHash, err)
+	assert.Equal(t, doltdb.ErrBranchNameLooksLikeHash, ValidateCreateBranch(ctx, dEnv.DbData(), hashName, env.DefaultInitBranch))
+	err = CreateBranchWithStartPt(ctx, dEnv.DbData(), hashName, env.DefaultInitBranch, false, nil)
+	assert.ErrorContains(t, err, "looks like a commit hash")
+
+	// names that are merely hex-like, but not hash length, are fine
+	require.NoError(t, CreateBranchOnDB(ctx, dEnv.DoltDB, hashName[:8], env.DefaultInitBranch, false, nil, nil))
+
+	// the branch is created at a different commit than the one it's named like, so that resolving it by ref and by
+	// commit spec can be told apart
+	opts := CreateBranchOptions{AllowHashLikeName: true}
+	require.NoError(t, CreateBranchOnDBWithOptions(ctx, dEnv.DoltDB, hashName, mustHashOf(t, cms[0]), nil, opts, nil))
+	branchHead, err := dEnv.DoltDB.ResolveCommitRef(ctx, ref.NewBranchRef(hashName))
+	require.NoError(t, err)
+	assert.Equal(t, mustHashOf(t, cms[0]), mustHashOf(t, branchHead))
+
+	// a commit spec prefers the commit
+	cs, err := doltdb.NewCommitSpec(hashName)
+	require.NoError(t, err)
+	resolved, err := dEnv.DoltDB.Resolve(ctx, cs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, hashName, mustHashOf(t, resolved))
+
+	// moved to an unmerged commit, the branch is verified and deleted by its own head, not the merged commit it's
+	// named like
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "side", env.DefaultInitBranch, false, nil))
+	sideCommits := createTestCommits(t, dEnv, "side", 1)
+	opts.Force = true
+	require.NoError(t, CreateBranchOnDBWithOptions(ctx, dEnv.DoltDB, hashName, "side", nil, opts, nil))
+
+	headErrs, err := VerifyBranchHeads(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Empty(t, headErrs)
+
+	err = DeleteBranch(ctx, dEnv.DbData(), hashName, DeleteOptions{}, nil, nil)
+	assert.Equal(t, ErrUnmergedBranch, err)
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), hashName, DeleteOptions{Force: true}, nil, nil))
+	ok, err := IsBranch(ctx, dEnv.DoltDB, hashName)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// its deletion records its own head
+	require.NoError(t, RecoverDeletedBranch(ctx, dEnv.DbData(), hashName))
+	branchHead, err = dEnv.DoltDB.ResolveCommitRef(ctx, ref.NewBranchRef(hashName))
+	require.NoError(t, err)
+	assert.Equal(t, mustHashOf(t, sideCommits[len(sideCommits)-1]), mustHashOf(t, branchHead))
+}
+
+func TestBranchesContaining(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	mainCommits := createTestCommits(t, dEnv, env.DefaultInitBranch, 2)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "old", "main~1", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	featureCommits := createTestCommits(t, dEnv, "feature", 1)
+
+	branchNames := func(target *doltdb.Commit) []string {
+		h, err := target.HashOf()
+		require.NoError(t, err)
+		branches, err := BranchesContaining(ctx, dEnv.DoltDB, h)
+		require.NoError(t, err)
+		var names []string
+		for _, b := range branches {
+			names = append(names, b.GetPath())
+		}
+		return names
+	}
+
+	assert.ElementsMatch(t, []string{"feature", "main", "old"}, branchNames(mainCommits[1]))
+	assert.ElementsMatch(t, []string{"feature", "main"}, branchNames(mainCommits[2]))
+	assert.ElementsMatch(t, []string{"feature"}, branchNames(featureCommits[1]))
+}
+
+func TestRecoverDeletedBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	featureCommits := createTestCommits(t, dEnv, "feature", 2)
+
+	assert.Equal(t, ErrDeletedBranchNotFound, RecoverDeletedBranch(ctx, dEnv.DbData(), "feature"))
+
+	err := DeleteBranch(ctx, dEnv.DbData(), "feature", DeleteOptions{Force: true}, nil, nil)
+	require.NoError(t, err)
+	ok, err := IsBranch(ctx, dEnv.DoltDB, "feature")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, RecoverDeletedBranch(ctx, dEnv.DbData(), "feature"))
+	cm, err := dEnv.DoltDB.ResolveCommitRef(ctx, ref.NewBranchRef("feature"))
+	require.NoError(t, err)
+	assert.Equal(t, mustHashOf(t, featureCommits[2]), mustHashOf(t, cm))
+
+	// the record is consumed by a successful recovery
+	assert.Equal(t, ErrDeletedBranchNotFound, RecoverDeletedBranch(ctx, dEnv.DbData(), "feature"))
+
+	// renaming a branch doesn't record its old name as deleted
+	require.NoError(t, RenameBranch(ctx, dEnv.DbData(), "feature", "renamed", nil, false, nil))
+	assert.Equal(t, ErrDeletedBranchNotFound, RecoverDeletedBranch(ctx, dEnv.DbData(), "feature"))
+}
+
+func TestRecoverDeletedBranchKeptWorkingSet(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	createTestCommits(t, dEnv, "feature", 1)
+	makeBranchDirty(t, dEnv, "feature")
+	head := branchHeadHash(t, dEnv, "feature")
+
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "feature", DeleteOptions{Force: true, KeepWorkingSet: true}, nil, nil))
+	require.NoError(t, RecoverDeletedBranch(ctx, dEnv.DbData(), "feature"))
+	assert.Equal(t, head, branchHeadHash(t, dEnv, "feature"))
+	assert.True(t, hasDirtyTable(t, dEnv, "feature"), "the kept working set is reattached")
+}
+
+func TestPurgeDeletedBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+		require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), name, DeleteOptions{Force: true}, nil, nil))
+	}
+	deleted, err := DeletedBranches(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, deleted)
+
+	purged, err := PurgeDeletedBranches(ctx, dEnv.DoltDB, "b", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, purged)
+	assert.Equal(t, ErrDeletedBranchNotFound, RecoverDeletedBranch(ctx, dEnv.DbData(), "b"))
+
+	purged, err = PurgeDeletedBranches(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, purged)
+	deleted, err = DeletedBranches(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func TestDeleteBranchKeepWorkingSet(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"kept", "dropped"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+		makeBranchDirty(t, dEnv, name)
+	}
+
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "kept", DeleteOptions{Force: true, KeepWorkingSet: true}, nil, nil))
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "dropped", DeleteOptions{Force: true}, nil, nil))
+
+	ok, err := IsBranch(ctx, dEnv.DoltDB, "kept")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, hasDirtyTable(t, dEnv, "kept"))
+
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("dropped"))
+	require.NoError(t, err)
+	_, err = dEnv.DoltDB.ResolveWorkingSet(ctx, wsRef)
+	assert.ErrorIs(t, err, doltdb.ErrWorkingSetNotFound)
+}
+
+func TestMergeValidationCanceled(t *testing.T) {
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(context.Background(), dEnv.DbData(), "feature", "main", false, nil))
+	createTestCommits(t, dEnv, "feature", 500)
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 500)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := validateBranchMergedIntoCurrentWorkingBranch(ctx, dEnv.DbData(), ref.NewBranchRef("feature"), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+
+	feature, err := dEnv.DoltDB.ResolveCommitRef(context.Background(), ref.NewBranchRef("feature"))
+	require.NoError(t, err)
+	main, err := dEnv.DoltDB.ResolveCommitRef(context.Background(), ref.NewBranchRef(env.DefaultInitBranch))
+	require.NoError(t, err)
+	_, err = feature.CanFastForwardTo(ctx, main)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDeleteBranchWithMissingUpstreamRemote(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"merged", "unmerged"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+		require.NoError(t, dEnv.RepoStateWriter().UpdateBranch(name, env.BranchConfig{
+			Merge:  ref.MarshalableRef{Ref: ref.NewBranchRef(name)},
+			Remote: "origin",
+		}))
+	}
+	createTestCommits(t, dEnv, "unmerged", 1)
+
+	err := validateBranchMergedIntoUpstream(ctx, dEnv.DbData(), ref.NewBranchRef("merged"), "origin", nil, RetryPolicy{})
+	assert.ErrorIs(t, err, env.ErrRemoteNotFound)
+
+	// without the remote, the branch is checked against the current branch instead
+	assert.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "merged", DeleteOptions{}, nil, nil))
+	assert.Equal(t, ErrUnmergedBranch, DeleteBranch(ctx, dEnv.DbData(), "unmerged", DeleteOptions{}, nil, nil))
+}
+
+// flakyRemoteDbProvider fails the first |failures| requests for a remote database, then opens a new database over the
+// chunks of |db|, and counts how many of the databases it opened are closed
+type flakyRemoteDbProvider struct {
+	db       *doltdb.DoltDB
+	failures int
+	calls    int
+	closes   int
+}
+
+func (p *flakyRemoteDbProvider) GetRemoteDB(_ context.Context, _ *types.NomsBinFormat, _ env.Remote, _ bool) (*doltdb.DoltDB, error) {
+	p.calls++
+	if p.calls <= p.failures {
+		return nil, errors.New("connection reset")
+	}
+	cs := datas.ChunkStoreFromDatabase(doltdb.HackDatasDatabaseFromDoltDB(p.db))
+	return doltdb.DoltDBFromCS(closeCountingChunkStore{cs, &p.closes}), nil
+}
+
+// closeCountingChunkStore counts the times it's closed, without closing the chunk store it wraps
+type closeCountingChunkStore struct {
+	chunks.ChunkStore
+	closes *int
+}
+
+func (cs closeCountingChunkStore) Close() error {
+	*cs.closes++
+	return nil
+}
+
+func TestDeleteBranchRetriesUnreachableRemote(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, dEnv.RepoStateWriter().AddRemote(env.NewRemote("origin", "file:///doesnotexist", nil)))
+	for _, name := range []string{"first", "second"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+		require.NoError(t, dEnv.RepoStateWriter().UpdateBranch(name, env.BranchConfig{
+			Merge:  ref.MarshalableRef{Ref: ref.NewBranchRef(name)},
+			Remote: "origin",
+		}))
+	}
+
+	// the local database stands in for the remote, so each branch is merged into its upstream
+	// without retries, the remote's error is returned as is
+	pro := &flakyRemoteDbProvider{db: dEnv.DoltDB, failures: 2}
+	err := DeleteBranch(ctx, dEnv.DbData(), "first", DeleteOptions{}, pro, nil)
+	require.EqualError(t, err, "connection reset")
+	var unreachable RemoteUnreachableError
+	assert.False(t, errors.As(err, &unreachable))
+	assert.Equal(t, 1, pro.calls)
+
+	pro = &flakyRemoteDbProvider{db: dEnv.DoltDB, failures: 2}
+	retry := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "first", DeleteOptions{RemoteRetry: retry}, pro, nil))
+	assert.Equal(t, 3, pro.calls)
+	assert.Equal(t, 1, pro.closes)
+
+	pro = &flakyRemoteDbProvider{db: dEnv.DoltDB, failures: 3}
+	err = DeleteBranch(ctx, dEnv.DbData(), "second", DeleteOptions{RemoteRetry: retry}, pro, nil)
+	require.ErrorAs(t, err, &unreachable)
+	assert.Equal(t, "origin", unreachable.Remote)
+	assert.Equal(t, 3, pro.calls)
+
+	// the database is closed when the branch can't be resolved on it, too
+	remoteEnv := dtestutils.CreateTestEnv()
+	defer remoteEnv.DoltDB.Close()
+	pro = &flakyRemoteDbProvider{db: remoteEnv.DoltDB}
+	_ = DeleteBranch(ctx, dEnv.DbData(), "second", DeleteOptions{RemoteRetry: retry}, pro, nil)
+	assert.Equal(t, 1, pro.calls)
+	assert.Equal(t, 1, pro.closes)
+}
+
+func TestRemoteDefaultBranch(t *testing.T) {
+	ctx := context.Background()
+	remoteEnv := dtestutils.CreateTestEnv()
+	defer remoteEnv.DoltDB.Close()
+	remoteDb := remoteEnv.DoltDB
+	origin := env.NewRemote("origin", "file:///doesnotexist", nil)
+	format := remoteDb.ValueReadWriter().Format()
+
+	require.NoError(t, CreateBranchOnDB(ctx, remoteDb, "alpha", env.DefaultInitBranch, false, nil, nil))
+	pro := &flakyRemoteDbProvider{db: remoteDb}
+	name, err := RemoteDefaultBranch(ctx, pro, origin, format)
+	require.NoError(t, err)
+	assert.Equal(t, env.DefaultInitBranch, name)
+
+	// without main or master, the first branch by name
+	require.NoError(t, CreateBranchOnDB(ctx, remoteDb, "zeta", env.DefaultInitBranch, false, nil, nil))
+	require.NoError(t, remoteDb.DeleteBranch(ctx, ref.NewBranchRef(env.DefaultInitBranch), nil))
+	name, err = RemoteDefaultBranch(ctx, pro, origin, format)
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", name)
+
+	pro = &flakyRemoteDbProvider{db: remoteDb, failures: 1}
+	_, err = RemoteDefaultBranch(ctx, pro, origin, format)
+	var unreachable RemoteUnreachableError
+	assert.ErrorAs(t, err, &unreachable)
+}
+
+func TestCanDeleteLocally(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"merged", "unmerged"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+		// the upstream's remote isn't configured, and must not be needed
+		require.NoError(t, dEnv.RepoStateWriter().UpdateBranch(name, env.BranchConfig{
+			Merge:  ref.MarshalableRef{Ref: ref.NewBranchRef(name)},
+			Remote: "origin",
+		}))
+	}
+	createTestCommits(t, dEnv, "unmerged", 1)
+
+	ok, reason, err := CanDeleteLocally(ctx, dEnv.DbData(), ref.NewBranchRef("merged"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Contains(t, reason, "fully merged")
+
+	ok, reason, err = CanDeleteLocally(ctx, dEnv.DbData(), ref.NewBranchRef("unmerged"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, reason, "not fully merged")
+
+	ok, reason, err = CanDeleteLocally(ctx, dEnv.DbData(), ref.NewBranchRef("main"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, reason, "checked out")
+
+	_, _, err = CanDeleteLocally(ctx, dEnv.DbData(), ref.NewBranchRef("missing"))
+	assert.Error(t, err)
+}
+
+func TestDeleteBranchMergedInto(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"release", "feature"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+	}
+	commits := createTestCommits(t, dEnv, "feature", 1)
+	require.NoError(t, FastForwardBranch(ctx, dEnv.DbData(), ref.NewBranchRef("main"), commits[1], nil))
+
+	// merged into main, which is checked out, but not into release
+	release := ref.NewBranchRef("release")
+	err := DeleteBranch(ctx, dEnv.DbData(), "feature", DeleteOptions{MergedInto: release}, nil, nil)
+	assert.Equal(t, ErrUnmergedBranch, err)
+
+	require.NoError(t, FastForwardBranch(ctx, dEnv.DbData(), release, commits[1], nil))
+	assert.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "feature", DeleteOptions{MergedInto: release}, nil, nil))
+
+	err = DeleteBranch(ctx, dEnv.DbData(), "release", DeleteOptions{MergedInto: ref.NewBranchRef("missing")}, nil, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+}
+
+func TestDeleteBranchWithCurrentHead(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	commits := createTestCommits(t, dEnv, "feature", 1)
+	require.NoError(t, FastForwardBranch(ctx, dEnv.DbData(), ref.NewBranchRef("main"), commits[1], nil))
+
+	// the head held is used in place of the current one, which feature is merged into
+	err := DeleteBranch(ctx, dEnv.DbData(), "feature", DeleteOptions{CurrentHead: NewCurrentHeadCache(commits[0])}, nil, nil)
+	assert.Equal(t, ErrUnmergedBranch, err)
+
+	// the head isn't resolved for a branch that's checked against another target
+	currentHead := NewCurrentHeadCache(nil)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "other", "main", false, nil))
+	opts := DeleteOptions{CurrentHead: currentHead, MergedInto: ref.NewBranchRef("main")}
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "other", opts, nil, nil))
+	assert.Equal(t, 0, currentHead.resolves)
+
+	assert.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "feature", DeleteOptions{CurrentHead: currentHead}, nil, nil))
+	assert.Equal(t, 1, currentHead.resolves)
+	head, err := currentHead.Resolve(ctx, dEnv.DbData())
+	require.NoError(t, err)
+	assert.Equal(t, mustHashOf(t, commits[1]), mustHashOf(t, head))
+	assert.Equal(t, 1, currentHead.resolves)
+}
+
+// BenchmarkDeleteMergedBranches reports how many times the head of the current branch is resolved to delete many
+// merged branches, with a CurrentHeadCache for each branch, as when they're deleted one at a time, and with one shared
+// by all of them
+func BenchmarkDeleteMergedBranches(b *testing.B) {
+	const numBranches = 100
+	for _, shared := range []bool{false, true} {
+		b.Run(fmt.Sprintf("sharedCurrentHead=%t", shared), func(b *testing.B) {
+			ctx := context.Background()
+			dEnv := dtestutils.CreateTestEnv()
+			defer dEnv.DoltDB.Close()
+
+			resolves := 0
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				for j := 0; j < numBranches; j++ {
+					err := CreateBranchOnDB(ctx, dEnv.DoltDB, fmt.Sprintf("branch%d", j), env.DefaultInitBranch, false, nil, nil)
+					require.NoError(b, err)
+				}
+				b.StartTimer()
+
+				opts := DeleteOptions{Ancestry: NewAncestryCache(), CurrentHead: NewCurrentHeadCache(nil)}
+				for j := 0; j < numBranches; j++ {
+					if !shared && j > 0 {
+						resolves += opts.CurrentHead.resolves
+						opts.CurrentHead = NewCurrentHeadCache(nil)
+					}
+					err := DeleteBranch(ctx, dEnv.DbData(), fmt.Sprintf("branch%d", j), opts, nil, nil)
+					require.NoError(b, err)
+				}
+				resolves += opts.CurrentHead.resolves
+			}
+			b.ReportMetric(float64(resolves)/float64(b.N), "resolves/op")
+		})
+	}
+}
+
+func TestSetAndUnsetBranchUpstream(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	err := SetBranchUpstream(ctx, dEnv.DbData(), "feature", "origin", "feature")
+	assert.ErrorIs(t, err, env.ErrRemoteNotFound)
+
+	require.NoError(t, dEnv.RepoStateWriter().AddRemote(env.NewRemote("origin", "file:///doesnotexist", nil)))
+	require.NoError(t, SetBranchUpstream(ctx, dEnv.DbData(), "feature", "origin", "feature"))
+	branches, err := dEnv.RepoStateReader().GetBranches()
+	require.NoError(t, err)
+	require.Contains(t, branches, "feature")
+	assert.Equal(t, "origin", branches["feature"].Remote)
+
+	// renaming moves the upstream
+	upstream := branches["feature"]
+	require.NoError(t, RenameBranches(ctx, dEnv.DbData(), map[string]string{"feature": "renamed"}, nil, false, nil))
+	branches, err = dEnv.RepoStateReader().GetBranches()
+	require.NoError(t, err)
+	assert.NotContains(t, branches, "feature")
+	assert.Equal(t, upstream, branches["renamed"])
+
+	require.NoError(t, UnsetBranchUpstream(ctx, dEnv.DbData(), "renamed"))
+	branches, err = dEnv.RepoStateReader().GetBranches()
+	require.NoError(t, err)
+	assert.NotContains(t, branches, "renamed")
+
+	// unsetting a branch with no upstream does nothing
+	require.NoError(t, UnsetBranchUpstream(ctx, dEnv.DbData(), "renamed"))
+}
+
+func TestRenameBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"v1", "v2", "other"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+	}
+
+	assertBranches := func(expected ...string) {
+		branches, err := dEnv.DoltDB.GetBranches(ctx)
+		require.NoError(t, err)
+		var names []string
+		for _, b := range branches {
+			names = append(names, b.GetPath())
+		}
+		assert.ElementsMatch(t, expected, names)
+	}
+
+	err := RenameBranches(ctx, dEnv.DbData(), map[string]string{"v1": "release", "v2": "release"}, nil, false, nil)
+	assert.Error(t, err)
+	err = RenameBranches(ctx, dEnv.DbData(), map[string]string{"v1": "release-1", "v2": "other"}, nil, false, nil)
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+	err = RenameBranches(ctx, dEnv.DbData(), map[string]string{"v1": "release-1", "missing": "release-2"}, nil, false, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+	assertBranches("main", "v1", "v2", "other")
+
+	err = RenameBranches(ctx, dEnv.DbData(), map[string]string{"v1": "release-1", "v2": "release-2"}, nil, false, nil)
+	require.NoError(t, err)
+	assertBranches("main", "release-1", "release-2", "other")
+}
+
+func TestRenameBranchesForcedRollback(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	dbData := dEnv.DbData()
+
+	for _, name := range []string{"a", "b", "other", "dirty"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dbData, name, "main", false, nil))
+	}
+	createTestCommits(t, dEnv, "other", 1)
+	makeBranchDirty(t, dEnv, "other")
+	require.NoError(t, SetBranchDescription(ctx, dbData, "other", "the other branch"))
+	makeBranchDirty(t, dEnv, "dirty")
+	aHead, otherHead := branchHeadHash(t, dEnv, "a"), branchHeadHash(t, dEnv, "other")
+
+	// a is renamed onto other first, and then renaming b onto dirty fails, since dirty has uncommitted changes
+	err := RenameBranches(ctx, dbData, map[string]string{"a": "other", "b": "dirty"}, nil, true, nil)
+	assert.ErrorIs(t, err, ErrWorkingSetsOnBothBranches)
+
+	assert.Equal(t, aHead, branchHeadHash(t, dEnv, "a"))
+	assert.Equal(t, otherHead, branchHeadHash(t, dEnv, "other"), "the replaced branch is restored")
+	assert.True(t, hasDirtyTable(t, dEnv, "other"), "the replaced branch's working set is restored")
+	description, err := GetBranchDescription(dbData, "other")
+	require.NoError(t, err)
+	assert.Equal(t, "the other branch", description)
+	assert.True(t, hasDirtyTable(t, dEnv, "dirty"))
+}
+
+func TestCreateBranchWithOptionsUpstream(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	opts := CreateBranchOptions{Upstream: &BranchUpstream{Remote: "origin", Branch: "no-remote"}}
+	err := CreateBranchWithOptions(ctx, dEnv.DbData(), "no-remote", "main", opts, nil)
+	assert.ErrorIs(t, err, env.ErrRemoteNotFound)
+	ok, err := IsBranch(ctx, dEnv.DoltDB, "no-remote")
+	require.NoError(t, err)
+	assert.True(t, ok, "branch must be created even if the upstream can't be set")
+
+	require.NoError(t, dEnv.RepoStateWriter().AddRemote(env.NewRemote("origin", "file:///doesnotexist", nil)))
+	opts = CreateBranchOptions{Upstream: &BranchUpstream{Remote: "origin", Branch: "feature"}}
+	require.NoError(t, CreateBranchWithOptions(ctx, dEnv.DbData(), "feature", "main", opts, nil))
+
+	// the database alone has nowhere to record the upstream
+	err = CreateBranchOnDBWithOptions(ctx, dEnv.DoltDB, "db-only", "main", nil, opts, nil)
+	assert.Error(t, err)
+	ok, err = IsBranch(ctx, dEnv.DoltDB, "db-only")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	branches, err := dEnv.RepoStateReader().GetBranches()
+	require.NoError(t, err)
+	upstream, ok := branches["feature"]
+	require.True(t, ok)
+	assert.Equal(t, "origin", upstream.Remote)
+	_, ok = branches["no-remote"]
+	assert.False(t, ok)
+}
+
+func TestLookupBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	commits := createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+
+	exists, head, err := LookupBranch(ctx, dEnv.DoltDB, env.DefaultInitBranch)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, mustHashOf(t, commits[1]), head.String())
+
+	exists, head, err = LookupBranch(ctx, dEnv.DoltDB, "missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.True(t, head.IsEmpty())
+}
+
+func TestDeleteLastBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	// check out a branch that isn't main so that main isn't protected as the checked out branch, then delete it
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "other", "main", false, nil))
+	require.NoError(t, dEnv.RepoStateWriter().SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: ref.NewBranchRef("other")}))
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), env.DefaultInitBranch, DeleteOptions{Force: true}, nil, nil))
+	require.NoError(t, dEnv.RepoStateWriter().SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: ref.NewBranchRef(env.DefaultInitBranch)}))
+
+	err := DeleteBranch(ctx, dEnv.DbData(), "other", DeleteOptions{Force: true}, nil, nil)
+	assert.Equal(t, doltdb.ErrCannotDeleteLastBranch, err)
+
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("other"))
+	require.NoError(t, err)
+	_, err = dEnv.DoltDB.ResolveWorkingSet(ctx, wsRef)
+	assert.NoError(t, err, "working set of the last branch must survive a refused deletion")
+}
+
+func TestFastForwardBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "behind", "main", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "diverged", "main", false, nil))
+	mainCommits := createTestCommits(t, dEnv, env.DefaultInitBranch, 2)
+	createTestCommits(t, dEnv, "diverged", 1)
+
+	behind := ref.NewBranchRef("behind")
+	require.NoError(t, FastForwardBranch(ctx, dEnv.DbData(), behind, mainCommits[2], nil))
+	head, err := dEnv.DoltDB.ResolveCommitRef(ctx, behind)
+	require.NoError(t, err)
+	assert.Equal(t, mustHashOf(t, mainCommits[2]), mustHashOf(t, head))
+
+	// already up to date
+	assert.NoError(t, FastForwardBranch(ctx, dEnv.DbData(), behind, mainCommits[2], nil))
+	assert.ErrorIs(t, FastForwardBranch(ctx, dEnv.DbData(), behind, mainCommits[1], nil), doltdb.ErrIsAhead)
+	assert.ErrorIs(t, FastForwardBranch(ctx, dEnv.DbData(), ref.NewBranchRef("diverged"), mainCommits[2], nil), ErrNotFastForward)
+}
+
+func TestAdvanceBranchTo(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "target", "main", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "diverged", "main", false, nil))
+	commits := createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	createTestCommits(t, dEnv, "diverged", 1)
+	h, err := commits[1].HashOf()
+	require.NoError(t, err)
+
+	target := ref.NewBranchRef("target")
+	require.NoError(t, AdvanceBranchTo(ctx, dEnv.DbData(), target, h, nil))
+	head, err := dEnv.DoltDB.ResolveCommitRef(ctx, target)
+	require.NoError(t, err)
+	assert.Equal(t, h.String(), mustHashOf(t, head))
+
+	headRoot, err := head.GetRootValue(ctx)
+	require.NoError(t, err)
+	headRootHash, err := headRoot.HashOf()
+	require.NoError(t, err)
+	wsRoot, ok, err := WorkingSetRoot(ctx, dEnv.DoltDB, target)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, headRootHash, wsRoot)
+
+	err = AdvanceBranchTo(ctx, dEnv.DbData(), ref.NewBranchRef("diverged"), h, nil)
+	assert.ErrorIs(t, err, ErrNotFastForward)
+	err = AdvanceBranchTo(ctx, dEnv.DbData(), target, hash.Of([]byte("no such commit")), nil)
+	assert.Error(t, err)
+}
+
+func TestVerifyBranchHeads(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+
+	headErrs, err := VerifyBranchHeads(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Empty(t, headErrs)
+}
+
+func TestCopyBranchUnique(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	name, err := CopyBranchUnique(ctx, dEnv.DoltDB, "main", "snapshot/main", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot/main", name)
+
+	name, err = CopyBranchUnique(ctx, dEnv.DoltDB, "main", "snapshot/main", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot/main-1", name)
+
+	name, err = CopyBranchUnique(ctx, dEnv.DoltDB, "main", "snapshot/main", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot/main-2", name)
+
+	_, err = CopyBranchUnique(ctx, dEnv.DoltDB, "main", "head", nil)
+	assert.Equal(t, doltdb.ErrInvBranchName, err)
+}
+
+// makeBranchDirty adds a new table to the working root of the branch named
+func makeBranchDirty(t *testing.T, dEnv *env.DoltEnv, branch string) {
+	ctx := context.Background()
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef(branch))
+	require.NoError(t, err)
+	ws, err := dEnv.DoltDB.ResolveWorkingSet(ctx, wsRef)
+	require.NoError(t, err)
+	wsHash, err := ws.HashOf()
+	require.NoError(t, err)
+
+	sch, err := dtestutils.Schema()
+	require.NoError(t, err)
+	root, err := ws.WorkingRoot().CreateEmptyTable(ctx, "dirty", sch)
+	require.NoError(t, err)
+
+	err = dEnv.DoltDB.UpdateWorkingSet(ctx, wsRef, ws.WithWorkingRoot(root), wsHash, doltdb.TodoWorkingSetMeta(), nil)
+	require.NoError(t, err)
+}
+
+func TestSetBranchHead(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	featureCommits := createTestCommits(t, dEnv, "feature", 2)
+	feature := ref.NewBranchRef("feature")
+
+	assertHead := func(expected *doltdb.Commit) {
+		head, err := dEnv.DoltDB.ResolveCommitRef(ctx, feature)
+		require.NoError(t, err)
+		assert.Equal(t, mustHashOf(t, expected), mustHashOf(t, head))
+	}
+
+	require.NoError(t, SetBranchHead(ctx, dEnv.DbData(), feature, featureCommits[1], true, false, nil))
+	assertHead(featureCommits[1])
+
+	makeBranchDirty(t, dEnv, "feature")
+	assert.Equal(t, ErrUncommittedChanges, SetBranchHead(ctx, dEnv.DbData(), feature, featureCommits[2], true, false, nil))
+	assertHead(featureCommits[1])
+
+	require.NoError(t, SetBranchHead(ctx, dEnv.DbData(), feature, featureCommits[2], false, false, nil))
+	assertHead(featureCommits[2])
+
+	require.NoError(t, SetBranchHead(ctx, dEnv.DbData(), feature, featureCommits[0], true, true, nil))
+	assertHead(featureCommits[0])
+	dirty, err := branchHasUncommittedChanges(ctx, dEnv.DoltDB, feature, featureCommits[0])
+	require.NoError(t, err)
+	assert.False(t, dirty)
+}
+
+func TestBranchMergeGraph(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "old", "main", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "same", "main", false, nil))
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	createTestCommits(t, dEnv, "feature", 1)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "diverged", "old", false, nil))
+	createTestCommits(t, dEnv, "diverged", 1)
+
+	graph, err := BranchMergeGraph(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"main":     {"old", "same"},
+		"old":      {"same"},
+		"same":     {"old"},
+		"feature":  {"main", "old", "same"},
+		"diverged": {"old", "same"},
+	}, graph)
+}
+
+func TestRenameBranchOntoDirtyBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"src", "dirty", "clean"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+	}
+	makeBranchDirty(t, dEnv, "dirty")
+
+	err := RenameBranch(ctx, dEnv.DbData(), "src", "dirty", nil, true, nil)
+	assert.ErrorIs(t, err, ErrWorkingSetsOnBothBranches)
+	ok, err := IsBranch(ctx, dEnv.DoltDB, "src")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	dirtyRef := ref.NewBranchRef("dirty")
+	head, err := dEnv.DoltDB.ResolveCommitRef(ctx, dirtyRef)
+	require.NoError(t, err)
+	dirty, err := branchHasUncommittedChanges(ctx, dEnv.DoltDB, dirtyRef, head)
+	require.NoError(t, err)
+	assert.True(t, dirty, "a refused rename must not touch the target's working set")
+
+	opts := RenameOptions{Force: true, DiscardTargetChanges: true}
+	require.NoError(t, RenameBranchWithOptions(ctx, dEnv.DbData(), "src", "dirty", nil, opts, nil))
+	dirty, err = branchHasUncommittedChanges(ctx, dEnv.DoltDB, dirtyRef, head)
+	require.NoError(t, err)
+	assert.False(t, dirty)
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "src", "main", false, nil))
+	require.NoError(t, RenameBranch(ctx, dEnv.DbData(), "src", "clean", nil, true, nil))
+	ok, err = IsBranch(ctx, dEnv.DoltDB, "src")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// failingRepoState is a repo state whose reads of the current working branch fail with |readErr| and whose writes of
+// it fail with |writeErr|, when they're set
+type failingRepoState struct {
+	env.RepoStateReader
+	env.RepoStateWriter
+	readErr, writeErr error
+}
+
+func (rs failingRepoState) CWBHeadRef() (ref.DoltRef, error) {
+	if rs.readErr != nil {
+		return nil, rs.readErr
+	}
+	return rs.RepoStateReader.CWBHeadRef()
+}
+
+func (rs failingRepoState) SetCWBHeadRef(ctx context.Context, r ref.MarshalableRef) error {
+	if rs.writeErr != nil {
+		return rs.writeErr
+	}
+	return rs.RepoStateWriter.SetCWBHeadRef(ctx, r)
+}
+
+func TestRenameBranchInterrupted(t *testing.T) {
+	interrupted := errors.New("interrupted")
+	tests := []struct {
+		name              string
+		readErr, writeErr error
+	}{
+		{"reading the current branch", interrupted, nil},
+		{"moving the current branch", nil, interrupted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			dEnv := dtestutils.CreateTestEnv()
+			defer dEnv.DoltDB.Close()
+			dbData := dEnv.DbData()
+
+			require.NoError(t, CreateBranchWithStartPt(ctx, dbData, "src", "main", false, nil))
+			createTestCommits(t, dEnv, "src", 1)
+			makeBranchDirty(t, dEnv, "src")
+			require.NoError(t, dbData.Rsw.SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: ref.NewBranchRef("src")}))
+			srcHead := branchHeadHash(t, dEnv, "src")
+
+			failing := dbData
+			rs := failingRepoState{RepoStateReader: dbData.Rsr, RepoStateWriter: dbData.Rsw, readErr: test.readErr, writeErr: test.writeErr}
+			failing.Rsr, failing.Rsw = rs, rs
+
+			err := RenameBranch(ctx, failing, "src", "dst", nil, false, nil)
+			assert.ErrorIs(t, err, interrupted)
+
+			assert.Equal(t, srcHead, branchHeadHash(t, dEnv, "src"))
+			assert.True(t, hasDirtyTable(t, dEnv, "src"))
+			ok, err := IsBranch(ctx, dEnv.DoltDB, "dst")
+			require.NoError(t, err)
+			assert.False(t, ok)
+			_, err = dEnv.DoltDB.ResolveWorkingSet(ctx, mustWorkingSetRef(t, "dst"))
+			assert.ErrorIs(t, err, doltdb.ErrWorkingSetNotFound)
+			headRef, err := dbData.Rsr.CWBHeadRef()
+			require.NoError(t, err)
+			assert.Equal(t, "src", headRef.GetPath())
+
+			// the rename can be retried
+			require.NoError(t, RenameBranch(ctx, dbData, "src", "dst", nil, false, nil))
+			assert.Equal(t, srcHead, branchHeadHash(t, dEnv, "dst"))
+			assert.True(t, hasDirtyTable(t, dEnv, "dst"))
+			ok, err = IsBranch(ctx, dEnv.DoltDB, "src")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func mustWorkingSetRef(t *testing.T, branch string) ref.WorkingSetRef {
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef(branch))
+	require.NoError(t, err)
+	return wsRef
+}
+
+func TestCreateBranchFromTagAncestor(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	// the init commit and two more, so the tag has two generations of ancestors
+	history := createTestCommits(t, dEnv, env.DefaultInitBranch, 2)
+	headRef, err := dEnv.RepoStateReader().CWBHeadRef()
+	require.NoError(t, err)
+	props := TagProps{TaggerName: "billy bob", TaggerEmail: "bigbillieb@fake.horse"}
+	require.NoError(t, CreateTagOnDB(ctx, ddb, "v1.0.0", "main", props, headRef))
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "at-tag", "v1.0.0", false, headRef, nil))
+	assert.Equal(t, mustHashOf(t, history[2]), branchHeadHash(t, dEnv, "at-tag"))
+
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "before-tag", "v1.0.0^", false, headRef, nil))
+	assert.Equal(t, mustHashOf(t, history[1]), branchHeadHash(t, dEnv, "before-tag"))
+
+	err = CreateBranchOnDB(ctx, ddb, "too-far", "v1.0.0~10", false, headRef, nil)
+	assert.ErrorIs(t, err, doltdb.ErrInvalidAncestorSpec)
+	assert.Contains(t, err.Error(), "the history of 'v1.0.0' ends after 2 of the 10 ancestor steps")
+	ok, err := IsBranch(ctx, ddb, "too-far")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCompareAndSwapBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	history := createTestCommits(t, dEnv, "main", 2)
+	hashes := make([]hash.Hash, len(history))
+	for i, cm := range history {
+		h, err := cm.HashOf()
+		require.NoError(t, err)
+		hashes[i] = h
+	}
+	main := ref.NewBranchRef("main")
+
+	swapped, err := CompareAndSwapBranch(ctx, ddb, main, hashes[2], hashes[0], nil)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	assert.Equal(t, hashes[0].String(), branchHeadHash(t, dEnv, "main"))
+
+	// a racer that read the head before the swap loses, and the branch is left as it is
+	swapped, err = CompareAndSwapBranch(ctx, ddb, main, hashes[2], hashes[1], nil)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	assert.Equal(t, hashes[0].String(), branchHeadHash(t, dEnv, "main"))
+
+	// swapping to the current head succeeds without changing anything
+	swapped, err = CompareAndSwapBranch(ctx, ddb, main, hashes[0], hashes[0], nil)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	_, err = CompareAndSwapBranch(ctx, ddb, ref.NewBranchRef("missing"), hash.Hash{}, hashes[0], nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+}
+
+func TestBranchesModifiedSince(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	since := time.Now().Add(time.Hour)
+	defer func() { datas.CommitNowFunc = time.Now }()
+	datas.CommitNowFunc = func() time.Time { return since.Add(time.Minute) }
+
+	for _, name := range []string{"untouched", "updated", "also-updated"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+	}
+	createTestCommits(t, dEnv, "updated", 1)
+	createTestCommits(t, dEnv, "also-updated", 1)
+
+	modifiedSince := func(since time.Time) []string {
+		branches, err := BranchesModifiedSince(ctx, ddb, since)
+		require.NoError(t, err)
+		var names []string
+		for _, b := range branches {
+			names = append(names, b.GetPath())
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"also-updated", "updated"}, modifiedSince(since))
+	assert.Empty(t, modifiedSince(since.Add(time.Hour)))
+	assert.Equal(t, []string{"also-updated", "main", "untouched", "updated"}, modifiedSince(time.Time{}))
+}
+
+func TestIsAncestor(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 2)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main~1", false, nil))
+	createTestCommits(t, dEnv, "feature", 1)
+
+	tests := []struct {
+		ancestor   string
+		descendant string
+		expected   bool
+	}{
+		{"HEAD~2", "HEAD", true},
+		{"main", "main", true},
+		{"main~1", "feature", true},
+		{"main", "feature", false},
+		{"feature", "main", false},
+		{"HEAD", "HEAD~1", false},
+	}
+	for _, test := range tests {
+		isAnc, err := IsAncestor(ctx, dEnv, test.ancestor, test.descendant)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, isAnc, "%s is ancestor of %s", test.ancestor, test.descendant)
+	}
+
+	_, err := IsAncestor(ctx, dEnv, "nosuchbranch", "main")
+	assert.Error(t, err)
+}
+
+func TestPruneMergedBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 2)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "merged1", "main~1", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "merged2", "main", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "unmerged", "main", false, nil))
+	createTestCommits(t, dEnv, "unmerged", 1)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	deleted, err := PruneMergedBranches(canceled, dEnv.DbData(), nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, deleted)
+
+	type progressCall struct {
+		branch  string
+		deleted bool
+	}
+	var calls []progressCall
+	deleted, err = PruneMergedBranches(ctx, dEnv.DbData(), func(branch string, deleted bool) {
+		calls = append(calls, progressCall{branch, deleted})
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"merged1", "merged2"}, deleted)
+	assert.Equal(t, []progressCall{{"merged1", true}, {"merged2", true}, {"unmerged", false}}, calls)
+
+	for _, name := range []string{env.DefaultInitBranch, "unmerged"} {
+		ok, err := IsBranch(ctx, dEnv.DoltDB, name)
+		require.NoError(t, err)
+		assert.True(t, ok, name)
+	}
+	require.NoError(t, RecoverDeletedBranch(ctx, dEnv.DbData(), "merged1"))
+}
+
+func TestDestroyBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"unmerged", "damaged"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+	}
+	createTestCommits(t, dEnv, "unmerged", 1)
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("damaged"))
+	require.NoError(t, err)
+	require.NoError(t, dEnv.DoltDB.DeleteWorkingSet(ctx, wsRef))
+
+	err = DeleteBranch(ctx, dEnv.DbData(), env.DefaultInitBranch, DeleteOptions{Destroy: true}, nil, nil)
+	assert.Equal(t, ErrCOBranchDelete, err)
+
+	for _, name := range []string{"unmerged", "damaged"} {
+		require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), name, DeleteOptions{Destroy: true}, nil, nil))
+		ok, err := IsBranch(ctx, dEnv.DoltDB, name)
+		require.NoError(t, err)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestCommitCount(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	mainCommits := createTestCommits(t, dEnv, env.DefaultInitBranch, 3)
+	mainRef := ref.NewBranchRef(env.DefaultInitBranch)
+
+	count, err := CommitCount(ctx, dEnv.DoltDB, mainRef, hash.Hash{})
+	require.NoError(t, err)
+	// the test env's initial commit plus the ones created here
+	assert.Equal(t, uint64(4), count)
+
+	since, err := mainCommits[1].HashOf()
+	require.NoError(t, err)
+	count, err = CommitCount(ctx, dEnv.DoltDB, mainRef, since)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = CommitCount(canceled, dEnv.DoltDB, mainRef, hash.Hash{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestEstimateReclaimableChunks(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "merged", "main", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "unmerged", "main", false, nil))
+	createTestCommits(t, dEnv, "unmerged", 2)
+
+	merged, err := EstimateReclaimableChunks(ctx, dEnv.DoltDB, []ref.DoltRef{ref.NewBranchRef("merged")})
+	require.NoError(t, err)
+	unmerged, err := EstimateReclaimableChunks(ctx, dEnv.DoltDB, []ref.DoltRef{ref.NewBranchRef("unmerged")})
+	require.NoError(t, err)
+	assert.Greater(t, unmerged, merged)
+
+	both, err := EstimateReclaimableChunks(ctx, dEnv.DoltDB, []ref.DoltRef{ref.NewBranchRef("merged"), ref.NewBranchRef("unmerged")})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, both, unmerged)
+
+	missing, err := EstimateReclaimableChunks(ctx, dEnv.DoltDB, []ref.DoltRef{ref.NewBranchRef("missing")})
+	require.NoError(t, err)
+	assert.Zero(t, missing)
+}
+
+func TestCommitRange(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "main", false, nil))
+	featureCommits := createTestCommits(t, dEnv, "feature", 3)
+	createTestCommits(t, dEnv, "main", 2)
+
+	main, feature := ref.NewBranchRef("main"), ref.NewBranchRef("feature")
+	hashes, err := CommitRange(ctx, dEnv.DoltDB, feature, main)
+	require.NoError(t, err)
+	require.Len(t, hashes, 3)
+	for i, h := range hashes {
+		assert.Equal(t, mustHashOf(t, featureCommits[3-i]), h.String())
+	}
+
+	hashes, err = CommitRange(ctx, dEnv.DoltDB, main, feature)
+	require.NoError(t, err)
+	assert.Len(t, hashes, 2)
+
+	hashes, err = CommitRange(ctx, dEnv.DoltDB, feature, feature)
+	require.NoError(t, err)
+	assert.Empty(t, hashes)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = CommitRange(canceled, dEnv.DoltDB, feature, main)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLikelyParentBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 2)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "old", "main~1", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "release", "main", false, nil))
+	release := createTestCommits(t, dEnv, "release", 1)
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature", "release", false, nil))
+	createTestCommits(t, dEnv, "feature", 1)
+	mainCommits := createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+
+	parent, base, err := LikelyParentBranch(ctx, dEnv.DoltDB, ref.NewBranchRef("feature"))
+	require.NoError(t, err)
+	assert.Equal(t, "release", parent.GetPath())
+	assert.Equal(t, mustHashOf(t, release[1]), base.String())
+
+	// "aaa" and main are equally close, and the default branch wins the tie
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "aaa", "main", false, nil))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "feature2", "main", false, nil))
+	createTestCommits(t, dEnv, "feature2", 1)
+	parent, base, err = LikelyParentBranch(ctx, dEnv.DoltDB, ref.NewBranchRef("feature2"))
+	require.NoError(t, err)
+	assert.Equal(t, env.DefaultInitBranch, parent.GetPath())
+	assert.Equal(t, mustHashOf(t, mainCommits[1]), base.String())
+}
+
+// defaultBranchRepoState is a RepoStateReader with a configured default branch
+type defaultBranchRepoState struct {
+	env.RepoStateReader
+	defaultBranch string
+}
+
+func (rs defaultBranchRepoState) GetDefaultBranch() (string, error) {
+	return rs.defaultBranch, nil
+}
+
+func TestDefaultBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	defaultRef, err := DefaultBranch(ctx, dEnv.DbData())
+	require.NoError(t, err)
+	assert.Equal(t, env.DefaultInitBranch, defaultRef.GetPath())
+
+	dbData := dEnv.DbData()
+	dbData.Rsr = defaultBranchRepoState{RepoStateReader: dbData.Rsr, defaultBranch: "dev"}
+	_, err = DefaultBranch(ctx, dbData)
+	assert.ErrorIs(t, err, ErrDefaultBranchNotFound)
+
+	// HEAD can't fall back to a default branch that doesn't exist
+	require.NoError(t, dEnv.RepoStateWriter().SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: ref.NewBranchRef("missing")}))
+	err = CreateBranchWithStartPt(ctx, dbData, "from-head", "HEAD", false, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+	require.NoError(t, dEnv.RepoStateWriter().SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: ref.NewBranchRef("main")}))
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dbData, "dev", "main", false, nil))
+	devCommits := createTestCommits(t, dEnv, "dev", 1)
+	defaultRef, err = DefaultBranch(ctx, dbData)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", defaultRef.GetPath())
+
+	// HEAD refers to the default branch when the current working branch was never created
+	require.NoError(t, dEnv.RepoStateWriter().SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: ref.NewBranchRef("missing")}))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dbData, "from-head", "HEAD", false, nil))
+	_, head, err := LookupBranch(ctx, dEnv.DoltDB, "from-head")
+	require.NoError(t, err)
+	assert.Equal(t, mustHashOf(t, devCommits[1]), head.String())
+
+	// but not when it was deleted
+	require.NoError(t, CreateBranchWithStartPt(ctx, dbData, "gone", "main", false, nil))
+	require.NoError(t, recordDeletedBranch(ctx, dEnv.DoltDB, ref.NewBranchRef("gone")))
+	require.NoError(t, dEnv.DoltDB.DeleteBranch(ctx, ref.NewBranchRef("gone"), nil))
+	require.NoError(t, dEnv.RepoStateWriter().SetCWBHeadRef(ctx, ref.MarshalableRef{Ref: ref.NewBranchRef("gone")}))
+	err = CreateBranchWithStartPt(ctx, dbData, "from-gone", "HEAD", false, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNotFound)
+}
+
+func TestCreateHierarchicalBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "team/feature/x", "main", false, nil))
+	ok, err := IsBranch(ctx, dEnv.DoltDB, "team/feature/x")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("team/feature/x"))
+	require.NoError(t, err)
+	assert.Equal(t, "heads/team/feature/x", wsRef.GetPath())
+	_, err = dEnv.DoltDB.ResolveWorkingSet(ctx, wsRef)
+	require.NoError(t, err)
+	headRef, err := wsRef.ToHeadRef()
+	require.NoError(t, err)
+	assert.Equal(t, "team/feature/x", headRef.GetPath())
+
+	for name, reason := range map[string]string{
+		"/team/x":  "can't start with '/'",
+		"team/x/":  "can't end with '/'",
+		"team//x":  "empty path segment",
+		"team/x//": "can't end with '/'",
+	} {
+		err := CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), reason, name)
+	}
+}
+
+func TestCreateUnicodeEquivalentBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	dbData := dEnv.DbData()
+
+	// "café" with a precomposed "é", and with "e" followed by a combining accent
+	nfc, nfd := "caf\u00e9", "cafe\u0301"
+	require.NotEqual(t, nfc, nfd)
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dbData, nfc, "main", false, nil))
+	err := CreateBranchWithStartPt(ctx, dbData, nfd, "main", false, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNameCollision)
+	err = CopyBranchOnDB(ctx, dEnv.DoltDB, "main", "team/"+nfd, false, nil)
+	assert.NoError(t, err, "names are only compared whole")
+	err = CopyBranchOnDB(ctx, dEnv.DoltDB, "main", nfd, false, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNameCollision)
+	require.NoError(t, DeleteBranch(ctx, dbData, nfc, DeleteOptions{Force: true}, nil, nil))
+
+	// a branch that already has the NFD spelling, e.g. one pulled from a remote, can still be updated
+	require.NoError(t, CreateBranchWithStartPt(ctx, dbData, nfd, "main", false, nil))
+	createTestCommits(t, dEnv, "main", 1)
+	head, err := dEnv.DoltDB.ResolveCommitRef(ctx, ref.NewBranchRef("main"))
+	require.NoError(t, err)
+	require.NoError(t, dEnv.DoltDB.NewBranchAtCommit(ctx, ref.NewBranchRef(nfd), head, nil))
+
+	err = CreateBranchWithStartPt(ctx, dbData, nfc, "main", false, nil)
+	assert.ErrorIs(t, err, doltdb.ErrBranchNameCollision)
+	ok, err := IsBranch(ctx, dEnv.DoltDB, nfc)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// renaming the branch to the other spelling replaces it, so it's allowed
+	require.NoError(t, RenameBranch(ctx, dbData, nfd, nfc, nil, false, nil))
+	ok, err = IsBranch(ctx, dEnv.DoltDB, nfc)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -17,6 +17,7 @@ package actions
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
@@ -243,6 +244,39 @@ func CheckoutBranch(ctx context.Context, dEnv *env.DoltEnv, brName string, force
 	return nil
 }
 
+// CreateAndCheckoutBranch creates a new branch named |newBranch| at |startPt| and checks it out, carrying over any
+// uncommitted changes in the current working set, like `checkout -b`. If the checkout fails, the new branch is removed
+// again so that it isn't left behind without having been switched to.
+func CreateAndCheckoutBranch(ctx context.Context, dEnv *env.DoltEnv, newBranch, startPt string) error {
+	err := CreateBranchWithStartPt(ctx, dEnv.DbData(), newBranch, startPt, false, nil)
+	if err != nil {
+		return err
+	}
+
+	err = CheckoutBranch(ctx, dEnv, newBranch, false)
+	if err != nil {
+		if rollbackErr := removeNewBranch(ctx, dEnv.DoltDB, ref.NewBranchRef(newBranch)); rollbackErr != nil {
+			return fmt.Errorf("%w; additionally, the new branch '%s' could not be removed: %v", err, newBranch, rollbackErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// removeNewBranch deletes a branch and its working set without recording it as a deleted branch
+func removeNewBranch(ctx context.Context, ddb *doltdb.DoltDB, branchRef ref.BranchRef) error {
+	wsRef, err := ref.WorkingSetRefForHead(branchRef)
+	if err != nil {
+		return err
+	}
+	err = ddb.DeleteWorkingSet(ctx, wsRef)
+	if err != nil && !errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+		return err
+	}
+	return ddb.DeleteBranch(ctx, branchRef, nil)
+}
+
 func transferWorkingChanges(
 	ctx context.Context,
 	dEnv *env.DoltEnv,
@@ -0,0 +1,71 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/filesys"
+)
+
+func TestCreateAndCheckoutBranch(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+
+	require.NoError(t, CreateAndCheckoutBranch(ctx, dEnv, "feature", "main~1"))
+	headRef, err := dEnv.RepoStateReader().CWBHeadRef()
+	require.NoError(t, err)
+	assert.Equal(t, ref.NewBranchRef("feature"), headRef)
+
+	err = CreateAndCheckoutBranch(ctx, dEnv, env.DefaultInitBranch, "feature")
+	assert.Error(t, err)
+	headRef, err = dEnv.RepoStateReader().CWBHeadRef()
+	require.NoError(t, err)
+	assert.Equal(t, ref.NewBranchRef("feature"), headRef)
+
+	// the branch is created in the database, but checking it out fails to write the repo state
+	dEnv.FS = readOnlyFS{dEnv.FS}
+	err = CreateAndCheckoutBranch(ctx, dEnv, "unswitched", "main")
+	assert.ErrorIs(t, err, env.ErrStateUpdate)
+	ok, err := IsBranch(ctx, dEnv.DoltDB, "unswitched")
+	require.NoError(t, err)
+	assert.False(t, ok, "the new branch should be removed when checking it out fails")
+	_, err = dEnv.DoltDB.ResolveWorkingSet(ctx, mustWorkingSetRef(t, "unswitched"))
+	assert.ErrorIs(t, err, doltdb.ErrWorkingSetNotFound)
+	deleted, err := DeletedBranches(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.NotContains(t, deleted, "unswitched")
+}
+
+// readOnlyFS is a filesystem whose file writes fail
+type readOnlyFS struct {
+	filesys.Filesys
+}
+
+func (fs readOnlyFS) WriteFile(fp string, data []byte) error {
+	return errors.New("read-only filesystem")
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+var ErrRefNotFound = errors.New("no matching branch, tag or commit")
+
+// RefKind is a kind of thing a user-supplied ref name can refer to
+type RefKind int
+
+const (
+	RefKindBranch RefKind = iota
+	RefKindTag
+	RefKindCommit
+)
+
+func (k RefKind) String() string {
+	switch k {
+	case RefKindBranch:
+		return "branch"
+	case RefKindTag:
+		return "tag"
+	case RefKindCommit:
+		return "commit"
+	default:
+		return fmt.Sprintf("RefKind(%d)", int(k))
+	}
+}
+
+// DefaultRefPrecedence is the precedence ClassifyRef uses when none is given: branches win over tags, and tags over
+// commit hashes.
+var DefaultRefPrecedence = []RefKind{RefKindBranch, RefKindTag, RefKindCommit}
+
+// ClassifyRef returns the kind of ref |name| refers to in |ddb|. When more than one kind matches, the first of them in
+// |precedence| wins; if |precedence| is empty, DefaultRefPrecedence is used. Kinds missing from |precedence| are not
+// considered. All the kinds that matched are also returned, in precedence order, so that callers can warn about
+// ambiguous names. Returns ErrRefNotFound if nothing matches.
+func ClassifyRef(ctx context.Context, ddb *doltdb.DoltDB, name string, precedence []RefKind) (RefKind, []RefKind, error) {
+	if len(precedence) == 0 {
+		precedence = DefaultRefPrecedence
+	}
+
+	var matches []RefKind
+	for _, kind := range precedence {
+		ok, err := refKindMatches(ctx, ddb, name, kind)
+		if err != nil {
+			return 0, nil, err
+		}
+		if ok {
+			matches = append(matches, kind)
+		}
+	}
+
+	if len(matches) == 0 {
+		return 0, nil, fmt.Errorf("%w: '%s'", ErrRefNotFound, name)
+	}
+	return matches[0], matches, nil
+}
+
+func refKindMatches(ctx context.Context, ddb *doltdb.DoltDB, name string, kind RefKind) (bool, error) {
+	switch kind {
+	case RefKindBranch:
+		if !ref.IsValidBranchName(name) {
+			return false, nil
+		}
+		return IsBranchOnDB(ctx, ddb, name)
+	case RefKindTag:
+		if !ref.IsValidTagName(name) {
+			return false, nil
+		}
+		return ddb.HasTag(ctx, name)
+	case RefKindCommit:
+		if !doltdb.IsValidCommitHash(name) {
+			return false, nil
+		}
+		cs, err := doltdb.NewCommitSpec(name)
+		if err != nil {
+			return false, err
+		}
+		_, err = ddb.Resolve(ctx, cs, nil)
+		if doltdb.IsNotACommit(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown ref kind: %s", kind)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+)
+
+func TestClassifyRef(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	head := createTestCommits(t, dEnv, env.DefaultInitBranch, 0)[0]
+	headRef, err := dEnv.RepoStateReader().CWBHeadRef()
+	require.NoError(t, err)
+	props := TagProps{TaggerName: "billy bob", TaggerEmail: "bigbillieb@fake.horse"}
+	require.NoError(t, CreateTagOnDB(ctx, ddb, "v1", "main", props, headRef))
+	require.NoError(t, CreateTagOnDB(ctx, ddb, "ambiguous", "main", props, headRef))
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "ambiguous", "main", false, nil))
+
+	tests := []struct {
+		name       string
+		precedence []RefKind
+		kind       RefKind
+		matches    []RefKind
+	}{
+		{name: "main", kind: RefKindBranch, matches: []RefKind{RefKindBranch}},
+		{name: "v1", kind: RefKindTag, matches: []RefKind{RefKindTag}},
+		{name: mustHashOf(t, head), kind: RefKindCommit, matches: []RefKind{RefKindCommit}},
+		{name: "ambiguous", kind: RefKindBranch, matches: []RefKind{RefKindBranch, RefKindTag}},
+		{
+			name:       "ambiguous",
+			precedence: []RefKind{RefKindTag, RefKindBranch, RefKindCommit},
+			kind:       RefKindTag,
+			matches:    []RefKind{RefKindTag, RefKindBranch},
+		},
+	}
+
+	for _, test := range tests {
+		kind, matches, err := ClassifyRef(ctx, ddb, test.name, test.precedence)
+		require.NoError(t, err)
+		assert.Equal(t, test.kind, kind, test.name)
+		assert.Equal(t, test.matches, matches, test.name)
+	}
+
+	_, _, err = ClassifyRef(ctx, ddb, "nothing", nil)
+	assert.ErrorIs(t, err, ErrRefNotFound)
+	_, _, err = ClassifyRef(ctx, ddb, "v1", []RefKind{RefKindBranch})
+	assert.ErrorIs(t, err, ErrRefNotFound)
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// RemoteDbCache is an env.RemoteDbProvider that memoizes the remote databases returned by another provider, so that an
+// operation on many branches that share an upstream, such as deleting them, opens each remote only once. It should be
+// scoped to a single operation and closed when the operation is done.
+type RemoteDbCache struct {
+	pro env.RemoteDbProvider
+	dbs map[remoteDbCacheKey]*doltdb.DoltDB
+	mu  sync.Mutex
+}
+
+var _ env.RemoteDbProvider = (*RemoteDbCache)(nil)
+
+type remoteDbCacheKey struct {
+	name, url   string
+	format      *types.NomsBinFormat
+	withCaching bool
+}
+
+// NewRemoteDbCache returns a RemoteDbCache for the remote databases returned by |pro|
+func NewRemoteDbCache(pro env.RemoteDbProvider) *RemoteDbCache {
+	return &RemoteDbCache{pro: pro, dbs: make(map[remoteDbCacheKey]*doltdb.DoltDB)}
+}
+
+// GetRemoteDB implements env.RemoteDbProvider
+func (c *RemoteDbCache) GetRemoteDB(ctx context.Context, format *types.NomsBinFormat, r env.Remote, withCaching bool) (*doltdb.DoltDB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := remoteDbCacheKey{name: r.Name, url: r.Url, format: format, withCaching: withCaching}
+	if db, ok := c.dbs[key]; ok {
+		return db, nil
+	}
+
+	db, err := c.pro.GetRemoteDB(ctx, format, r, withCaching)
+	if err != nil {
+		return nil, err
+	}
+	c.dbs[key] = db
+	return db, nil
+}
+
+// Close closes the remote databases that were opened without caching, and returns the first error encountered. Those
+// opened with caching are owned by the underlying provider, and are left open.
+func (c *RemoteDbCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for key, db := range c.dbs {
+		if !key.withCaching {
+			if err := db.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		delete(c.dbs, key)
+	}
+	return firstErr
+}
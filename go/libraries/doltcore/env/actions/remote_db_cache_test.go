@@ -0,0 +1,61 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+type countingRemoteDbProvider struct {
+	opened []string
+}
+
+func (p *countingRemoteDbProvider) GetRemoteDB(_ context.Context, _ *types.NomsBinFormat, r env.Remote, _ bool) (*doltdb.DoltDB, error) {
+	p.opened = append(p.opened, r.Name)
+	return dtestutils.CreateTestEnv().DoltDB, nil
+}
+
+func TestRemoteDbCache(t *testing.T) {
+	ctx := context.Background()
+	pro := &countingRemoteDbProvider{}
+	cache := NewRemoteDbCache(pro)
+
+	origin := env.NewRemote("origin", "file:///origin", nil)
+	upstream := env.NewRemote("upstream", "file:///upstream", nil)
+
+	db1, err := cache.GetRemoteDB(ctx, types.Format_Default, origin, false)
+	require.NoError(t, err)
+	db2, err := cache.GetRemoteDB(ctx, types.Format_Default, origin, false)
+	require.NoError(t, err)
+	assert.Same(t, db1, db2)
+	_, err = cache.GetRemoteDB(ctx, types.Format_Default, upstream, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"origin", "upstream"}, pro.opened)
+
+	require.NoError(t, cache.Close())
+	_, err = cache.GetRemoteDB(ctx, types.Format_Default, origin, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"origin", "upstream", "origin"}, pro.opened)
+	require.NoError(t, cache.Close())
+}
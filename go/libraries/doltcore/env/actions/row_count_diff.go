@@ -0,0 +1,77 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+)
+
+// RowCountDiffBranches returns, for each table on either of the heads of branches |a| and |b|, the number of rows in
+// the table on |b| minus the number on |a|. A table on only one of the branches has a count of zero on the other.
+// Counts are read from the tables' row indexes rather than by scanning rows, so this is cheap even for very large
+// tables, but a table whose rows changed without changing its count has a delta of zero.
+func RowCountDiffBranches(ctx context.Context, ddb *doltdb.DoltDB, a, b ref.DoltRef) (map[string]int64, error) {
+	fromRoot, err := refHeadRoot(ctx, ddb, a)
+	if err != nil {
+		return nil, err
+	}
+	toRoot, err := refHeadRoot(ctx, ddb, b)
+	if err != nil {
+		return nil, err
+	}
+
+	fromCounts, err := tableRowCounts(ctx, fromRoot)
+	if err != nil {
+		return nil, err
+	}
+	toCounts, err := tableRowCounts(ctx, toRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := make(map[string]int64, len(toCounts))
+	for name, count := range toCounts {
+		deltas[name] = int64(count)
+	}
+	for name, count := range fromCounts {
+		deltas[name] -= int64(count)
+	}
+	return deltas, nil
+}
+
+// tableRowCounts returns the number of rows in each table in |root|, keyed by table name
+func tableRowCounts(ctx context.Context, root *doltdb.RootValue) (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	err := root.IterTables(ctx, func(name string, table *doltdb.Table, _ schema.Schema) (bool, error) {
+		rows, err := table.GetRowData(ctx)
+		if err != nil {
+			return true, err
+		}
+		count, err := rows.Count()
+		if err != nil {
+			return true, err
+		}
+		counts[name] = count
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
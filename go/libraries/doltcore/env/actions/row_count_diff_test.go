@@ -0,0 +1,116 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb/durable"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/types"
+	"github.com/dolthub/dolt/go/store/val"
+)
+
+func TestRowCountDiffBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	main := ref.NewBranchRef(env.DefaultInitBranch)
+	feature := ref.NewBranchRef("feature")
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "feature", env.DefaultInitBranch, false, nil, nil))
+
+	deltas, err := RowCountDiffBranches(ctx, ddb, main, feature)
+	require.NoError(t, err)
+	assert.Empty(t, deltas)
+
+	sch, err := dtestutils.Schema()
+	require.NoError(t, err)
+	commitEmptyTable(t, dEnv, env.DefaultInitBranch, "people", sch)
+	commitEmptyTable(t, dEnv, "feature", "pets", sch)
+
+	// tables on either branch are included
+	deltas, err = RowCountDiffBranches(ctx, ddb, main, feature)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"people": 0, "pets": 0}, deltas)
+
+	_, err = RowCountDiffBranches(ctx, ddb, main, ref.NewBranchRef("missing"))
+	assert.Error(t, err)
+
+	if !types.IsFormat_DOLT(ddb.Format()) {
+		t.Skip("rows are only written in the __DOLT__ format")
+	}
+
+	// feature gains rows of one table and loses rows of the other
+	commitTableRows(t, dEnv, env.DefaultInitBranch, "people", 1, 2)
+	commitTableRows(t, dEnv, env.DefaultInitBranch, "pets", 1, 2, 3)
+	commitTableRows(t, dEnv, "feature", "people", 1, 2, 3, 4, 5)
+	commitTableRows(t, dEnv, "feature", "pets", 1)
+	deltas, err = RowCountDiffBranches(ctx, ddb, main, feature)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"people": 3, "pets": -2}, deltas)
+	deltas, err = RowCountDiffBranches(ctx, ddb, feature, main)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"people": -3, "pets": 2}, deltas)
+}
+
+// commitTableRows commits |table| on |branch|, replacing any table of that name, with a row for each of |ids|
+func commitTableRows(t *testing.T, dEnv *env.DoltEnv, branch, table string, ids ...int64) {
+	ctx := context.Background()
+	ddb := dEnv.DoltDB
+	vrw, ns := ddb.ValueReadWriter(), ddb.NodeStore()
+
+	sch := schema.MustSchemaFromCols(schema.NewColCollection(
+		schema.NewColumn("id", 0, types.IntKind, true, schema.NotNullConstraint{}),
+		schema.NewColumn("v", 1, types.IntKind, false),
+	))
+	idx, err := durable.NewEmptyIndex(ctx, vrw, ns, sch)
+	require.NoError(t, err)
+	mut := durable.ProllyMapFromIndex(idx).Mutate()
+	kb, vb := val.NewTupleBuilder(sch.GetKeyDescriptor()), val.NewTupleBuilder(sch.GetValueDescriptor())
+	for _, id := range ids {
+		kb.PutInt64(0, id)
+		vb.PutInt64(0, id)
+		require.NoError(t, mut.Put(ctx, kb.Build(ns.Pool()), vb.Build(ns.Pool())))
+	}
+	rows, err := mut.Map(ctx)
+	require.NoError(t, err)
+	tbl, err := doltdb.NewTable(ctx, vrw, ns, sch, durable.IndexFromProllyMap(rows), nil, nil)
+	require.NoError(t, err)
+
+	head, err := ddb.ResolveCommitRef(ctx, ref.NewBranchRef(branch))
+	require.NoError(t, err)
+	root, err := head.GetRootValue(ctx)
+	require.NoError(t, err)
+	root, err = root.PutTable(ctx, table, tbl)
+	require.NoError(t, err)
+	_, rootHash, err := ddb.WriteRootValue(ctx, root)
+	require.NoError(t, err)
+
+	meta, err := datas.NewCommitMeta("billy bob", "bigbillieb@fake.horse", "write "+table)
+	require.NoError(t, err)
+	_, err = ddb.Commit(ctx, rootHash, ref.NewBranchRef(branch), meta)
+	require.NoError(t, err)
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+// TableSchemaChange describes how the definition of a single table differs between two branches
+type TableSchemaChange struct {
+	// FromName is the name of the table on the first branch, or empty if it was added
+	FromName string
+	// ToName is the name of the table on the second branch, or empty if it was removed
+	ToName   string
+	DiffType diff.TableDiffType
+	// Columns are the columns that were added, removed or modified, in schema order. Modified tables may have no
+	// column changes if only their indexes, checks or foreign keys changed.
+	Columns []diff.ColumnDifference
+}
+
+// SchemaDiffBranches returns the tables whose schemas differ between the heads of branches |a| and |b|, ordered by
+// name. Only table definitions are compared, not row data, so this is cheap even for very large tables.
+func SchemaDiffBranches(ctx context.Context, ddb *doltdb.DoltDB, a, b ref.DoltRef) ([]TableSchemaChange, error) {
+	fromRoot, err := refHeadRoot(ctx, ddb, a)
+	if err != nil {
+		return nil, err
+	}
+	toRoot, err := refHeadRoot(ctx, ddb, b)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas, err := diff.GetTableDeltas(ctx, fromRoot, toRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []TableSchemaChange
+	for _, td := range deltas {
+		schemaChanged, err := td.HasSchemaChanged(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !schemaChanged && !td.IsRename() {
+			continue
+		}
+
+		change := TableSchemaChange{FromName: td.FromName, ToName: td.ToName}
+		switch {
+		case td.IsAdd():
+			change.DiffType = diff.AddedTable
+		case td.IsDrop():
+			change.DiffType = diff.RemovedTable
+		case td.IsRename():
+			change.DiffType = diff.RenamedTable
+		default:
+			change.DiffType = diff.ModifiedTable
+		}
+
+		fromSch, toSch, err := td.GetSchemas(ctx)
+		if err != nil {
+			return nil, err
+		}
+		colDiffs, unionTags := diff.DiffSchColumns(fromSch, toSch)
+		for _, tag := range unionTags {
+			if colDiffs[tag].DiffType != diff.SchDiffNone {
+				change.Columns = append(change.Columns, colDiffs[tag])
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return schemaChangeName(changes[i]) < schemaChangeName(changes[j])
+	})
+	return changes, nil
+}
+
+func schemaChangeName(change TableSchemaChange) string {
+	if change.ToName != "" {
+		return change.ToName
+	}
+	return change.FromName
+}
+
+// refHeadRoot returns the root value of the commit that |r| points to
+func refHeadRoot(ctx context.Context, ddb *doltdb.DoltDB, r ref.DoltRef) (*doltdb.RootValue, error) {
+	cm, err := ddb.ResolveCommitRef(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return cm.GetRootValue(ctx)
+}
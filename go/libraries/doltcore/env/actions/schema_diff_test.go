@@ -0,0 +1,95 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/store/datas"
+)
+
+// commitEmptyTable commits an empty table with the name and schema given on top of the branch named
+func commitEmptyTable(t *testing.T, dEnv *env.DoltEnv, branch, table string, sch schema.Schema) {
+	ctx := context.Background()
+	ddb := dEnv.DoltDB
+
+	head, err := ddb.ResolveCommitRef(ctx, ref.NewBranchRef(branch))
+	require.NoError(t, err)
+	root, err := head.GetRootValue(ctx)
+	require.NoError(t, err)
+	root, err = root.CreateEmptyTable(ctx, table, sch)
+	require.NoError(t, err)
+	_, rootHash, err := ddb.WriteRootValue(ctx, root)
+	require.NoError(t, err)
+
+	meta, err := datas.NewCommitMeta("billy bob", "bigbillieb@fake.horse", "create "+table)
+	require.NoError(t, err)
+	_, err = ddb.Commit(ctx, rootHash, ref.NewBranchRef(branch), meta)
+	require.NoError(t, err)
+}
+
+func TestSchemaDiffBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	main := ref.NewBranchRef(env.DefaultInitBranch)
+	feature := ref.NewBranchRef("feature")
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "feature", env.DefaultInitBranch, false, nil, nil))
+
+	changes, err := SchemaDiffBranches(ctx, ddb, main, feature)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	sch, err := dtestutils.Schema()
+	require.NoError(t, err)
+	cols := sch.GetAllCols()
+	narrowSch, err := schema.SchemaFromCols(schema.NewColCollection(cols.GetByIndex(0), cols.GetByIndex(1)))
+	require.NoError(t, err)
+
+	commitEmptyTable(t, dEnv, env.DefaultInitBranch, "people", narrowSch)
+	commitEmptyTable(t, dEnv, "feature", "people", sch)
+	commitEmptyTable(t, dEnv, "feature", "pets", sch)
+
+	changes, err = SchemaDiffBranches(ctx, ddb, main, feature)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	assert.Equal(t, "people", changes[0].ToName)
+	assert.Equal(t, diff.ModifiedTable, changes[0].DiffType)
+	require.Len(t, changes[0].Columns, cols.Size()-2)
+	for _, colDiff := range changes[0].Columns {
+		assert.Equal(t, diff.SchDiffAdded, colDiff.DiffType)
+	}
+
+	assert.Equal(t, "pets", changes[1].ToName)
+	assert.Equal(t, diff.AddedTable, changes[1].DiffType)
+
+	changes, err = SchemaDiffBranches(ctx, ddb, feature, main)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "pets", changes[1].FromName)
+	assert.Equal(t, diff.RemovedTable, changes[1].DiffType)
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/merge"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// UpstreamInfo describes the upstream configured for a local branch.
+type UpstreamInfo struct {
+	// Remote is the name of the upstream's remote
+	Remote string
+	// Branch is the name of the upstream branch on the remote
+	Branch string
+	// Tracking is the local remote tracking ref of the upstream branch, which is updated on fetch
+	Tracking ref.RemoteRef
+	// RemoteExists is whether the upstream's remote is still configured. If it isn't, the upstream config is dangling,
+	// and can't be used to fetch, pull or push.
+	RemoteExists bool
+	// TrackingExists is whether the remote tracking ref exists, i.e. whether the upstream branch has been fetched
+	TrackingExists bool
+}
+
+// BranchUpstreams returns the upstream configured for every local branch that has one, keyed by branch name.
+func BranchUpstreams(ctx context.Context, dbData env.DbData) (map[string]UpstreamInfo, error) {
+	trackedBranches, err := dbData.Rsr.GetBranches()
+	if err != nil {
+		return nil, err
+	}
+	remotes, err := dbData.Rsr.GetRemotes()
+	if err != nil {
+		return nil, err
+	}
+
+	upstreams := make(map[string]UpstreamInfo, len(trackedBranches))
+	for name, config := range trackedBranches {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		info := UpstreamInfo{
+			Remote:   config.Remote,
+			Branch:   config.Merge.Ref.GetPath(),
+			Tracking: ref.NewRemoteRef(config.Remote, config.Merge.Ref.GetPath()),
+		}
+		_, info.RemoteExists = remotes[config.Remote]
+		info.TrackingExists, err = dbData.Ddb.HasRef(ctx, info.Tracking)
+		if err != nil {
+			return nil, err
+		}
+		upstreams[name] = info
+	}
+
+	return upstreams, nil
+}
+
+// BranchStaleness describes how a local branch has diverged from its upstream's remote tracking branch.
+type BranchStaleness struct {
+	// Branch is the name of the local branch
+	Branch string
+	// Upstream is the remote tracking ref of the branch's upstream
+	Upstream ref.RemoteRef
+	// Ahead is the number of commits on the local branch that aren't on its upstream
+	Ahead uint64
+	// Behind is the number of commits on the upstream that aren't on the local branch
+	Behind uint64
+	// MergeBase is the hash of the merge base of the local branch and its upstream. When the branch is up to date it's
+	// the hash of their shared head.
+	MergeBase hash.Hash
+}
+
+// UpToDate returns whether the branch and its upstream have the same head.
+func (s BranchStaleness) UpToDate() bool {
+	return s.Ahead == 0 && s.Behind == 0
+}
+
+// StaleBranches returns the staleness of every local branch that has an upstream configured, sorted by branch name.
+// Branches are compared against their remote tracking branches as of the last fetch, so no remote is contacted.
+// Branches whose remote tracking branch doesn't exist are omitted.
+func StaleBranches(ctx context.Context, dbData env.DbData) ([]BranchStaleness, error) {
+	upstreams, err := BranchUpstreams(ctx, dbData)
+	if err != nil {
+		return nil, err
+	}
+
+	var stalenesses []BranchStaleness
+	for name, upstream := range upstreams {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !upstream.TrackingExists {
+			continue
+		}
+
+		staleness, ok, err := branchStaleness(ctx, dbData.Ddb, ref.NewBranchRef(name), upstream.Tracking)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			stalenesses = append(stalenesses, staleness)
+		}
+	}
+
+	sort.Slice(stalenesses, func(i, j int) bool {
+		return stalenesses[i].Branch < stalenesses[j].Branch
+	})
+	return stalenesses, nil
+}
+
+// branchStaleness compares |branch| with |upstream|. Returns false if either ref doesn't exist.
+func branchStaleness(ctx context.Context, ddb *doltdb.DoltDB, branch ref.BranchRef, upstream ref.RemoteRef) (BranchStaleness, bool, error) {
+	local, ok, err := resolveRefIfExists(ctx, ddb, branch)
+	if err != nil || !ok {
+		return BranchStaleness{}, false, err
+	}
+	remote, ok, err := resolveRefIfExists(ctx, ddb, upstream)
+	if err != nil || !ok {
+		return BranchStaleness{}, false, err
+	}
+
+	localHash, err := local.HashOf()
+	if err != nil {
+		return BranchStaleness{}, false, err
+	}
+	remoteHash, err := remote.HashOf()
+	if err != nil {
+		return BranchStaleness{}, false, err
+	}
+
+	staleness := BranchStaleness{
+		Branch:    branch.GetPath(),
+		Upstream:  upstream,
+		MergeBase: localHash,
+	}
+	if localHash == remoteHash {
+		return staleness, true, nil
+	}
+
+	staleness.MergeBase, err = merge.MergeBase(ctx, local, remote)
+	if err != nil {
+		return BranchStaleness{}, false, err
+	}
+	staleness.Ahead, err = countCommits(ctx, ddb, localHash, remoteHash)
+	if err != nil {
+		return BranchStaleness{}, false, err
+	}
+	staleness.Behind, err = countCommits(ctx, ddb, remoteHash, localHash)
+	if err != nil {
+		return BranchStaleness{}, false, err
+	}
+
+	return staleness, true, nil
+}
+
+func resolveRefIfExists(ctx context.Context, ddb *doltdb.DoltDB, r ref.DoltRef) (*doltdb.Commit, bool, error) {
+	ok, err := ddb.HasRef(ctx, r)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	cm, err := ddb.ResolveCommitRef(ctx, r)
+	if err != nil {
+		return nil, false, err
+	}
+	return cm, true, nil
+}
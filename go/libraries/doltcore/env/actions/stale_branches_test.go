@@ -0,0 +1,110 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+func TestStaleBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	require.NoError(t, dEnv.RepoStateWriter().AddRemote(env.NewRemote("origin", "file:///doesnotexist", nil)))
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "feature", env.DefaultInitBranch, false, nil, nil))
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "upstream", env.DefaultInitBranch, false, nil, nil))
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "unfetched", env.DefaultInitBranch, false, nil, nil))
+	for _, branch := range []string{env.DefaultInitBranch, "feature", "unfetched"} {
+		require.NoError(t, SetBranchUpstream(ctx, dEnv.DbData(), branch, "origin", branch))
+	}
+
+	base := createTestCommits(t, dEnv, env.DefaultInitBranch, 0)[0]
+	featureCommits := createTestCommits(t, dEnv, "feature", 2)
+	upstreamCommits := createTestCommits(t, dEnv, "upstream", 1)
+	baseHash, err := base.HashOf()
+	require.NoError(t, err)
+	upstreamHash, err := upstreamCommits[1].HashOf()
+	require.NoError(t, err)
+	require.NoError(t, ddb.SetHead(ctx, ref.NewRemoteRef("origin", env.DefaultInitBranch), baseHash))
+	require.NoError(t, ddb.SetHead(ctx, ref.NewRemoteRef("origin", "feature"), upstreamHash))
+
+	stalenesses, err := StaleBranches(ctx, dEnv.DbData())
+	require.NoError(t, err)
+	require.Len(t, stalenesses, 2)
+
+	feature := stalenesses[0]
+	assert.Equal(t, "feature", feature.Branch)
+	assert.Equal(t, ref.NewRemoteRef("origin", "feature"), feature.Upstream)
+	assert.Equal(t, uint64(2), feature.Ahead)
+	assert.Equal(t, uint64(1), feature.Behind)
+	assert.Equal(t, mustHashOf(t, featureCommits[0]), feature.MergeBase.String())
+	assert.False(t, feature.UpToDate())
+
+	upToDate := stalenesses[1]
+	assert.Equal(t, env.DefaultInitBranch, upToDate.Branch)
+	assert.True(t, upToDate.UpToDate())
+	assert.Equal(t, baseHash, upToDate.MergeBase)
+}
+
+func TestBranchUpstreams(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	require.NoError(t, dEnv.RepoStateWriter().AddRemote(env.NewRemote("origin", "file:///doesnotexist", nil)))
+	require.NoError(t, CreateBranchOnDB(ctx, ddb, "feature", env.DefaultInitBranch, false, nil, nil))
+	require.NoError(t, SetBranchUpstream(ctx, dEnv.DbData(), env.DefaultInitBranch, "origin", "trunk"))
+	require.NoError(t, SetBranchUpstream(ctx, dEnv.DbData(), "feature", "origin", "feature"))
+	head := createTestCommits(t, dEnv, env.DefaultInitBranch, 0)[0]
+	headHash, err := head.HashOf()
+	require.NoError(t, err)
+	require.NoError(t, ddb.SetHead(ctx, ref.NewRemoteRef("origin", "trunk"), headHash))
+
+	upstreams, err := BranchUpstreams(ctx, dEnv.DbData())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]UpstreamInfo{
+		env.DefaultInitBranch: {
+			Remote:         "origin",
+			Branch:         "trunk",
+			Tracking:       ref.NewRemoteRef("origin", "trunk"),
+			RemoteExists:   true,
+			TrackingExists: true,
+		},
+		"feature": {
+			Remote:       "origin",
+			Branch:       "feature",
+			Tracking:     ref.NewRemoteRef("origin", "feature"),
+			RemoteExists: true,
+		},
+	}, upstreams)
+
+	// removing the remote leaves the upstream config dangling
+	require.NoError(t, dEnv.RepoStateWriter().RemoveRemote(ctx, "origin"))
+	upstreams, err = BranchUpstreams(ctx, dEnv.DbData())
+	require.NoError(t, err)
+	require.Contains(t, upstreams, "feature")
+	assert.False(t, upstreams["feature"].RemoteExists)
+}
@@ -140,3 +140,32 @@ func IterResolvedTags(ctx context.Context, ddb *doltdb.DoltDB, cb func(tag *dolt
 
 	return nil
 }
+
+// TagsAtBranchHead returns the tags that point at the head commit of |branch|, sorted by name, e.g. to warn before
+// deleting a branch whose tip is a tagged release.
+func TagsAtBranchHead(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef) ([]ref.TagRef, error) {
+	head, err := ddb.ResolveCommitRef(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+	headHash, err := head.HashOf()
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := ddb.GetTagsWithHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var atHead []ref.TagRef
+	for _, t := range tags {
+		if t.Hash == headHash {
+			atHead = append(atHead, ref.NewTagRef(t.Tag.Name))
+		}
+	}
+	sort.Slice(atHead, func(i, j int) bool {
+		return atHead[i].GetPath() < atHead[j].GetPath()
+	})
+	return atHead, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+func TestTagsAtBranchHead(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	createTestCommits(t, dEnv, env.DefaultInitBranch, 1)
+	headRef, err := dEnv.RepoStateReader().CWBHeadRef()
+	require.NoError(t, err)
+	props := TagProps{TaggerName: "billy bob", TaggerEmail: "bigbillieb@fake.horse"}
+	require.NoError(t, CreateTagOnDB(ctx, ddb, "v2", "main", props, headRef))
+	require.NoError(t, CreateTagOnDB(ctx, ddb, "release", "main", props, headRef))
+	require.NoError(t, CreateTagOnDB(ctx, ddb, "v1", "main~1", props, headRef))
+
+	tags, err := TagsAtBranchHead(ctx, ddb, ref.NewBranchRef(env.DefaultInitBranch))
+	require.NoError(t, err)
+	assert.Equal(t, []ref.TagRef{ref.NewTagRef("release"), ref.NewTagRef("v2")}, tags)
+
+	require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), "untagged", "main", false, nil))
+	createTestCommits(t, dEnv, "untagged", 1)
+	tags, err = TagsAtBranchHead(ctx, ddb, ref.NewBranchRef("untagged"))
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+}
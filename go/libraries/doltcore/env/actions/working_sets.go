@@ -0,0 +1,197 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// FindOrphanedWorkingSets returns the working sets in |ddb| whose head no longer exists, e.g. because their branch was
+// deleted with DeleteOptions.KeepWorkingSet, or a crash interrupted a branch deletion. Orphaned working sets are
+// unreachable from any branch, but their storage is never reclaimed until they are deleted.
+func FindOrphanedWorkingSets(ctx context.Context, ddb *doltdb.DoltDB) ([]ref.WorkingSetRef, error) {
+	wsRefs, err := ddb.GetWorkingSetRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	heads, err := ddb.GetHeadRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	headNames := make(map[string]struct{}, len(heads))
+	for _, head := range heads {
+		headNames[head.String()] = struct{}{}
+	}
+
+	var orphaned []ref.WorkingSetRef
+	for _, wsRef := range wsRefs {
+		headRef, err := wsRef.ToHeadRef()
+		if err != nil {
+			// not a working set for a head we know how to check
+			continue
+		}
+		if _, ok := headNames[headRef.String()]; !ok {
+			orphaned = append(orphaned, wsRef)
+		}
+	}
+
+	return orphaned, nil
+}
+
+// DeleteOrphanedWorkingSets deletes the working sets returned by FindOrphanedWorkingSets and returns their refs. Any
+// uncommitted changes in them are lost.
+func DeleteOrphanedWorkingSets(ctx context.Context, ddb *doltdb.DoltDB) ([]ref.WorkingSetRef, error) {
+	orphaned, err := FindOrphanedWorkingSets(ctx, ddb)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, wsRef := range orphaned {
+		err = ddb.DeleteWorkingSet(ctx, wsRef)
+		if err != nil {
+			return orphaned[:i], err
+		}
+	}
+
+	return orphaned, nil
+}
+
+// MoveWorkingSet moves the uncommitted changes on |from| to |to|, e.g. to recover from making changes on the wrong
+// branch, and leaves |from| clean at its head. The working and staged roots are copied as they are, so if the branches
+// have different heads, the differences between the heads also show up as changes on |to|. Any merge in progress on
+// |from| moves along with its changes. If |to| has uncommitted changes of its own, the move fails with
+// ErrWorkingSetsOnBothBranches, unless |force| is true, in which case they're lost.
+func MoveWorkingSet(ctx context.Context, dbData env.DbData, from, to ref.DoltRef, force bool) error {
+	if ref.Equals(from, to) {
+		return nil
+	}
+
+	ddb := dbData.Ddb
+	fromHead, err := ddb.ResolveCommitRef(ctx, from)
+	if err != nil {
+		return err
+	}
+	toHead, err := ddb.ResolveCommitRef(ctx, to)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		dirty, err := branchHasUncommittedChanges(ctx, ddb, to, toHead)
+		if err != nil {
+			return err
+		} else if dirty {
+			return ErrWorkingSetsOnBothBranches
+		}
+	}
+
+	fromWSRef, err := ref.WorkingSetRefForHead(from)
+	if err != nil {
+		return err
+	}
+	toWSRef, err := ref.WorkingSetRefForHead(to)
+	if err != nil {
+		return err
+	}
+
+	fromWS, err := ddb.ResolveWorkingSet(ctx, fromWSRef)
+	if errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+		// no changes to move
+		return nil
+	} else if err != nil {
+		return err
+	}
+	fromWSHash, err := fromWS.HashOf()
+	if err != nil {
+		return err
+	}
+
+	err = ddb.CopyWorkingSet(ctx, fromWSRef, toWSRef, true)
+	if err != nil {
+		return err
+	}
+
+	headRoot, err := fromHead.GetRootValue(ctx)
+	if err != nil {
+		return err
+	}
+	cleanWS := fromWS.ClearMerge().WithWorkingRoot(headRoot).WithStagedRoot(headRoot)
+	return ddb.UpdateWorkingSet(ctx, fromWSRef, cleanWS, fromWSHash, doltdb.TodoWorkingSetMeta(), nil)
+}
+
+// WorkingSetRoot returns the hash of the working root of |branch|'s working set, and whether it has one. A branch has
+// no working set if it was never given one, or if it's a kind of ref that can't have one.
+func WorkingSetRoot(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef) (hash.Hash, bool, error) {
+	wsRef, err := ref.WorkingSetRefForHead(branch)
+	if errors.Is(err, ref.ErrWorkingSetUnsupported) {
+		return hash.Hash{}, false, nil
+	} else if err != nil {
+		return hash.Hash{}, false, err
+	}
+
+	ws, err := ddb.ResolveWorkingSet(ctx, wsRef)
+	if errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+		return hash.Hash{}, false, nil
+	} else if err != nil {
+		return hash.Hash{}, false, err
+	}
+
+	h, err := ws.WorkingRoot().HashOf()
+	if err != nil {
+		return hash.Hash{}, false, err
+	}
+	return h, true, nil
+}
+
+// DirtyBranches returns the branches in |ddb|, in name order, whose working set has uncommitted changes, i.e. a working
+// or staged root that differs from the root of the branch's head. Use it to check for work that would be lost before
+// deleting or rewriting branches in bulk. Branches without a working set are clean.
+func DirtyBranches(ctx context.Context, ddb *doltdb.DoltDB) ([]ref.DoltRef, error) {
+	branches, err := ddb.GetBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].GetPath() < branches[j].GetPath()
+	})
+
+	var dirty []ref.DoltRef
+	for _, branch := range branches {
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		if err != nil {
+			return nil, err
+		}
+
+		hasChanges, err := branchHasUncommittedChanges(ctx, ddb, branch, head)
+		if errors.Is(err, ref.ErrWorkingSetUnsupported) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if hasChanges {
+			dirty = append(dirty, branch)
+		}
+	}
+
+	return dirty, nil
+}
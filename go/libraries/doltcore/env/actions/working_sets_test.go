@@ -0,0 +1,147 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+func TestOrphanedWorkingSets(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	for _, name := range []string{"kept", "deleted", "live"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+	}
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "kept", DeleteOptions{Force: true, KeepWorkingSet: true}, nil, nil))
+	require.NoError(t, DeleteBranch(ctx, dEnv.DbData(), "deleted", DeleteOptions{Force: true}, nil, nil))
+
+	keptWsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("kept"))
+	require.NoError(t, err)
+
+	orphaned, err := FindOrphanedWorkingSets(ctx, ddb)
+	require.NoError(t, err)
+	assert.Equal(t, []ref.WorkingSetRef{keptWsRef}, orphaned)
+
+	deleted, err := DeleteOrphanedWorkingSets(ctx, ddb)
+	require.NoError(t, err)
+	assert.Equal(t, []ref.WorkingSetRef{keptWsRef}, deleted)
+
+	orphaned, err = FindOrphanedWorkingSets(ctx, ddb)
+	require.NoError(t, err)
+	assert.Empty(t, orphaned)
+
+	liveWsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("live"))
+	require.NoError(t, err)
+	_, err = ddb.ResolveWorkingSet(ctx, liveWsRef)
+	assert.NoError(t, err)
+}
+
+func TestMoveWorkingSet(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	ddb := dEnv.DoltDB
+
+	wrong, right := ref.NewBranchRef("wrong"), ref.NewBranchRef("right")
+	for _, branch := range []ref.DoltRef{wrong, right} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), branch.GetPath(), "main", false, nil))
+	}
+	isDirty := func(branch ref.DoltRef) bool {
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		require.NoError(t, err)
+		dirty, err := branchHasUncommittedChanges(ctx, ddb, branch, head)
+		require.NoError(t, err)
+		return dirty
+	}
+
+	makeBranchDirty(t, dEnv, "wrong")
+	require.NoError(t, MoveWorkingSet(ctx, dEnv.DbData(), wrong, right, false))
+	assert.False(t, isDirty(wrong))
+	assert.True(t, isDirty(right))
+
+	makeBranchDirty(t, dEnv, "wrong")
+	err := MoveWorkingSet(ctx, dEnv.DbData(), wrong, right, false)
+	assert.ErrorIs(t, err, ErrWorkingSetsOnBothBranches)
+	assert.True(t, isDirty(wrong))
+
+	require.NoError(t, MoveWorkingSet(ctx, dEnv.DbData(), wrong, right, true))
+	assert.False(t, isDirty(wrong))
+	assert.True(t, isDirty(right))
+}
+
+func TestWorkingSetRoot(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	main := ref.NewBranchRef("main")
+	head, err := dEnv.DoltDB.ResolveCommitRef(ctx, main)
+	require.NoError(t, err)
+	headRoot, err := head.GetRootValue(ctx)
+	require.NoError(t, err)
+	headRootHash, err := headRoot.HashOf()
+	require.NoError(t, err)
+
+	h, ok, err := WorkingSetRoot(ctx, dEnv.DoltDB, main)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, headRootHash, h)
+
+	makeBranchDirty(t, dEnv, "main")
+	h, ok, err = WorkingSetRoot(ctx, dEnv.DoltDB, main)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.NotEqual(t, headRootHash, h)
+
+	_, ok, err = WorkingSetRoot(ctx, dEnv.DoltDB, ref.NewTagRef("v1"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = WorkingSetRoot(ctx, dEnv.DoltDB, ref.NewBranchRef("missing"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDirtyBranches(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	for _, name := range []string{"clean", "dirty2", "dirty1"} {
+		require.NoError(t, CreateBranchWithStartPt(ctx, dEnv.DbData(), name, "main", false, nil))
+	}
+
+	dirty, err := DirtyBranches(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	assert.Empty(t, dirty)
+
+	makeBranchDirty(t, dEnv, "dirty2")
+	makeBranchDirty(t, dEnv, "dirty1")
+	dirty, err = DirtyBranches(ctx, dEnv.DoltDB)
+	require.NoError(t, err)
+	require.Len(t, dirty, 2)
+	assert.Equal(t, "dirty1", dirty[0].GetPath())
+	assert.Equal(t, "dirty2", dirty[1].GetPath())
+}
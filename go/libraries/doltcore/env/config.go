@@ -52,6 +52,10 @@ const (
 	MetricsInsecure = "metrics.insecure"
 
 	PushAutoSetupRemote = "push.autosetupremote"
+
+	// BranchExpireAfterKey is the age after which fully merged branches are deleted by `dolt branch --prune-stale`.
+	// It can be overridden for a single branch with branch.<name>.expireafter.
+	BranchExpireAfterKey = "branch.expireafter"
 )
 
 var LocalConfigWhitelist = set.NewStrSet([]string{UserNameKey, UserEmailKey})
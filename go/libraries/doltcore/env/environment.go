@@ -973,6 +973,50 @@ func (dEnv *DoltEnv) UpdateBranch(name string, new BranchConfig) error {
 	return nil
 }
 
+// RemoveBranchConfig implements BranchConfigRemover
+func (dEnv *DoltEnv) RemoveBranchConfig(name string) error {
+	if dEnv.RSLoadErr != nil {
+		return dEnv.RSLoadErr
+	}
+
+	if _, ok := dEnv.RepoState.Branches[name]; !ok {
+		return nil
+	}
+	delete(dEnv.RepoState.Branches, name)
+
+	err := dEnv.RepoState.Save(dEnv.FS)
+	if err != nil {
+		return ErrFailedToWriteRepoState
+	}
+	return nil
+}
+
+// GetBranchDescriptions implements BranchDescriber
+func (dEnv *DoltEnv) GetBranchDescriptions() (map[string]string, error) {
+	if dEnv.RSLoadErr != nil {
+		return nil, dEnv.RSLoadErr
+	}
+
+	return dEnv.RepoState.BranchDescriptions, nil
+}
+
+// SetBranchDescription implements BranchDescriber
+func (dEnv *DoltEnv) SetBranchDescription(name, description string) error {
+	if dEnv.RSLoadErr != nil {
+		return dEnv.RSLoadErr
+	}
+
+	if !dEnv.RepoState.SetBranchDescription(name, description) {
+		return nil
+	}
+
+	err := dEnv.RepoState.Save(dEnv.FS)
+	if err != nil {
+		return ErrFailedToWriteRepoState
+	}
+	return nil
+}
+
 var ErrNotACred = errors.New("not a valid credential key id or public key")
 
 func (dEnv *DoltEnv) FindCreds(credsDir, pubKeyOrId string) (string, error) {
@@ -531,6 +531,12 @@ func getAbsFileRemoteUrl(u *url.URL, fs filesys2.Filesys) (string, error) {
 // the configs default config branch first, then init branch main, then the old init branch master,
 // and finally the first lexicographical branch if none of the others are found
 func GetDefaultBranch(dEnv *DoltEnv, branches []ref.DoltRef) string {
+	return DefaultBranchOf(branches, GetDefaultInitBranch(dEnv.Config))
+}
+
+// DefaultBranchOf returns the default branch from among the branches given, as GetDefaultBranch does, with
+// |initBranch| as the configured init branch
+func DefaultBranchOf(branches []ref.DoltRef, initBranch string) string {
 	if len(branches) == 0 {
 		return DefaultInitBranch
 	}
@@ -552,9 +558,8 @@ func GetDefaultBranch(dEnv *DoltEnv, branches []ref.DoltRef) string {
 	}
 
 	// todo: do we care about this during clone?
-	defaultOrMain := GetDefaultInitBranch(dEnv.Config)
-	if _, ok := branchMap[defaultOrMain]; ok {
-		return defaultOrMain
+	if _, ok := branchMap[initBranch]; ok {
+		return initBranch
 	}
 
 	return branches[0].GetPath()
@@ -45,6 +45,28 @@ type RepoStateWriter interface {
 	UpdateBranch(name string, new BranchConfig) error
 }
 
+// DefaultBranchReader is implemented by RepoStateReaders which have a configurable default branch, such as the one set
+// for a database served by sql-server with its @@<db>_default_branch system variable.
+type DefaultBranchReader interface {
+	// GetDefaultBranch returns the name of the configured default branch, or the empty string if none is configured
+	GetDefaultBranch() (string, error)
+}
+
+// BranchConfigRemover is implemented by RepoStateWriters which can remove the upstream config of a branch.
+type BranchConfigRemover interface {
+	// RemoveBranchConfig removes the upstream config of the branch named, if it has one
+	RemoveBranchConfig(name string) error
+}
+
+// BranchDescriber is implemented by repo states which can store a free-form description of each branch, as set with
+// `dolt branch --edit-description`.
+type BranchDescriber interface {
+	// GetBranchDescriptions returns the description of each branch that has one, keyed by branch name
+	GetBranchDescriptions() (map[string]string, error)
+	// SetBranchDescription sets the description of the branch named, removing it if |description| is empty
+	SetBranchDescription(name, description string) error
+}
+
 type RepoStateReadWriter interface {
 	RepoStateReader
 	RepoStateWriter
@@ -71,6 +93,9 @@ type RepoState struct {
 	Remotes  map[string]Remote       `json:"remotes"`
 	Backups  map[string]Remote       `json:"backups"`
 	Branches map[string]BranchConfig `json:"branches"`
+	// BranchDescriptions holds the description of each branch that has one, keyed by branch name. Descriptions are kept
+	// apart from Branches, whose entries are upstream tracking configs.
+	BranchDescriptions map[string]string `json:"branch_descriptions,omitempty"`
 	// |staged|, |working|, and |merge| are legacy fields left over from when Dolt repos stored this info in the repo
 	// state file, not in the DB directly. They're still here so that we can migrate existing repositories forward to the
 	// new storage format, but they should be used only for this purpose and are no longer written.
@@ -82,25 +107,27 @@ type RepoState struct {
 // repoStateLegacy only exists to unmarshall legacy repo state files, since the JSON marshaller can't work with
 // unexported fields
 type repoStateLegacy struct {
-	Head     ref.MarshalableRef      `json:"head"`
-	Remotes  map[string]Remote       `json:"remotes"`
-	Backups  map[string]Remote       `json:"backups"`
-	Branches map[string]BranchConfig `json:"branches"`
-	Staged   string                  `json:"staged,omitempty"`
-	Working  string                  `json:"working,omitempty"`
-	Merge    *mergeState             `json:"merge,omitempty"`
+	Head               ref.MarshalableRef      `json:"head"`
+	Remotes            map[string]Remote       `json:"remotes"`
+	Backups            map[string]Remote       `json:"backups"`
+	Branches           map[string]BranchConfig `json:"branches"`
+	BranchDescriptions map[string]string       `json:"branch_descriptions,omitempty"`
+	Staged             string                  `json:"staged,omitempty"`
+	Working            string                  `json:"working,omitempty"`
+	Merge              *mergeState             `json:"merge,omitempty"`
 }
 
 // repoStateLegacyFromRepoState creates a new repoStateLegacy from a RepoState file. Only for testing.
 func repoStateLegacyFromRepoState(rs *RepoState) *repoStateLegacy {
 	return &repoStateLegacy{
-		Head:     rs.Head,
-		Remotes:  rs.Remotes,
-		Backups:  rs.Backups,
-		Branches: rs.Branches,
-		Staged:   rs.staged,
-		Working:  rs.working,
-		Merge:    rs.merge,
+		Head:               rs.Head,
+		Remotes:            rs.Remotes,
+		Backups:            rs.Backups,
+		Branches:           rs.Branches,
+		BranchDescriptions: rs.BranchDescriptions,
+		Staged:             rs.staged,
+		Working:            rs.working,
+		Merge:              rs.merge,
 	}
 }
 
@@ -111,13 +138,14 @@ type mergeState struct {
 
 func (rs *repoStateLegacy) toRepoState() *RepoState {
 	return &RepoState{
-		Head:     rs.Head,
-		Remotes:  rs.Remotes,
-		Backups:  rs.Backups,
-		Branches: rs.Branches,
-		staged:   rs.Staged,
-		working:  rs.Working,
-		merge:    rs.Merge,
+		Head:               rs.Head,
+		Remotes:            rs.Remotes,
+		Backups:            rs.Backups,
+		Branches:           rs.Branches,
+		BranchDescriptions: rs.BranchDescriptions,
+		staged:             rs.Staged,
+		working:            rs.Working,
+		merge:              rs.Merge,
 	}
 }
 
@@ -226,3 +254,20 @@ func (rs *RepoState) AddBackup(r Remote) {
 func (rs *RepoState) RemoveBackup(r Remote) {
 	delete(rs.Backups, r.Name)
 }
+
+// SetBranchDescription sets the description of the branch named, removing it if |description| is empty, and returns
+// whether the repo state changed
+func (rs *RepoState) SetBranchDescription(name, description string) bool {
+	if rs.BranchDescriptions[name] == description {
+		return false
+	}
+	if description == "" {
+		delete(rs.BranchDescriptions, name)
+		return true
+	}
+	if rs.BranchDescriptions == nil {
+		rs.BranchDescriptions = make(map[string]string)
+	}
+	rs.BranchDescriptions[name] = description
+	return true
+}
@@ -15,6 +15,7 @@
 package ref
 
 import (
+	"errors"
 	"regexp"
 	"strings"
 
@@ -32,14 +33,47 @@ var InvalidBranchNameRegex = regexp.MustCompile(strings.Join([]string{
 	`\/\/`, `\A\/`, `\/\z`,
 }, "|"))
 
+// hashLikeBranchNameRegex matches a name that looks exactly like a commit id. It's one of the patterns of
+// InvalidBranchNameRegex.
+var hashLikeBranchNameRegex = regexp.MustCompile(`\A[0-9a-v]{32}\z`)
+
+// IsValidBranchName returns whether |s| is a valid branch name. See ValidateBranchName.
 func IsValidBranchName(s string) bool {
-	if InvalidBranchNameRegex.MatchString(s) {
-		return false
+	return ValidateBranchName(s) == nil
+}
+
+// ValidateBranchName returns an error describing why |s| isn't a valid branch name, or nil if it is. Like git refs,
+// branch names may be hierarchical, with path segments separated by "/", e.g. team/feature/x, but no path segment may
+// be empty.
+func ValidateBranchName(s string) error {
+	if err := ValidateHashLikeBranchName(s); err != nil {
+		return err
+	}
+	if hashLikeBranchNameRegex.MatchString(s) {
+		return errors.New("branch name looks like a commit hash")
+	}
+	return nil
+}
+
+// ValidateHashLikeBranchName is ValidateBranchName for callers that allow a name that looks like a commit hash. Every
+// other rule still applies.
+func ValidateHashLikeBranchName(s string) error {
+	switch {
+	case s == "":
+		return errors.New("branch name is empty")
+	case strings.HasPrefix(s, "/"):
+		return errors.New("branch name can't start with '/'")
+	case strings.HasSuffix(s, "/"):
+		return errors.New("branch name can't end with '/'")
+	case strings.Contains(s, "//"):
+		return errors.New("branch name can't have an empty path segment")
+	case !hashLikeBranchNameRegex.MatchString(s) && InvalidBranchNameRegex.MatchString(s):
+		return errors.New("branch name is reserved")
 	}
 
 	if err := datas.ValidateDatasetId(s); err != nil {
-		return false
+		return errors.New("branch name contains a forbidden character or sequence")
 	}
 
-	return true
+	return nil
 }
@@ -83,3 +83,20 @@ func TestBranchName(t *testing.T) {
 	assert.False(t, IsValidBranchName("HEAD"))
 	assert.False(t, IsValidBranchName("-"))
 }
+
+func TestValidateBranchName(t *testing.T) {
+	assert.NoError(t, ValidateBranchName("team/feature/x"))
+	assert.EqualError(t, ValidateBranchName(""), "branch name is empty")
+	assert.EqualError(t, ValidateBranchName("/team/feature"), "branch name can't start with '/'")
+	assert.EqualError(t, ValidateBranchName("team/feature/"), "branch name can't end with '/'")
+	assert.EqualError(t, ValidateBranchName("team//feature"), "branch name can't have an empty path segment")
+	assert.EqualError(t, ValidateBranchName("HEAD"), "branch name is reserved")
+	assert.EqualError(t, ValidateBranchName("team/feature.lock"), "branch name contains a forbidden character or sequence")
+
+	hashLike := "0123456789abcdefghijklmnopqrstuv"
+	assert.EqualError(t, ValidateBranchName(hashLike), "branch name looks like a commit hash")
+	assert.False(t, IsValidBranchName(hashLike))
+	assert.NoError(t, ValidateHashLikeBranchName(hashLike))
+	assert.EqualError(t, ValidateHashLikeBranchName("HEAD"), "branch name is reserved")
+	assert.EqualError(t, ValidateHashLikeBranchName(hashLike+".lock"), "branch name contains a forbidden character or sequence")
+}
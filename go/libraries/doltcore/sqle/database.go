@@ -420,7 +420,8 @@ func (db Database) getTableInsensitive(ctx *sql.Context, head *doltdb.Commit, ds
 			sess, db.RevisionQualifiedName(),
 			map[string]env.Remote{},
 			map[string]env.BranchConfig{},
-			map[string]env.Remote{})
+			map[string]env.Remote{},
+			nil)
 		ws, err := sess.WorkingSet(ctx, db.RevisionQualifiedName())
 		if err != nil {
 			return nil, false, err
@@ -596,15 +597,30 @@ func (db Database) getTable(ctx *sql.Context, root *doltdb.RootValue, tableName
 
 	cachedTable, ok := dbState.SessionCache().GetCachedTable(key, tableName)
 	if ok {
+		dsess.VerifyCachedTable(tableName, cachedTable, func() (sql.Table, bool, error) {
+			return db.newTable(ctx, root, tableName)
+		})
 		return cachedTable, true, nil
 	}
 
+	table, ok, err := db.newTable(ctx, root, tableName)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	dbState.SessionCache().CacheTable(key, table.Name(), table)
+
+	return table, true, nil
+}
+
+// newTable returns the user table with the given baseName from the root given, without consulting the session cache
+func (db Database) newTable(ctx *sql.Context, root *doltdb.RootValue, tableName string) (sql.Table, bool, error) {
 	tableNames, err := getAllTableNames(ctx, root)
 	if err != nil {
 		return nil, true, err
 	}
 
-	tableName, ok = sql.GetTableNameInsensitive(tableName, tableNames)
+	tableName, ok := sql.GetTableNameInsensitive(tableName, tableNames)
 	if !ok {
 		return nil, false, nil
 	}
@@ -636,8 +652,6 @@ func (db Database) getTable(ctx *sql.Context, root *doltdb.RootValue, tableName
 		table = &AlterableDoltTable{WritableDoltTable{DoltTable: readonlyTable, db: db}}
 	}
 
-	dbState.SessionCache().CacheTable(key, tableName, table)
-
 	return table, true, nil
 }
 
@@ -721,6 +735,24 @@ func (db Database) SetRoot(ctx *sql.Context, newRoot *doltdb.RootValue) error {
 	return sess.SetRoot(ctx, db.RevisionQualifiedName(), newRoot)
 }
 
+// setRootForSchemaChange sets the working root for this database after a schema change to the tables named. Other
+// sessions may have cached metadata for those tables at the root being replaced, so they are notified to drop it.
+func (db Database) setRootForSchemaChange(ctx *sql.Context, newRoot *doltdb.RootValue, tableNames ...string) error {
+	oldRoot, err := db.GetRoot(ctx)
+	if err != nil {
+		return err
+	}
+	oldKey, err := doltdb.NewDataCacheKey(oldRoot)
+	if err != nil {
+		return err
+	}
+	for _, tableName := range tableNames {
+		dsess.InvalidateCachedTable(oldKey, tableName)
+	}
+
+	return db.SetRoot(ctx, newRoot)
+}
+
 // GetHeadRoot returns root value for the current session head
 func (db Database) GetHeadRoot(ctx *sql.Context) (*doltdb.RootValue, error) {
 	sess := dsess.DSessFromSess(ctx.Session)
@@ -785,7 +817,7 @@ func (db Database) dropTable(ctx *sql.Context, tableName string) error {
 		}
 	}
 
-	return db.SetRoot(ctx, newRoot)
+	return db.setRootForSchemaChange(ctx, newRoot, tableName)
 }
 
 // removeTableFromAutoIncrementTracker updates the global auto increment tracking as necessary to deal with the table
@@ -1001,7 +1033,7 @@ func (db Database) createDoltTable(ctx *sql.Context, tableName string, root *dol
 		return err
 	}
 
-	return db.SetRoot(ctx, newRoot)
+	return db.setRootForSchemaChange(ctx, newRoot, tableName)
 }
 
 // CreateTemporaryTable creates a table that only exists the length of a session.
@@ -1057,7 +1089,7 @@ func (db Database) RenameTable(ctx *sql.Context, oldName, newName string) error
 		return err
 	}
 
-	return db.SetRoot(ctx, newRoot)
+	return db.setRootForSchemaChange(ctx, newRoot, oldName, newName)
 }
 
 // GetViewDefinition implements sql.ViewDatabase
@@ -1092,6 +1124,16 @@ func (db Database) GetViewDefinition(ctx *sql.Context, viewName string) (sql.Vie
 
 	if dbState.SessionCache().ViewsCached(key) {
 		view, ok := dbState.SessionCache().GetCachedViewDefinition(key, viewName)
+		if ok {
+			dsess.VerifyCachedView(viewName, view, func() (sql.ViewDefinition, bool, error) {
+				tbl, ok, err := db.GetTableInsensitive(ctx, doltdb.SchemasTableName)
+				if err != nil || !ok {
+					return sql.ViewDefinition{}, false, err
+				}
+				_, viewDef, found, err := getViewDefinitionFromSchemaFragmentsOfView(ctx, tbl.(*WritableDoltTable), viewName)
+				return viewDef, found, err
+			})
+		}
 		return view, ok, nil
 	}
 
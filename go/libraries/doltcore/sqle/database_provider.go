@@ -898,15 +898,24 @@ func initialDbState(ctx context.Context, db dsess.SqlDatabase, branch string) (d
 		return dsess.InitialDbState{}, err
 	}
 
+	// branch descriptions are optional, so a repo state that can't provide them has none
+	var descriptions map[string]string
+	if describer, ok := rsr.(env.BranchDescriber); ok {
+		if descriptions, err = describer.GetBranchDescriptions(); err != nil {
+			descriptions = nil
+		}
+	}
+
 	return dsess.InitialDbState{
-		Db:         db,
-		HeadCommit: headCommit,
-		WorkingSet: ws,
-		DbData:     db.DbData(),
-		Remotes:    remotes,
-		Branches:   branches,
-		Backups:    backups,
-		Err:        retainedErr,
+		Db:                 db,
+		HeadCommit:         headCommit,
+		WorkingSet:         ws,
+		DbData:             db.DbData(),
+		Remotes:            remotes,
+		Branches:           branches,
+		Backups:            backups,
+		BranchDescriptions: descriptions,
+		Err:                retainedErr,
 	}, nil
 }
 
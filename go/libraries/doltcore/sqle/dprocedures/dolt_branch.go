@@ -130,6 +130,7 @@ func renameBranch(ctx *sql.Context, dbData env.DbData, apr *argparser.ArgParseRe
 	if err != nil {
 		return err
 	}
+	sess.DatabaseCache(ctx).InvalidateSessionVars(dbName)
 	err = branch_control.AddAdminForContext(ctx, newBranchName)
 	if err != nil {
 		return err
@@ -175,12 +176,21 @@ func deleteBranches(ctx *sql.Context, dbData env.DbData, apr *argparser.ArgParse
 	}
 
 	dSess := dsess.DSessFromSess(ctx.Session)
+	// branches that share an upstream are validated against the same remote database
+	remoteDbs := actions.NewRemoteDbCache(dSess.Provider())
+	defer remoteDbs.Close()
+
+	force := apr.Contains(cli.DeleteForceFlag) || apr.Contains(cli.ForceFlag)
+	// branches are checked for being merged into the same head, so it's resolved at most once for all of them, when the
+	// first branch that needs it is checked
+	currentHead := actions.NewCurrentHeadCache(nil)
+	ancestry := actions.NewAncestryCache()
+
 	for _, branchName := range apr.Args {
 		if len(branchName) == 0 {
 			return EmptyBranchNameErr
 		}
 
-		force := apr.Contains(cli.DeleteForceFlag) || apr.Contains(cli.ForceFlag)
 		if !force {
 			err = validateBranchNotActiveInAnySession(ctx, branchName)
 			if err != nil {
@@ -195,11 +205,14 @@ func deleteBranches(ctx *sql.Context, dbData env.DbData, apr *argparser.ArgParse
 		}
 
 		err = actions.DeleteBranch(ctx, dbData, branchName, actions.DeleteOptions{
-			Force: force,
-		}, dSess.Provider(), rsc)
+			Force:       force,
+			Ancestry:    ancestry,
+			CurrentHead: currentHead,
+		}, remoteDbs, rsc)
 		if err != nil {
 			return err
 		}
+		dSess.DatabaseCache(ctx).InvalidateSessionVars(dbName)
 	}
 
 	return nil
@@ -387,6 +400,10 @@ func copyABranch(ctx *sql.Context, dbData env.DbData, srcBr string, destBr strin
 			return errors.New(fmt.Sprintf("fatal: Unexpected error copying branch from '%s' to '%s'", srcBr, destBr))
 		}
 	}
+	err = actions.CopyBranchDescription(dbData, srcBr, destBr)
+	if err != nil {
+		return err
+	}
 	err = branch_control.AddAdminForContext(ctx, destBr)
 	if err != nil {
 		return err
@@ -0,0 +1,107 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dprocedures
+
+import (
+	"fmt"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/branch_control"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqlserver"
+)
+
+// doltBranchPrune is the stored procedure version of `dolt branch --prune-stale`. It deletes the fully merged branches
+// older than their expiry age, as configured with branch.expireafter, and returns a row with the name of each branch
+// deleted. Branches that the current user isn't permitted to delete, that are in use by other sessions, or that have
+// uncommitted changes are kept.
+func doltBranchPrune(ctx *sql.Context, args ...string) (sql.RowIter, error) {
+	if len(args) != 0 {
+		return nil, InvalidArgErr
+	}
+
+	dbName := ctx.GetCurrentDatabase()
+	if len(dbName) == 0 {
+		return nil, fmt.Errorf("Empty database name.")
+	}
+
+	dSess := dsess.DSessFromSess(ctx.Session)
+	dbData, ok := dSess.GetDbData(ctx, dbName)
+	if !ok {
+		return nil, fmt.Errorf("Could not load database %s", dbName)
+	}
+
+	// The expiry policy is read from the config of the database being pruned, which isn't necessarily the one in the
+	// server's working directory
+	fs, err := dSess.Provider().FileSystemForDatabase(dbName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the config of database %s: %w", dbName, err)
+	}
+	cfg, err := env.LoadDoltCliConfig(env.GetCurrentUserHomeDir, fs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the config of database %s: %w", dbName, err)
+	}
+	policy, err := actions.BranchExpiryPolicyFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	} else if policy.ExpireAfter == 0 && len(policy.Overrides) == 0 {
+		return nil, fmt.Errorf("no branch expiry age is configured; set one with `dolt config --local --add %s 30d`", env.BranchExpireAfterKey)
+	}
+
+	// The branch checked out on the CLI is the default branch of a running server, so it's kept, as it is by
+	// dolt_branch('-d')
+	var headOnCLI string
+	if repoState, err := env.LoadRepoState(fs); err == nil {
+		headOnCLI = repoState.Head.Ref.GetPath()
+	}
+
+	// branches that share an upstream are validated against the same remote database
+	remoteDbs := actions.NewRemoteDbCache(dSess.Provider())
+	defer remoteDbs.Close()
+
+	var rsc doltdb.ReplicationStatusController
+	deleted, err := actions.PruneStaleBranches(ctx, dbData, policy, actions.PruneStaleOptions{
+		Keep: func(branch string) bool {
+			if branch_control.CanDeleteBranch(ctx, branch) != nil {
+				return true
+			}
+			if validateBranchNotActiveInAnySession(ctx, branch) != nil {
+				return true
+			}
+			return branch == headOnCLI && sqlserver.RunningInServerMode() && !shouldAllowDefaultBranchDeletion(ctx)
+		},
+	}, remoteDbs, &rsc)
+	if err != nil {
+		return nil, err
+	}
+	if len(deleted) > 0 {
+		dSess.DatabaseCache(ctx).InvalidateSessionVars(dbName)
+	}
+
+	err = commitTransaction(ctx, dSess, &rsc)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]sql.Row, len(deleted))
+	for i, branch := range deleted {
+		rows[i] = sql.Row{branch}
+	}
+	return sql.RowsToRowIter(rows...), nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dsess
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	if os.Getenv("DOLT_VERIFY_SESSION_CACHE") != "" {
+		verifySessionCache.Store(true)
+	}
+}
+
+// verifySessionCache is whether SessionCache hits are verified, see SetVerifySessionCache
+var verifySessionCache atomic.Bool
+
+// SetVerifySessionCache turns verification of SessionCache hits on or off. When it's on, the layer that reads the
+// cache recomputes every value it gets from it, and the functions below panic if the cached value doesn't match. It's
+// intended for catching cache coherency bugs in tests, and is off by default, or on if DOLT_VERIFY_SESSION_CACHE is
+// set.
+func SetVerifySessionCache(verify bool) {
+	verifySessionCache.Store(verify)
+}
+
+// VerifyingSessionCache returns whether SessionCache hits are being verified
+func VerifyingSessionCache() bool {
+	return verifySessionCache.Load()
+}
+
+// These functions verify a cache hit against the value computed by |recompute|, which returns the value from storage
+// and false if it doesn't exist. The cache doesn't know how to resolve anything itself, so the recompute functions are
+// supplied by the caller. They do nothing unless verification is on, and must be called without the cache's lock held,
+// since recomputing a value may populate the cache.
+
+// VerifyCachedTable verifies the table |cached| returned by SessionCache.GetCachedTable
+func VerifyCachedTable(name string, cached sql.Table, recompute func() (sql.Table, bool, error)) {
+	if !VerifyingSessionCache() || cached == nil {
+		return
+	}
+	actual, ok, err := recompute()
+	if !checkRecomputed("table", name, ok, err) {
+		return
+	}
+	if !strings.EqualFold(cached.Name(), actual.Name()) || !cached.Schema().Equals(actual.Schema()) {
+		panicOnCacheMismatch("table", name, cached.Schema(), actual.Schema())
+	}
+}
+
+// VerifyCachedIndexes verifies the indexes |cached| returned by SessionCache.GetTableIndexesCache
+func VerifyCachedIndexes(table string, cached []sql.Index, recompute func() ([]sql.Index, bool, error)) {
+	if !VerifyingSessionCache() {
+		return
+	}
+	actual, ok, err := recompute()
+	if !checkRecomputed("indexes of table", table, ok, err) {
+		return
+	}
+	cachedIDs, actualIDs := indexIDs(cached), indexIDs(actual)
+	if strings.Join(cachedIDs, ",") != strings.Join(actualIDs, ",") {
+		panicOnCacheMismatch("indexes of table", table, cachedIDs, actualIDs)
+	}
+}
+
+// VerifyCachedView verifies the view |cached| returned by SessionCache.GetCachedViewDefinition
+func VerifyCachedView(name string, cached sql.ViewDefinition, recompute func() (sql.ViewDefinition, bool, error)) {
+	if !VerifyingSessionCache() {
+		return
+	}
+	actual, ok, err := recompute()
+	if !checkRecomputed("view", name, ok, err) {
+		return
+	}
+	if !strings.EqualFold(cached.Name, actual.Name) || cached.TextDefinition != actual.TextDefinition {
+		panicOnCacheMismatch("view", name, cached.TextDefinition, actual.TextDefinition)
+	}
+}
+
+// VerifyCachedRowCount verifies the row count |cached| returned by SessionCache.GetRowCountCache
+func VerifyCachedRowCount(table string, cached uint64, recompute func() (uint64, bool, error)) {
+	if !VerifyingSessionCache() {
+		return
+	}
+	actual, ok, err := recompute()
+	if !checkRecomputed("row count of table", table, ok, err) {
+		return
+	}
+	if cached != actual {
+		panicOnCacheMismatch("row count of table", table, cached, actual)
+	}
+}
+
+// checkRecomputed returns whether a recomputed value can be compared to the cached one. A value that's cached but
+// doesn't exist is a mismatch, but a value that couldn't be recomputed can't be verified.
+func checkRecomputed(kind, name string, ok bool, err error) bool {
+	if err != nil {
+		logrus.Warnf("unable to verify cached %s %s: %v", kind, name, err)
+		return false
+	}
+	if !ok {
+		panic(fmt.Sprintf("session cache returned %s %s, which doesn't exist", kind, name))
+	}
+	return true
+}
+
+func panicOnCacheMismatch(kind, name string, cached, actual interface{}) {
+	panic(fmt.Sprintf("session cache returned a stale %s %s: cached %v, but found %v", kind, name, cached, actual))
+}
+
+func indexIDs(indexes []sql.Index) []string {
+	ids := make([]string, len(indexes))
+	for i, idx := range indexes {
+		ids[i] = strings.ToLower(idx.ID())
+	}
+	sort.Strings(ids)
+	return ids
+}
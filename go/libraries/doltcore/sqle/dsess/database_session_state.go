@@ -41,6 +41,8 @@ type InitialDbState struct {
 	Remotes  map[string]env.Remote
 	Branches map[string]env.BranchConfig
 	Backups  map[string]env.Remote
+	// BranchDescriptions holds the description of each branch that has one, keyed by branch name
+	BranchDescriptions map[string]string
 
 	// If err is set, this InitialDbState is partially invalid, but may be
 	// usable to initialize a database at a revision specifier, for
@@ -80,6 +82,12 @@ type DatabaseSessionState struct {
 	globalState globalstate.GlobalState
 	// tmpFileDir is the directory to use for temporary files for this database
 	tmpFileDir string
+	// cacheConfig is the configuration of the session caches in headCache
+	cacheConfig CacheConfig
+	// pinnedHead is the branch head whose session cache has the working root of the checked out branch pinned, and
+	// pinnedRoot is that root. See DoltSession.pinWorkingRoot.
+	pinnedHead string
+	pinnedRoot doltdb.DataCacheKey
 
 	// Same as InitialDbState.Err, this signifies that this
 	// DatabaseSessionState is invalid. LookupDbState returning a
@@ -87,10 +95,11 @@ type DatabaseSessionState struct {
 	Err error
 }
 
-func newEmptyDatabaseSessionState() *DatabaseSessionState {
+func newEmptyDatabaseSessionState(cacheConfig CacheConfig) *DatabaseSessionState {
 	return &DatabaseSessionState{
-		heads:     make(map[string]*branchState),
-		headCache: make(map[string]*SessionCache),
+		heads:       make(map[string]*branchState),
+		headCache:   make(map[string]*SessionCache),
+		cacheConfig: cacheConfig,
 	}
 }
 
@@ -139,7 +148,7 @@ func (dbState *DatabaseSessionState) NewEmptyBranchState(head string) *branchSta
 	dbState.heads[head] = b
 	_, ok := dbState.headCache[head]
 	if !ok {
-		dbState.headCache[head] = newSessionCache()
+		dbState.headCache[head] = newSessionCache(dbState.cacheConfig)
 	}
 
 	return b
@@ -16,14 +16,19 @@ package dsess
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/dolthub/go-mysql-server/sql"
 	_ "github.com/dolthub/go-mysql-server/sql/variables"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/src-d/go-errors.v1"
 
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
 	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
 	"github.com/dolthub/dolt/go/libraries/utils/config"
 	"github.com/dolthub/dolt/go/libraries/utils/filesys"
@@ -302,3 +307,75 @@ func (e emptyRevisionDatabaseProvider) CreateDatabase(ctx *sql.Context, dbName s
 func (e emptyRevisionDatabaseProvider) RevisionDbState(_ *sql.Context, revDB string) (InitialDbState, error) {
 	return InitialDbState{}, sql.ErrDatabaseNotFound.New(revDB)
 }
+
+func TestPinWorkingRoot(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+	root, err := doltdb.EmptyRootValue(ctx, dEnv.DoltDB.ValueReadWriter(), dEnv.DoltDB.NodeStore())
+	require.NoError(t, err)
+	key, err := doltdb.NewDataCacheKey(root)
+	require.NoError(t, err)
+
+	sess := DefaultSession(emptyDatabaseProvider())
+	dbState := newEmptyDatabaseSessionState(CacheConfig{})
+	main := dbState.NewEmptyBranchState("main")
+	main.headRoot = root
+	feature := dbState.NewEmptyBranchState("feature")
+	feature.headRoot = root
+
+	sess.pinWorkingRoot(main)
+	assert.Contains(t, main.SessionCache().pinned, key)
+
+	// switching branches moves the pin
+	sess.pinWorkingRoot(feature)
+	assert.NotContains(t, main.SessionCache().pinned, key)
+	assert.Contains(t, feature.SessionCache().pinned, key)
+	assert.Equal(t, "feature", dbState.pinnedHead)
+}
+
+// repoStateDatabaseProvider is a provider whose databases all share one in-memory filesystem
+type repoStateDatabaseProvider struct {
+	emptyRevisionDatabaseProvider
+	fs filesys.Filesys
+}
+
+func (p repoStateDatabaseProvider) FileSystemForDatabase(dbname string) (filesys.Filesys, error) {
+	return p.fs, nil
+}
+
+func TestSessionStateAdapterConcurrentDescriptions(t *testing.T) {
+	fs := filesys.NewInMemFS([]string{"/" + dbfactory.DoltDir}, nil, "/")
+	_, err := env.CreateRepoState(fs, "refs/heads/main")
+	require.NoError(t, err)
+	pro := repoStateDatabaseProvider{fs: fs}
+
+	// each adapter stands for a different session updating the same repo state file
+	const writers = 16
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			adapter := NewSessionStateAdapter(DefaultSession(pro), "db", nil, nil, nil, nil)
+			errs[i] = adapter.SetBranchDescription(fmt.Sprintf("branch%d", i), fmt.Sprintf("description %d", i))
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	// no update was lost
+	repoState, err := env.LoadRepoState(fs)
+	require.NoError(t, err)
+	assert.Len(t, repoState.BranchDescriptions, writers)
+
+	// descriptions are read from the ones loaded with the database, not from the file
+	adapter := NewSessionStateAdapter(DefaultSession(pro), "db", nil, nil, nil, map[string]string{"main": "the main branch"})
+	require.NoError(t, adapter.SetBranchDescription("branch0", ""))
+	descriptions, err := adapter.GetBranchDescriptions()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"main": "the main branch"}, descriptions)
+}
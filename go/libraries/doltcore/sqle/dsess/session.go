@@ -53,6 +53,7 @@ type DoltSession struct {
 	email            string
 	dbStates         map[string]*DatabaseSessionState
 	dbCache          *DatabaseCache
+	cacheConfig      CacheConfig
 	provider         DoltDatabaseProvider
 	tempTables       map[string][]sql.Table
 	globalsConf      config.ReadWriteConfig
@@ -76,7 +77,7 @@ func DefaultSession(pro DoltDatabaseProvider) *DoltSession {
 		username:         "",
 		email:            "",
 		dbStates:         make(map[string]*DatabaseSessionState),
-		dbCache:          newDatabaseCache(),
+		dbCache:          newDatabaseCache(CacheConfig{}),
 		provider:         pro,
 		tempTables:       make(map[string][]sql.Table),
 		globalsConf:      config.NewMapConfig(make(map[string]string)),
@@ -85,12 +86,14 @@ func DefaultSession(pro DoltDatabaseProvider) *DoltSession {
 	}
 }
 
-// NewDoltSession creates a DoltSession object from a standard sql.Session and 0 or more Database objects.
+// NewDoltSession creates a DoltSession object from a standard sql.Session and 0 or more Database objects. The caches of
+// the session are configured with |cacheConfig|, the zero value of which is the default configuration.
 func NewDoltSession(
 	sqlSess *sql.BaseSession,
 	pro DoltDatabaseProvider,
 	conf config.ReadWriteConfig,
 	branchController *branch_control.Controller,
+	cacheConfig CacheConfig,
 ) (*DoltSession, error) {
 	username := conf.GetStringOrDefault(env.UserNameKey, "")
 	email := conf.GetStringOrDefault(env.UserEmailKey, "")
@@ -101,7 +104,8 @@ func NewDoltSession(
 		username:         username,
 		email:            email,
 		dbStates:         make(map[string]*DatabaseSessionState),
-		dbCache:          newDatabaseCache(),
+		dbCache:          newDatabaseCache(cacheConfig),
+		cacheConfig:      cacheConfig,
 		provider:         pro,
 		tempTables:       make(map[string][]sql.Table),
 		globalsConf:      globals,
@@ -880,6 +884,7 @@ func (d *DoltSession) SetWorkingSet(ctx *sql.Context, dbName string, ws *doltdb.
 	}
 
 	branchState.dirty = true
+	d.repinWorkingRoot(branchState)
 	return nil
 }
 
@@ -923,10 +928,51 @@ func (d *DoltSession) SwitchWorkingSet(
 	}
 
 	ctx.SetCurrentDatabase(baseName)
+	d.pinWorkingRoot(branchState)
 
 	return d.setDbSessionVars(ctx, branchState, false)
 }
 
+// pinWorkingRoot pins the working root of |branchState| in its session cache, so that the cached tables of the branch
+// the session has checked out aren't evicted by lookups of other revisions, and unpins the root previously pinned for
+// the same database. Pinning is only an optimization, so a root that can't be pinned is left unpinned.
+func (d *DoltSession) pinWorkingRoot(branchState *branchState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dbState := branchState.dbState
+	if dbState.pinnedHead != "" {
+		if cache, ok := dbState.headCache[dbState.pinnedHead]; ok {
+			cache.Unpin(dbState.pinnedRoot)
+		}
+		dbState.pinnedHead, dbState.pinnedRoot = "", doltdb.DataCacheKey{}
+	}
+
+	root := branchState.WorkingRoot()
+	if root == nil {
+		return
+	}
+	key, err := doltdb.NewDataCacheKey(root)
+	if err != nil {
+		return
+	}
+	if branchState.SessionCache().Pin(key) {
+		dbState.pinnedHead, dbState.pinnedRoot = branchState.head, key
+	}
+}
+
+// repinWorkingRoot moves the pin of pinWorkingRoot to the new working root of |branchState|, if it's the branch with
+// the pinned root
+func (d *DoltSession) repinWorkingRoot(branchState *branchState) {
+	d.mu.Lock()
+	pinned := branchState.dbState.pinnedHead == branchState.head
+	d.mu.Unlock()
+
+	if pinned {
+		d.pinWorkingRoot(branchState)
+	}
+}
+
 func (d *DoltSession) UseDatabase(ctx *sql.Context, db sql.Database) error {
 	sdb, ok := db.(SqlDatabase)
 	if !ok {
@@ -1094,7 +1140,7 @@ func (d *DoltSession) addDB(ctx *sql.Context, db SqlDatabase) error {
 	}
 
 	if !sessionStateExists {
-		sessionState = newEmptyDatabaseSessionState()
+		sessionState = newEmptyDatabaseSessionState(d.cacheConfig)
 		d.dbStates[baseName] = sessionState
 
 		var err error
@@ -1138,7 +1184,7 @@ func (d *DoltSession) addDB(ctx *sql.Context, db SqlDatabase) error {
 	// TODO: this no longer gets called at session creation time, so the error handling below never occurs when a
 	//  database is deleted out from under a running server
 	branchState.dbData = dbState.DbData
-	adapter := NewSessionStateAdapter(d, db.Name(), dbState.Remotes, dbState.Branches, dbState.Backups)
+	adapter := NewSessionStateAdapter(d, db.Name(), dbState.Remotes, dbState.Branches, dbState.Backups, dbState.BranchDescriptions)
 	branchState.dbData.Rsr = adapter
 	branchState.dbData.Rsw = adapter
 	branchState.readOnly = dbState.ReadOnly
@@ -1623,22 +1669,24 @@ func TransactionRoot(ctx *sql.Context, db SqlDatabase) (hash.Hash, error) {
 	return nomsRoot, nil
 }
 
+// defaultBranchFromSystemVar returns the name of the default branch set for the database named with its
+// @@<db>_default_branch system variable, or the empty string if none is set.
+func defaultBranchFromSystemVar(baseName string) string {
+	_, val, ok := sql.SystemVariables.GetGlobal(DefaultBranchKey(baseName))
+	if !ok {
+		return ""
+	}
+	branchRef, err := ref.Parse(val.(string))
+	if err != nil {
+		return ""
+	}
+	return branchRef.GetPath()
+}
+
 // DefaultHead returns the head for the database given when one isn't specified
 func DefaultHead(baseName string, db SqlDatabase) (string, error) {
-	head := ""
-
 	// First check the global variable for the default branch
-	_, val, ok := sql.SystemVariables.GetGlobal(DefaultBranchKey(baseName))
-	if ok {
-		head = val.(string)
-		branchRef, err := ref.Parse(head)
-		if err == nil {
-			head = branchRef.GetPath()
-		} else {
-			head = ""
-			// continue to below
-		}
-	}
+	head := defaultBranchFromSystemVar(baseName)
 
 	// Fall back to the database's initially checked out branch
 	if head == "" {
@@ -17,19 +17,48 @@ package dsess
 import (
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dolthub/go-mysql-server/sql"
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/store/hash"
 )
 
 // SessionCache caches various pieces of expensive to compute information to speed up future lookups in the session.
 type SessionCache struct {
-	indexes map[doltdb.DataCacheKey]map[string][]sql.Index
-	tables  map[doltdb.DataCacheKey]map[string]sql.Table
+	indexes map[doltdb.DataCacheKey]map[string]cachedIndexes
+	checks  map[doltdb.DataCacheKey]map[string]cachedChecks
+	tables  map[doltdb.DataCacheKey]map[string]*cachedTable
 	views   map[doltdb.DataCacheKey]map[string]sql.ViewDefinition
-
-	mu sync.RWMutex
+	// rowCounts caches approximate table row counts. Root values are immutable, so a modified table is always under
+	// a new key and entries never need to be invalidated individually.
+	rowCounts map[doltdb.DataCacheKey]map[string]uint64
+	// partitions caches the partitions of each table, which are stable for a given root
+	partitions map[doltdb.DataCacheKey]map[string]cachedPartitions
+	// generatedColumns caches the resolved expressions of each table's generated columns, keyed by column name
+	generatedColumns map[doltdb.DataCacheKey]map[string]cachedGeneratedColumns
+	// systemTableSchemas caches the schemas of system tables, which may depend on the root, e.g. dolt_diff_<table>.
+	// Only schemas are cached, since the contents of many system tables change with every commit.
+	systemTableSchemas map[doltdb.DataCacheKey]map[string]sql.Schema
+	// autoIncrements caches the AUTO_INCREMENT high-water mark of each table. See CacheAutoIncrement.
+	autoIncrements map[doltdb.DataCacheKey]map[string]cachedAutoIncrement
+	// pkOrdinals caches the ordinals of each table's primary key columns. See CachePKOrdinals.
+	pkOrdinals map[doltdb.DataCacheKey]map[string]cachedPKOrdinals
+	// columnDefaults caches the resolved default values of each table's columns, keyed by column name
+	columnDefaults map[doltdb.DataCacheKey]map[string]cachedColumnDefaults
+	// pinned records the roots exempt from capacity eviction. See Pin.
+	pinned map[doltdb.DataCacheKey]struct{}
+
+	config CacheConfig
+	// lastUsed is the time of the most recent use of the cache, in Unix nanoseconds. It's only maintained when the
+	// config has an IdleExpiry.
+	lastUsed atomic.Int64
+	// generation is the cache generation when this cache was created or last flushed. See BumpCacheGeneration.
+	generation atomic.Uint64
+	counters   cacheCounters
+	mu         sync.RWMutex
 }
 
 // DatabaseCache stores databases and their initial states, offloading the compute / IO involved in resolving a
@@ -37,194 +66,1290 @@ type SessionCache struct {
 // handles to data or state, but always defer to the session. Keys in the secondary map are revision specifier strings
 type DatabaseCache struct {
 	// revisionDbs caches databases by name. The name is always lower case and revision qualified
-	revisionDbs map[revisionDbCacheKey]SqlDatabase
+	revisionDbs map[revisionDbCacheKey]*revisionDbCacheEntry
+	// revisionDbClock is a logical clock used to record the recency of use of revisionDbs entries
+	revisionDbClock atomic.Uint64
 	// initialDbStates caches the initial state of databases by name for a given noms root, which is the primary key.
 	// The secondary key is the lower-case revision-qualified database name.
-	initialDbStates map[doltdb.DataCacheKey]map[string]InitialDbState
+	initialDbStates map[doltdb.DataCacheKey]map[string]cachedInitialDbState
 	// sessionVars records a key for the most recently used session vars for each database in the session
 	sessionVars map[string]sessionVarCacheKey
+	// revisionRoots caches the root that each revision database pinned to an immutable revision resolves to. The key
+	// is the lower-case revision-qualified database name. See IsImmutableRevisionType.
+	revisionRoots map[string]doltdb.DataCacheKey
+	// collations caches the resolved default collation of each database. The key is the lower-case base database name.
+	collations map[string]sql.CollationID
+
+	config   CacheConfig
+	counters cacheCounters
+	mu       sync.RWMutex
+}
+
+// CacheStats reports why entries have been removed from a cache
+type CacheStats struct {
+	// CapacityEvictions is the number of entries removed to keep the cache within its size bounds. If this is high, the
+	// cache is too small for the workload.
+	CapacityEvictions uint64
+	// ExplicitInvalidations is the number of times all or part of the cache was cleared by an explicit invalidation,
+	// e.g. because of a root change.
+	ExplicitInvalidations uint64
+}
+
+type cacheCounters struct {
+	capacityEvictions     atomic.Uint64
+	explicitInvalidations atomic.Uint64
+}
+
+func (cc *cacheCounters) stats() CacheStats {
+	return CacheStats{
+		CapacityEvictions:     cc.capacityEvictions.Load(),
+		ExplicitInvalidations: cc.explicitInvalidations.Load(),
+	}
+}
+
+// revisionDbCacheKey is the key for a cached revision database. The dbName is the lower-cased, revision-qualified name
+// of the database, and is normalized on both store and lookup. The requestedName is intentionally kept in the case the
+// client used, since it becomes the Name() of the cached database and must be echoed back verbatim.
+type revisionDbCacheKey struct {
+	dbName        string
+	requestedName string
+}
+
+// revisionDbCacheEntry is a cached revision database along with the logical time it was last used, which determines the
+// order of eviction when the cache is full.
+type revisionDbCacheEntry struct {
+	db       SqlDatabase
+	lastUsed atomic.Uint64
+}
+
+// baseName returns the lower-cased name of the database without its revision qualifier
+func (k revisionDbCacheKey) baseName() string {
+	baseName, _ := SplitRevisionDbName(k.dbName)
+	return baseName
+}
+
+func newRevisionDbCacheKey(revisionDbName, requestedName string) revisionDbCacheKey {
+	return revisionDbCacheKey{
+		dbName:        strings.ToLower(revisionDbName),
+		requestedName: requestedName,
+	}
+}
+
+type sessionVarCacheKey struct {
+	root doltdb.DataCacheKey
+	head string
+}
+
+const maxCachedKeys = 64
+
+// CacheConfig configures SessionCache and DatabaseCache. The zero value is the default configuration.
+//
+// Most fields are the number of entries each map may hold before it's evicted. For the per-root maps of SessionCache,
+// it's the number of roots, not the number of tables. Entries differ a lot in weight, e.g. a cached sql.Table is much
+// heavier than a sql.ViewDefinition, so deployments can tune these to their schemas. A capacity that isn't positive
+// means the default, 64.
+type CacheConfig struct {
+	// IdleExpiry, if positive, is how long a SessionCache may go unused before everything in it is dropped, so that
+	// idle pooled sessions don't hold on to a full cache indefinitely. Entries are dropped on the next use after the
+	// expiry rather than when it passes.
+	IdleExpiry time.Duration
+
+	Indexes            int
+	Checks             int
+	Tables             int
+	Views              int
+	RowCounts          int
+	Partitions         int
+	GeneratedColumns   int
+	SystemTableSchemas int
+	AutoIncrements     int
+	PKOrdinals         int
+	ColumnDefaults     int
+
+	RevisionDbs     int
+	InitialDbStates int
+	RevisionRoots   int
+	Collations      int
+}
+
+// capacityOrDefault returns |capacity| if it's positive, and the default capacity otherwise
+func capacityOrDefault(capacity int) int {
+	if capacity <= 0 {
+		return maxCachedKeys
+	}
+	return capacity
+}
+
+// MaxCachedRevisionDbsPerDatabase is the maximum number of revision databases cached for any one base database. This
+// keeps a session that touches many revisions of one database from evicting the cached revisions of all the others.
+// The total number of cached revision databases is still bounded by CacheConfig.RevisionDbs.
+var MaxCachedRevisionDbsPerDatabase = 16
+
+func newSessionCache(config CacheConfig) *SessionCache {
+	c := &SessionCache{config: config}
+	c.generation.Store(cacheGeneration.Load())
+	return c
+}
+
+func newDatabaseCache(config CacheConfig) *DatabaseCache {
+	return &DatabaseCache{
+		sessionVars: make(map[string]sessionVarCacheKey),
+		config:      config,
+	}
+}
+
+// evictRootsIfFull removes everything cached for every root that isn't pinned if any of the per-root caches holds
+// entries for more roots than its capacity. All the per-root caches are evicted together, so that they never hold
+// different sets of roots, e.g. indexes for a root whose tables have been evicted. Callers must hold the write lock.
+func (c *SessionCache) evictRootsIfFull() {
+	capacity := c.config
+	if len(c.tables) <= capacityOrDefault(capacity.Tables) &&
+		len(c.indexes) <= capacityOrDefault(capacity.Indexes) &&
+		len(c.checks) <= capacityOrDefault(capacity.Checks) &&
+		len(c.views) <= capacityOrDefault(capacity.Views) &&
+		len(c.rowCounts) <= capacityOrDefault(capacity.RowCounts) &&
+		len(c.partitions) <= capacityOrDefault(capacity.Partitions) &&
+		len(c.generatedColumns) <= capacityOrDefault(capacity.GeneratedColumns) &&
+		len(c.systemTableSchemas) <= capacityOrDefault(capacity.SystemTableSchemas) &&
+		len(c.autoIncrements) <= capacityOrDefault(capacity.AutoIncrements) &&
+		len(c.pkOrdinals) <= capacityOrDefault(capacity.PKOrdinals) &&
+		len(c.columnDefaults) <= capacityOrDefault(capacity.ColumnDefaults) {
+		return
+	}
+
+	roots := c.cachedRoots()
+	for k := range c.pinned {
+		delete(roots, k)
+	}
+	c.counters.capacityEvictions.Add(uint64(len(roots)))
+
+	for k := range roots {
+		delete(c.tables, k)
+		delete(c.indexes, k)
+		delete(c.checks, k)
+		delete(c.views, k)
+		delete(c.rowCounts, k)
+		delete(c.partitions, k)
+		delete(c.generatedColumns, k)
+		delete(c.systemTableSchemas, k)
+		delete(c.autoIncrements, k)
+		delete(c.pkOrdinals, k)
+		delete(c.columnDefaults, k)
+	}
+}
+
+// cacheGeneration is shared by all sessions in the process. Each SessionCache records the generation current when it
+// was created or last flushed, and drops everything it holds on its next use after the generation is bumped.
+var cacheGeneration atomic.Uint64
+
+// BumpCacheGeneration invalidates everything cached by the SessionCache of every session, e.g. after a migration that
+// changes how schemas are interpreted. Each cache is emptied on its next use, rather than all of them at once.
+func BumpCacheGeneration() {
+	cacheGeneration.Add(1)
+}
+
+// expireIfStale drops everything in the cache if the cache generation has been bumped since it was last flushed, or if
+// it has gone unused for longer than its IdleExpiry, and records this use. It must be called by every method that
+// reads or adds entries, before taking the lock.
+func (c *SessionCache) expireIfStale() {
+	generation := cacheGeneration.Load()
+	flushed := c.generation.Load() != generation
+
+	idle := false
+	if c.config.IdleExpiry > 0 {
+		now := time.Now().UnixNano()
+		last := c.lastUsed.Swap(now)
+		idle = last != 0 && time.Duration(now-last) > c.config.IdleExpiry
+	}
+	if !flushed && !idle {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another use may have flushed the cache while this one waited for the lock
+	flushed = c.generation.Swap(generation) != generation
+	if !flushed && !idle {
+		return
+	}
+
+	roots := c.cachedRoots()
+	if flushed {
+		c.counters.explicitInvalidations.Add(1)
+	} else {
+		c.counters.capacityEvictions.Add(uint64(len(roots)))
+	}
+	for k := range roots {
+		delete(c.tables, k)
+		delete(c.indexes, k)
+		delete(c.checks, k)
+		delete(c.views, k)
+		delete(c.rowCounts, k)
+		delete(c.partitions, k)
+		delete(c.generatedColumns, k)
+		delete(c.systemTableSchemas, k)
+		delete(c.autoIncrements, k)
+		delete(c.pkOrdinals, k)
+		delete(c.columnDefaults, k)
+	}
+}
+
+// SeedFrom copies everything |other| has cached for the roots in |validKeys| into this cache, without replacing any
+// entries this cache already has, e.g. so that a session on a recycled connection doesn't start cold. Roots not in
+// |validKeys| are skipped, so callers should only include roots that are still current. |other| may be in use
+// concurrently; it's only locked while its entries are copied, and isn't modified.
+func (c *SessionCache) SeedFrom(other *SessionCache, validKeys map[doltdb.DataCacheKey]bool) {
+	c.expireIfStale()
+
+	if other == nil || other == c {
+		return
+	}
+
+	// Copy |other| before locking this cache, so that two caches seeding from each other can't deadlock
+	other.mu.RLock()
+	snapshot := &SessionCache{
+		indexes:            seedRoots(nil, other.indexes, validKeys),
+		checks:             seedRoots(nil, other.checks, validKeys),
+		tables:             seedRoots(nil, other.tables, validKeys),
+		views:              seedRoots(nil, other.views, validKeys),
+		rowCounts:          seedRoots(nil, other.rowCounts, validKeys),
+		partitions:         seedRoots(nil, other.partitions, validKeys),
+		generatedColumns:   seedRoots(nil, other.generatedColumns, validKeys),
+		systemTableSchemas: seedRoots(nil, other.systemTableSchemas, validKeys),
+		autoIncrements:     seedRoots(nil, other.autoIncrements, validKeys),
+		pkOrdinals:         seedRoots(nil, other.pkOrdinals, validKeys),
+		columnDefaults:     seedRoots(nil, other.columnDefaults, validKeys),
+	}
+	other.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.indexes = seedRoots(c.indexes, snapshot.indexes, validKeys)
+	c.checks = seedRoots(c.checks, snapshot.checks, validKeys)
+	c.tables = seedRoots(c.tables, snapshot.tables, validKeys)
+	c.views = seedRoots(c.views, snapshot.views, validKeys)
+	c.rowCounts = seedRoots(c.rowCounts, snapshot.rowCounts, validKeys)
+	c.partitions = seedRoots(c.partitions, snapshot.partitions, validKeys)
+	c.generatedColumns = seedRoots(c.generatedColumns, snapshot.generatedColumns, validKeys)
+	c.systemTableSchemas = seedRoots(c.systemTableSchemas, snapshot.systemTableSchemas, validKeys)
+	c.autoIncrements = seedRoots(c.autoIncrements, snapshot.autoIncrements, validKeys)
+	c.pkOrdinals = seedRoots(c.pkOrdinals, snapshot.pkOrdinals, validKeys)
+	c.columnDefaults = seedRoots(c.columnDefaults, snapshot.columnDefaults, validKeys)
+	c.evictRootsIfFull()
+}
+
+// seedRoots copies the entries in |src| for the roots in |validKeys| into |dst|, creating it if it's nil, and returns
+// it. Entries already in |dst| are kept. The per-root maps of |src| are copied rather than shared, but the entries in
+// them are shared, so they must not be modified after they're cached.
+func seedRoots[V any](dst, src map[doltdb.DataCacheKey]map[string]V, validKeys map[doltdb.DataCacheKey]bool) map[doltdb.DataCacheKey]map[string]V {
+	for key, srcForKey := range src {
+		if !validKeys[key] {
+			continue
+		}
+		if dst == nil {
+			dst = make(map[doltdb.DataCacheKey]map[string]V)
+		}
+		dstForKey, ok := dst[key]
+		if !ok {
+			dstForKey = make(map[string]V, len(srcForKey))
+			dst[key] = dstForKey
+		}
+		for name, v := range srcForKey {
+			if _, ok := dstForKey[name]; !ok {
+				dstForKey[name] = v
+			}
+		}
+	}
+	return dst
+}
+
+// CachedKeys returns the keys of all the roots this cache holds entries for, in no particular order. It's intended for
+// diagnostics, e.g. finding roots that are never evicted.
+func (c *SessionCache) CachedKeys() []doltdb.DataCacheKey {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	roots := c.cachedRoots()
+	keys := make([]doltdb.DataCacheKey, 0, len(roots))
+	for k := range roots {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// cachedRoots returns the set of roots that any of the per-root caches holds entries for. Callers must hold the lock.
+func (c *SessionCache) cachedRoots() map[doltdb.DataCacheKey]struct{} {
+	roots := make(map[doltdb.DataCacheKey]struct{})
+	for k := range c.tables {
+		roots[k] = struct{}{}
+	}
+	for k := range c.indexes {
+		roots[k] = struct{}{}
+	}
+	for k := range c.checks {
+		roots[k] = struct{}{}
+	}
+	for k := range c.views {
+		roots[k] = struct{}{}
+	}
+	for k := range c.rowCounts {
+		roots[k] = struct{}{}
+	}
+	for k := range c.partitions {
+		roots[k] = struct{}{}
+	}
+	for k := range c.generatedColumns {
+		roots[k] = struct{}{}
+	}
+	for k := range c.systemTableSchemas {
+		roots[k] = struct{}{}
+	}
+	for k := range c.autoIncrements {
+		roots[k] = struct{}{}
+	}
+	for k := range c.pkOrdinals {
+		roots[k] = struct{}{}
+	}
+	for k := range c.columnDefaults {
+		roots[k] = struct{}{}
+	}
+	return roots
+}
+
+// maxPinnedRoots is the most roots that may be pinned in a SessionCache at once. Pinned roots can't be evicted to make
+// room, so without a bound a caller that never unpins would turn the cache into an unbounded one.
+const maxPinnedRoots = 4
+
+// Pin exempts everything cached for the root given from capacity eviction, e.g. so that the root a session is working
+// on isn't evicted by incidental lookups of other roots. Pinned entries are still removed by explicit invalidation.
+// At most maxPinnedRoots roots may be pinned at once. Pin returns whether the root is pinned, which is false if it
+// wasn't already pinned and the limit has been reached.
+func (c *SessionCache) Pin(key doltdb.DataCacheKey) bool {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.pinned[key]; ok {
+		return true
+	}
+	if len(c.pinned) >= maxPinnedRoots {
+		return false
+	}
+	if c.pinned == nil {
+		c.pinned = make(map[doltdb.DataCacheKey]struct{})
+	}
+	c.pinned[key] = struct{}{}
+	return true
+}
+
+// Unpin makes the root given subject to capacity eviction again. Unpinning a root that isn't pinned does nothing.
+func (c *SessionCache) Unpin(key doltdb.DataCacheKey) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pinned, key)
+}
+
+// CacheTableIndexes caches all indexes for the table with the name given
+func (c *SessionCache) CacheTableIndexes(key doltdb.DataCacheKey, table string, indexes []sql.Index) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table = strings.ToLower(table)
+
+	if c.indexes == nil {
+		c.indexes = make(map[doltdb.DataCacheKey]map[string]cachedIndexes)
+	}
+	c.evictRootsIfFull()
+
+	tableIndexes, ok := c.indexes[key]
+	if !ok {
+		tableIndexes = make(map[string]cachedIndexes)
+		c.indexes[key] = tableIndexes
+	}
+
+	tableIndexes[table] = cachedIndexes{
+		indexes:  indexes,
+		columns:  indexColumns(indexes),
+		cachedAt: tableInvalidations.current(),
+	}
+}
+
+// cachedIndexes is an entry in the index cache
+type cachedIndexes struct {
+	indexes []sql.Index
+	// columns are the ordered column names of each index, keyed by lower-case index name
+	columns map[string][]string
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
+}
+
+// indexColumns returns the ordered column names of each of the indexes given, keyed by lower-case index name
+func indexColumns(indexes []sql.Index) map[string][]string {
+	columns := make(map[string][]string, len(indexes))
+	for _, idx := range indexes {
+		if idx == nil {
+			continue
+		}
+		exprs := idx.Expressions()
+		cols := make([]string, len(exprs))
+		for i, expr := range exprs {
+			// expressions are qualified with the table name, e.g. "t.col"
+			cols[i] = strings.TrimPrefix(expr, idx.Table()+".")
+		}
+		columns[strings.ToLower(idx.ID())] = cols
+	}
+	return columns
+}
+
+// GetIndexColumns returns the ordered column names of the index named on the table named, as derived from the indexes
+// cached with CacheTableIndexes, and whether they were present. They are invalidated along with the cached indexes.
+func (c *SessionCache) GetIndexColumns(key doltdb.DataCacheKey, table, indexName string) ([]string, bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	table = strings.ToLower(table)
+	entry, ok := c.indexes[key][table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return nil, false
+	}
+
+	cols, ok := entry.columns[strings.ToLower(indexName)]
+	if !ok {
+		return nil, false
+	}
+	return append([]string(nil), cols...), true
+}
+
+// GetTableIndexesCache returns the cached index information for the table named, and whether the cache was present
+func (c *SessionCache) GetTableIndexesCache(key doltdb.DataCacheKey, table string) ([]sql.Index, bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.indexes == nil {
+		return nil, false
+	}
+
+	tableIndexes, ok := c.indexes[key]
+	if !ok {
+		return nil, false
+	}
+	table = strings.ToLower(table)
+
+	entry, ok := tableIndexes[table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return nil, false
+	}
+	return entry.indexes, true
+}
+
+// GetAllCachedIndexes returns a copy of the index information cached for every table for the key given, keyed by
+// lower-case table name, and whether any index information was cached for the key. Tables invalidated with
+// InvalidateCachedTable are omitted.
+func (c *SessionCache) GetAllCachedIndexes(key doltdb.DataCacheKey) (indexes map[string][]sql.Index, ok bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tableIndexes, ok := c.indexes[key]
+	if !ok {
+		return nil, false
+	}
+
+	indexes = make(map[string][]sql.Index, len(tableIndexes))
+	for table, entry := range tableIndexes {
+		if tableInvalidations.isStale(key, table, entry.cachedAt) {
+			continue
+		}
+		indexes[table] = append([]sql.Index(nil), entry.indexes...)
+	}
+	return indexes, true
+}
+
+// CacheCheckConstraints caches all check constraints for the table with the name given
+func (c *SessionCache) CacheCheckConstraints(key doltdb.DataCacheKey, table string, checks []sql.CheckConstraint) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table = strings.ToLower(table)
+
+	if c.checks == nil {
+		c.checks = make(map[doltdb.DataCacheKey]map[string]cachedChecks)
+	}
+	c.evictRootsIfFull()
+
+	tableChecks, ok := c.checks[key]
+	if !ok {
+		tableChecks = make(map[string]cachedChecks)
+		c.checks[key] = tableChecks
+	}
+
+	tableChecks[table] = cachedChecks{checks: checks, cachedAt: tableInvalidations.current()}
+}
+
+// cachedChecks is an entry in the check constraint cache
+type cachedChecks struct {
+	checks []sql.CheckConstraint
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
+}
+
+// GetCheckConstraintsCache returns the cached check constraints for the table named, and whether the cache was present.
+// Entries for a table whose schema has changed at this root, as signaled with InvalidateCachedTable, are cache misses.
+func (c *SessionCache) GetCheckConstraintsCache(key doltdb.DataCacheKey, table string) (checks []sql.CheckConstraint, ok bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.checks == nil {
+		return nil, false
+	}
+
+	tableChecks, ok := c.checks[key]
+	if !ok {
+		return nil, false
+	}
+	table = strings.ToLower(table)
+
+	entry, ok := tableChecks[table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return nil, false
+	}
+	return entry.checks, true
+}
+
+// cachedTable is an entry in the table cache. Tables cached with CacheLazyTable aren't loaded until their first lookup,
+// after which the loaded table is kept in the entry.
+type cachedTable struct {
+	table sql.Table
+	load  func() (sql.Table, error)
+	once  sync.Once
+	err   error
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
+	// schemaHash is the hash of the table's schema when it was cached, if the caller provided one
+	schemaHash hash.Hash
+}
+
+// get returns the table for this entry, loading it first if necessary
+func (t *cachedTable) get() (sql.Table, error) {
+	if t.load != nil {
+		t.once.Do(func() {
+			t.table, t.err = t.load()
+		})
+	}
+	return t.table, t.err
+}
+
+// CacheTable caches a sql.Table implementation for the table named
+func (c *SessionCache) CacheTable(key doltdb.DataCacheKey, tableName string, table sql.Table) {
+	c.cacheTableEntry(key, tableName, &cachedTable{table: table})
+}
+
+// CacheTableWithSchemaHash caches a sql.Table implementation for the table named, along with the hash of its schema.
+// See GetCachedTableWithSchemaHash.
+func (c *SessionCache) CacheTableWithSchemaHash(key doltdb.DataCacheKey, tableName string, table sql.Table, schemaHash hash.Hash) {
+	c.cacheTableEntry(key, tableName, &cachedTable{table: table, schemaHash: schemaHash})
+}
+
+// CacheLazyTable caches a function that loads the sql.Table implementation for the table named. It's called on the
+// first GetCachedTable for the table, and its result is cached in place of the function. If it returns an error, the
+// lookup is a cache miss and the entry is removed.
+func (c *SessionCache) CacheLazyTable(key doltdb.DataCacheKey, tableName string, load func() (sql.Table, error)) {
+	c.cacheTableEntry(key, tableName, &cachedTable{load: load})
+}
+
+func (c *SessionCache) cacheTableEntry(key doltdb.DataCacheKey, tableName string, entry *cachedTable) {
+	c.expireIfStale()
 
-	mu sync.RWMutex
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tableName = strings.ToLower(tableName)
+	if c.tables == nil {
+		c.tables = make(map[doltdb.DataCacheKey]map[string]*cachedTable)
+	}
+	c.evictRootsIfFull()
+
+	tablesForKey, ok := c.tables[key]
+	if !ok {
+		tablesForKey = make(map[string]*cachedTable)
+		c.tables[key] = tablesForKey
+	}
+
+	entry.cachedAt = tableInvalidations.current()
+	tablesForKey[tableName] = entry
+	if entry.table != nil {
+		c.cachePKOrdinalsOf(key, tableName, entry.table)
+	}
+}
+
+// CacheTables caches all the tables in |tables|, keyed by table name, for the cache key given. This is equivalent to
+// calling CacheTable for each entry, but takes the lock once.
+func (c *SessionCache) CacheTables(key doltdb.DataCacheKey, tables map[string]sql.Table) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tables == nil {
+		c.tables = make(map[doltdb.DataCacheKey]map[string]*cachedTable)
+	}
+	c.evictRootsIfFull()
+
+	tablesForKey, ok := c.tables[key]
+	if !ok {
+		tablesForKey = make(map[string]*cachedTable, len(tables))
+		c.tables[key] = tablesForKey
+	}
+
+	cachedAt := tableInvalidations.current()
+	for tableName, table := range tables {
+		tableName = strings.ToLower(tableName)
+		tablesForKey[tableName] = &cachedTable{table: table, cachedAt: cachedAt}
+		c.cachePKOrdinalsOf(key, tableName, table)
+	}
+}
+
+// ClearTableCache removes all cache info for all tables at all cache keys
+func (c *SessionCache) ClearTableCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters.explicitInvalidations.Add(1)
+	for k := range c.tables {
+		delete(c.tables, k)
+	}
+	for k := range c.partitions {
+		delete(c.partitions, k)
+	}
+	for k := range c.generatedColumns {
+		delete(c.generatedColumns, k)
+	}
+	for k := range c.autoIncrements {
+		delete(c.autoIncrements, k)
+	}
+	for k := range c.pkOrdinals {
+		delete(c.pkOrdinals, k)
+	}
+	for k := range c.columnDefaults {
+		delete(c.columnDefaults, k)
+	}
+}
+
+// EvictTablesWhere removes everything cached for each table for which |pred| returns true, at every cache key, and
+// returns the number of cache entries removed. The table name passed to |pred| is lower case. This visits every cached entry
+// while holding the write lock, so it's O(total entries) and |pred| must be cheap and must not use the cache.
+func (c *SessionCache) EvictTablesWhere(pred func(key doltdb.DataCacheKey, table string) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	evicted := evictWhere(c.tables, pred)
+	evicted += evictWhere(c.indexes, pred)
+	evicted += evictWhere(c.checks, pred)
+	evicted += evictWhere(c.rowCounts, pred)
+	evicted += evictWhere(c.partitions, pred)
+	evicted += evictWhere(c.generatedColumns, pred)
+	evicted += evictWhere(c.autoIncrements, pred)
+	evicted += evictWhere(c.pkOrdinals, pred)
+	evicted += evictWhere(c.columnDefaults, pred)
+	if evicted > 0 {
+		c.counters.explicitInvalidations.Add(1)
+	}
+	return evicted
+}
+
+// evictWhere removes the entries of |m| for which |pred| returns true, along with any keys left empty, and returns the
+// number of entries removed
+func evictWhere[V any](m map[doltdb.DataCacheKey]map[string]V, pred func(key doltdb.DataCacheKey, table string) bool) int {
+	evicted := 0
+	for key, forKey := range m {
+		for name := range forKey {
+			if pred(key, name) {
+				delete(forKey, name)
+				evicted++
+			}
+		}
+		if len(forKey) == 0 {
+			delete(m, key)
+		}
+	}
+	return evicted
+}
+
+// GetCachedTable returns the cached sql.Table for the table named, and whether the cache was present
+func (c *SessionCache) GetCachedTable(key doltdb.DataCacheKey, tableName string) (sql.Table, bool) {
+	table, _, ok := c.GetCachedTableWithSchemaHash(key, tableName)
+	return table, ok
+}
+
+// GetCachedTableWithSchemaHash returns the cached sql.Table for the table named, the schema hash it was cached with,
+// and whether the cache was present. Callers that track schema hashes can compare the one returned with the table's
+// current schema hash at the root, and treat a mismatch as a miss. The hash is empty for tables cached without one.
+func (c *SessionCache) GetCachedTableWithSchemaHash(key doltdb.DataCacheKey, tableName string) (sql.Table, hash.Hash, bool) {
+	c.expireIfStale()
+
+	tableName = strings.ToLower(tableName)
+
+	c.mu.RLock()
+	entry, ok := c.tables[key][tableName]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, hash.Hash{}, false
+	}
+	if tableInvalidations.isStale(key, tableName, entry.cachedAt) {
+		c.removeTableEntry(key, tableName, entry)
+		return nil, hash.Hash{}, false
+	}
+
+	// lazy tables are loaded outside the lock, since loading may be expensive
+	table, err := entry.get()
+	if err != nil {
+		c.removeTableEntry(key, tableName, entry)
+		return nil, hash.Hash{}, false
+	}
+
+	return table, entry.schemaHash, true
+}
+
+// removeTableEntry removes the entry for the table named, if it is still |entry|
+func (c *SessionCache) removeTableEntry(key doltdb.DataCacheKey, tableName string, entry *cachedTable) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tables[key][tableName] == entry {
+		delete(c.tables[key], tableName)
+	}
+}
+
+// GetCachedTablesForKey returns a copy of all the tables cached for the key given, keyed by lower-case table name.
+// Any lazily cached tables are loaded, and omitted from the result if loading fails. Tables invalidated with
+// InvalidateCachedTable are also omitted.
+func (c *SessionCache) GetCachedTablesForKey(key doltdb.DataCacheKey) map[string]sql.Table {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	entries := make(map[string]*cachedTable, len(c.tables[key]))
+	for name, entry := range c.tables[key] {
+		entries[name] = entry
+	}
+	c.mu.RUnlock()
+
+	tables := make(map[string]sql.Table, len(entries))
+	for name, entry := range entries {
+		if tableInvalidations.isStale(key, name, entry.cachedAt) {
+			c.removeTableEntry(key, name, entry)
+			continue
+		}
+		table, err := entry.get()
+		if err != nil {
+			c.removeTableEntry(key, name, entry)
+			continue
+		}
+		tables[name] = table
+	}
+
+	return tables
+}
+
+// CacheViews caches all views in a database for the cache key given
+func (c *SessionCache) CacheViews(key doltdb.DataCacheKey, views []sql.ViewDefinition) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.views == nil {
+		c.views = make(map[doltdb.DataCacheKey]map[string]sql.ViewDefinition)
+	}
+	c.evictRootsIfFull()
+
+	viewsForKey, ok := c.views[key]
+	if !ok {
+		viewsForKey = make(map[string]sql.ViewDefinition)
+		c.views[key] = viewsForKey
+	}
+
+	for i := range views {
+		viewName := strings.ToLower(views[i].Name)
+		viewsForKey[viewName] = views[i]
+	}
+}
+
+// ViewsCached returns whether this cache has been initialized with the set of views yet
+func (c *SessionCache) ViewsCached(key doltdb.DataCacheKey) bool {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.views == nil {
+		return false
+	}
+
+	_, ok := c.views[key]
+	return ok
+}
+
+// GetCachedViewDefinition returns the cached view named, and whether the cache was present
+func (c *SessionCache) GetCachedViewDefinition(key doltdb.DataCacheKey, viewName string) (sql.ViewDefinition, bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	viewName = strings.ToLower(viewName)
+	if c.views == nil {
+		return sql.ViewDefinition{}, false
+	}
+
+	viewsForKey, ok := c.views[key]
+	if !ok {
+		return sql.ViewDefinition{}, false
+	}
+
+	view, ok := viewsForKey[viewName]
+	return view, ok
+}
+
+// GetAllCachedViews returns a copy of all the views cached for the key given, keyed by lower-case view name, and
+// whether views have been cached for the key. See ViewsCached.
+func (c *SessionCache) GetAllCachedViews(key doltdb.DataCacheKey) (views map[string]sql.ViewDefinition, ok bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	viewsForKey, ok := c.views[key]
+	if !ok {
+		return nil, false
+	}
+
+	views = make(map[string]sql.ViewDefinition, len(viewsForKey))
+	for name, view := range viewsForKey {
+		views[name] = view
+	}
+	return views, true
+}
+
+// CacheRowCount caches the row count for the table named
+func (c *SessionCache) CacheRowCount(key doltdb.DataCacheKey, table string, count uint64) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table = strings.ToLower(table)
+
+	if c.rowCounts == nil {
+		c.rowCounts = make(map[doltdb.DataCacheKey]map[string]uint64)
+	}
+	c.evictRootsIfFull()
+
+	countsForKey, ok := c.rowCounts[key]
+	if !ok {
+		countsForKey = make(map[string]uint64)
+		c.rowCounts[key] = countsForKey
+	}
+
+	countsForKey[table] = count
+}
+
+// GetRowCountCache returns the cached row count for the table named, and whether the cache was present
+func (c *SessionCache) GetRowCountCache(key doltdb.DataCacheKey, table string) (uint64, bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.rowCounts == nil {
+		return 0, false
+	}
+
+	countsForKey, ok := c.rowCounts[key]
+	if !ok {
+		return 0, false
+	}
+	table = strings.ToLower(table)
+
+	count, ok := countsForKey[table]
+	return count, ok
+}
+
+// CacheTablePartitions caches the partitions of the table named
+func (c *SessionCache) CacheTablePartitions(key doltdb.DataCacheKey, table string, parts []sql.Partition) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table = strings.ToLower(table)
+
+	if c.partitions == nil {
+		c.partitions = make(map[doltdb.DataCacheKey]map[string]cachedPartitions)
+	}
+	c.evictRootsIfFull()
+
+	partsForKey, ok := c.partitions[key]
+	if !ok {
+		partsForKey = make(map[string]cachedPartitions)
+		c.partitions[key] = partsForKey
+	}
+
+	partsForKey[table] = cachedPartitions{parts: parts, cachedAt: tableInvalidations.current()}
+}
+
+// cachedPartitions is an entry in the partition cache
+type cachedPartitions struct {
+	parts []sql.Partition
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
+}
+
+// GetTablePartitionsCache returns the cached partitions of the table named, and whether the cache was present
+func (c *SessionCache) GetTablePartitionsCache(key doltdb.DataCacheKey, table string) ([]sql.Partition, bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.partitions == nil {
+		return nil, false
+	}
+
+	partsForKey, ok := c.partitions[key]
+	if !ok {
+		return nil, false
+	}
+	table = strings.ToLower(table)
+
+	entry, ok := partsForKey[table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return nil, false
+	}
+	return entry.parts, true
+}
+
+// CacheGeneratedColumns caches the resolved expressions of the generated columns of the table named, keyed by column
+// name. A table's generated columns are part of its schema, so a schema change always puts them under a new key.
+func (c *SessionCache) CacheGeneratedColumns(key doltdb.DataCacheKey, table string, exprs map[string]sql.Expression) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table = strings.ToLower(table)
+
+	if c.generatedColumns == nil {
+		c.generatedColumns = make(map[doltdb.DataCacheKey]map[string]cachedGeneratedColumns)
+	}
+	c.evictRootsIfFull()
+
+	exprsForKey, ok := c.generatedColumns[key]
+	if !ok {
+		exprsForKey = make(map[string]cachedGeneratedColumns)
+		c.generatedColumns[key] = exprsForKey
+	}
+
+	exprsForKey[table] = cachedGeneratedColumns{exprs: exprs, cachedAt: tableInvalidations.current()}
+}
+
+// cachedGeneratedColumns is an entry in the generated column cache
+type cachedGeneratedColumns struct {
+	exprs map[string]sql.Expression
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
+}
+
+// GetGeneratedColumnsCache returns the cached generated column expressions of the table named, and whether the cache
+// was present
+func (c *SessionCache) GetGeneratedColumnsCache(key doltdb.DataCacheKey, table string) (map[string]sql.Expression, bool) {
+	c.expireIfStale()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.generatedColumns == nil {
+		return nil, false
+	}
+
+	exprsForKey, ok := c.generatedColumns[key]
+	if !ok {
+		return nil, false
+	}
+	table = strings.ToLower(table)
+
+	entry, ok := exprsForKey[table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return nil, false
+	}
+	return entry.exprs, true
 }
 
-type revisionDbCacheKey struct {
-	dbName        string
-	requestedName string
+// CacheColumnDefaults caches the resolved default values of the columns of the table named, keyed by column name.
+// A table's column defaults are part of its schema, so entries are invalidated along with the table by
+// InvalidateCachedTable and ClearTableCache.
+func (c *SessionCache) CacheColumnDefaults(key doltdb.DataCacheKey, table string, defaults map[string]*sql.ColumnDefaultValue) {
+	c.expireIfStale()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table = strings.ToLower(table)
+
+	if c.columnDefaults == nil {
+		c.columnDefaults = make(map[doltdb.DataCacheKey]map[string]cachedColumnDefaults)
+	}
+	c.evictRootsIfFull()
+
+	defaultsForKey, ok := c.columnDefaults[key]
+	if !ok {
+		defaultsForKey = make(map[string]cachedColumnDefaults)
+		c.columnDefaults[key] = defaultsForKey
+	}
+
+	defaultsForKey[table] = cachedColumnDefaults{defaults: defaults, cachedAt: tableInvalidations.current()}
 }
 
-type sessionVarCacheKey struct {
-	root doltdb.DataCacheKey
-	head string
+// cachedColumnDefaults is an entry in the column default cache
+type cachedColumnDefaults struct {
+	defaults map[string]*sql.ColumnDefaultValue
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
 }
 
-const maxCachedKeys = 64
+// GetCachedColumnDefaults returns the cached column default values of the table named, and whether the cache was
+// present
+func (c *SessionCache) GetCachedColumnDefaults(key doltdb.DataCacheKey, table string) (map[string]*sql.ColumnDefaultValue, bool) {
+	c.expireIfStale()
 
-func newSessionCache() *SessionCache {
-	return &SessionCache{}
-}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-func newDatabaseCache() *DatabaseCache {
-	return &DatabaseCache{
-		sessionVars: make(map[string]sessionVarCacheKey),
+	table = strings.ToLower(table)
+	entry, ok := c.columnDefaults[key][table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return nil, false
 	}
+	return entry.defaults, true
 }
 
-// CacheTableIndexes caches all indexes for the table with the name given
-func (c *SessionCache) CacheTableIndexes(key doltdb.DataCacheKey, table string, indexes []sql.Index) {
+// CacheSystemTableSchema caches the schema of the system table named
+func (c *SessionCache) CacheSystemTableSchema(key doltdb.DataCacheKey, name string, sch sql.Schema) {
+	c.expireIfStale()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	table = strings.ToLower(table)
+	name = strings.ToLower(name)
 
-	if c.indexes == nil {
-		c.indexes = make(map[doltdb.DataCacheKey]map[string][]sql.Index)
-	}
-	if len(c.indexes) > maxCachedKeys {
-		for k := range c.indexes {
-			delete(c.indexes, k)
-		}
+	if c.systemTableSchemas == nil {
+		c.systemTableSchemas = make(map[doltdb.DataCacheKey]map[string]sql.Schema)
 	}
+	c.evictRootsIfFull()
 
-	tableIndexes, ok := c.indexes[key]
+	schemasForKey, ok := c.systemTableSchemas[key]
 	if !ok {
-		tableIndexes = make(map[string][]sql.Index)
-		c.indexes[key] = tableIndexes
+		schemasForKey = make(map[string]sql.Schema)
+		c.systemTableSchemas[key] = schemasForKey
 	}
 
-	tableIndexes[table] = indexes
+	schemasForKey[name] = sch
 }
 
-// GetTableIndexesCache returns the cached index information for the table named, and whether the cache was present
-func (c *SessionCache) GetTableIndexesCache(key doltdb.DataCacheKey, table string) ([]sql.Index, bool) {
+// GetSystemTableSchemaCache returns the cached schema of the system table named, and whether the cache was present
+func (c *SessionCache) GetSystemTableSchemaCache(key doltdb.DataCacheKey, name string) (sql.Schema, bool) {
+	c.expireIfStale()
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if c.indexes == nil {
+	if c.systemTableSchemas == nil {
 		return nil, false
 	}
 
-	tableIndexes, ok := c.indexes[key]
+	schemasForKey, ok := c.systemTableSchemas[key]
 	if !ok {
 		return nil, false
 	}
-	table = strings.ToLower(table)
 
-	indexes, ok := tableIndexes[table]
-	return indexes, ok
+	sch, ok := schemasForKey[strings.ToLower(name)]
+	return sch, ok
 }
 
-// CacheTable caches a sql.Table implementation for the table named
-func (c *SessionCache) CacheTable(key doltdb.DataCacheKey, tableName string, table sql.Table) {
+// CacheAutoIncrement caches the AUTO_INCREMENT high-water mark of the table named at the root given.
+//
+// Keys are generated from this value, so a stale one would corrupt data, and the cache is only correct if it's tightly
+// coupled to writes: every write to the table must invalidate the entry with InvalidateAutoIncrement before it starts,
+// because writes advance the value before the working root, and so the key, changes. getTableEditor does this for
+// every write made through SQL, and any other path that writes to a table must do the same or bypass the cache.
+// Entries are also invalidated along with the table by InvalidateCachedTable.
+func (c *SessionCache) CacheAutoIncrement(key doltdb.DataCacheKey, table string, value uint64) {
+	c.expireIfStale()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	tableName = strings.ToLower(tableName)
-	if c.tables == nil {
-		c.tables = make(map[doltdb.DataCacheKey]map[string]sql.Table)
-	}
-	if len(c.tables) > maxCachedKeys {
-		for k := range c.tables {
-			delete(c.tables, k)
-		}
+	table = strings.ToLower(table)
+
+	if c.autoIncrements == nil {
+		c.autoIncrements = make(map[doltdb.DataCacheKey]map[string]cachedAutoIncrement)
 	}
+	c.evictRootsIfFull()
 
-	tablesForKey, ok := c.tables[key]
+	valuesForKey, ok := c.autoIncrements[key]
 	if !ok {
-		tablesForKey = make(map[string]sql.Table)
-		c.tables[key] = tablesForKey
+		valuesForKey = make(map[string]cachedAutoIncrement)
+		c.autoIncrements[key] = valuesForKey
 	}
 
-	tablesForKey[tableName] = table
+	valuesForKey[table] = cachedAutoIncrement{value: value, cachedAt: tableInvalidations.current()}
 }
 
-// ClearTableCache removes all cache info for all tables at all cache keys
-func (c *SessionCache) ClearTableCache() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	for k := range c.tables {
-		delete(c.tables, k)
-	}
+// cachedAutoIncrement is an entry in the auto increment cache
+type cachedAutoIncrement struct {
+	value uint64
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
 }
 
-// GetCachedTable returns the cached sql.Table for the table named, and whether the cache was present
-func (c *SessionCache) GetCachedTable(key doltdb.DataCacheKey, tableName string) (sql.Table, bool) {
+// GetCachedAutoIncrement returns the cached AUTO_INCREMENT value of the table named, and whether the cache was present.
+// See CacheAutoIncrement.
+func (c *SessionCache) GetCachedAutoIncrement(key doltdb.DataCacheKey, table string) (uint64, bool) {
+	c.expireIfStale()
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	tableName = strings.ToLower(tableName)
-	if c.tables == nil {
-		return nil, false
+	table = strings.ToLower(table)
+	entry, ok := c.autoIncrements[key][table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return 0, false
 	}
+	return entry.value, true
+}
 
-	tablesForKey, ok := c.tables[key]
-	if !ok {
-		return nil, false
-	}
+// InvalidateAutoIncrement removes the cached AUTO_INCREMENT high-water mark of the table named at the root given. It
+// must be called before every write to the table. See CacheAutoIncrement.
+func (c *SessionCache) InvalidateAutoIncrement(key doltdb.DataCacheKey, table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	table, ok := tablesForKey[tableName]
-	return table, ok
+	delete(c.autoIncrements[key], strings.ToLower(table))
 }
 
-// CacheViews caches all views in a database for the cache key given
-func (c *SessionCache) CacheViews(key doltdb.DataCacheKey, views []sql.ViewDefinition) {
+// CachePKOrdinals caches the ordinals of the primary key columns of the table named, in key order. Tables cached with
+// CacheTable or CacheTables have their primary key ordinals cached as well. The primary key only changes with the
+// table's schema, so entries are invalidated along with the table by InvalidateCachedTable and ClearTableCache.
+func (c *SessionCache) CachePKOrdinals(key doltdb.DataCacheKey, table string, ordinals []int) {
+	c.expireIfStale()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.views == nil {
-		c.views = make(map[doltdb.DataCacheKey]map[string]sql.ViewDefinition)
-	}
-	if len(c.views) > maxCachedKeys {
-		for k := range c.views {
-			delete(c.views, k)
-		}
+	if c.pkOrdinals == nil {
+		c.pkOrdinals = make(map[doltdb.DataCacheKey]map[string]cachedPKOrdinals)
 	}
+	c.evictRootsIfFull()
+	c.cachePKOrdinals(key, strings.ToLower(table), ordinals)
+}
 
-	viewsForKey, ok := c.views[key]
+// cachePKOrdinalsOf caches the primary key ordinals of |table| if it has a primary key schema. Callers must hold the
+// write lock, and |tableName| must be lower case.
+func (c *SessionCache) cachePKOrdinalsOf(key doltdb.DataCacheKey, tableName string, table sql.Table) {
+	pkTable, ok := table.(sql.PrimaryKeyTable)
 	if !ok {
-		viewsForKey = make(map[string]sql.ViewDefinition)
-		c.views[key] = viewsForKey
+		return
 	}
-
-	for i := range views {
-		viewName := strings.ToLower(views[i].Name)
-		viewsForKey[viewName] = views[i]
+	if c.pkOrdinals == nil {
+		c.pkOrdinals = make(map[doltdb.DataCacheKey]map[string]cachedPKOrdinals)
 	}
+	c.cachePKOrdinals(key, tableName, pkTable.PrimaryKeySchema().PkOrdinals)
 }
 
-// ViewsCached returns whether this cache has been initialized with the set of views yet
-func (c *SessionCache) ViewsCached(key doltdb.DataCacheKey) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// cachePKOrdinals adds an entry to the primary key ordinals cache, which must not be nil. Callers must hold the write
+// lock, and |table| must be lower case.
+func (c *SessionCache) cachePKOrdinals(key doltdb.DataCacheKey, table string, ordinals []int) {
+	ordinalsForKey, ok := c.pkOrdinals[key]
+	if !ok {
+		ordinalsForKey = make(map[string]cachedPKOrdinals)
+		c.pkOrdinals[key] = ordinalsForKey
+	}
 
-	if c.views == nil {
-		return false
+	ordinalsForKey[table] = cachedPKOrdinals{
+		ordinals: append([]int(nil), ordinals...),
+		cachedAt: tableInvalidations.current(),
 	}
+}
 
-	_, ok := c.views[key]
-	return ok
+// cachedPKOrdinals is an entry in the primary key ordinals cache
+type cachedPKOrdinals struct {
+	ordinals []int
+	// cachedAt is the tableInvalidations sequence number when the entry was cached
+	cachedAt uint64
 }
 
-// GetCachedViewDefinition returns the cached view named, and whether the cache was present
-func (c *SessionCache) GetCachedViewDefinition(key doltdb.DataCacheKey, viewName string) (sql.ViewDefinition, bool) {
+// GetCachedPKOrdinals returns the cached primary key ordinals of the table named, and whether the cache was present.
+// The slice returned is a copy, and may be modified by the caller. A keyless table has no ordinals.
+func (c *SessionCache) GetCachedPKOrdinals(key doltdb.DataCacheKey, table string) ([]int, bool) {
+	c.expireIfStale()
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	viewName = strings.ToLower(viewName)
-	if c.views == nil {
-		return sql.ViewDefinition{}, false
+	table = strings.ToLower(table)
+	entry, ok := c.pkOrdinals[key][table]
+	if !ok || tableInvalidations.isStale(key, table, entry.cachedAt) {
+		return nil, false
 	}
+	return append([]int(nil), entry.ordinals...), true
+}
 
-	viewsForKey, ok := c.views[key]
-	if !ok {
-		return sql.ViewDefinition{}, false
-	}
+// Stats returns counts of the entries removed from this cache, and why
+func (c *SessionCache) Stats() CacheStats {
+	return c.counters.stats()
+}
 
-	table, ok := viewsForKey[viewName]
-	return table, ok
+// InvalidateRoot removes everything cached for the root given
+func (c *SessionCache) InvalidateRoot(key doltdb.DataCacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters.explicitInvalidations.Add(1)
+	delete(c.indexes, key)
+	delete(c.checks, key)
+	delete(c.tables, key)
+	delete(c.views, key)
+	delete(c.rowCounts, key)
+	delete(c.partitions, key)
+	delete(c.generatedColumns, key)
+	delete(c.systemTableSchemas, key)
+	delete(c.autoIncrements, key)
+	delete(c.pkOrdinals, key)
+	delete(c.columnDefaults, key)
 }
 
-// GetCachedRevisionDb returns the cached revision database named, and whether the cache was present
+// GetCachedRevisionDb returns the cached revision database named, and whether the cache was present. The revision
+// database name is matched case-insensitively, the requested name case-sensitively. A hit marks the entry as most
+// recently used. This is done with an atomic store under the read lock rather than by taking the write lock, since
+// this lookup happens on every revision database resolution and recency only needs to be approximately right.
 func (c *DatabaseCache) GetCachedRevisionDb(revisionDbName string, requestedName string) (SqlDatabase, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -233,32 +1358,94 @@ func (c *DatabaseCache) GetCachedRevisionDb(revisionDbName string, requestedName
 		return nil, false
 	}
 
-	db, ok := c.revisionDbs[revisionDbCacheKey{
-		dbName:        revisionDbName,
-		requestedName: requestedName,
-	}]
-	return db, ok
+	entry, ok := c.revisionDbs[newRevisionDbCacheKey(revisionDbName, requestedName)]
+	if !ok {
+		return nil, false
+	}
+
+	entry.lastUsed.Store(c.revisionDbClock.Add(1))
+	return entry.db, true
 }
 
-// CacheRevisionDb caches the revision database named
+// CacheRevisionDb caches the revision database named. If the cache is full, the least recently used database is
+// evicted to make room.
 func (c *DatabaseCache) CacheRevisionDb(database SqlDatabase) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.revisionDbs == nil {
-		c.revisionDbs = make(map[revisionDbCacheKey]SqlDatabase)
+		c.revisionDbs = make(map[revisionDbCacheKey]*revisionDbCacheEntry)
 	}
 
-	if len(c.revisionDbs) > maxCachedKeys {
-		for k := range c.revisionDbs {
-			delete(c.revisionDbs, k)
+	key := newRevisionDbCacheKey(database.RevisionQualifiedName(), database.RequestedName())
+	if _, ok := c.revisionDbs[key]; !ok {
+		if c.countRevisionDbs(key.baseName()) >= MaxCachedRevisionDbsPerDatabase {
+			c.evictLeastRecentlyUsedRevisionDb(key.baseName())
+		} else if len(c.revisionDbs) >= capacityOrDefault(c.config.RevisionDbs) {
+			c.evictLeastRecentlyUsedRevisionDb("")
+		}
+	}
+
+	entry := &revisionDbCacheEntry{db: database}
+	entry.lastUsed.Store(c.revisionDbClock.Add(1))
+	c.revisionDbs[key] = entry
+}
+
+// ForEachCachedRevisionDb calls |fn| for each cached revision database until it returns false. The set of entries is
+// snapshotted up front, and the lock is not held while |fn| runs. Entries evicted after the snapshot is taken are
+// skipped.
+func (c *DatabaseCache) ForEachCachedRevisionDb(fn func(SqlDatabase) bool) {
+	c.mu.RLock()
+	keys := make([]revisionDbCacheKey, 0, len(c.revisionDbs))
+	for k := range c.revisionDbs {
+		keys = append(keys, k)
+	}
+	c.mu.RUnlock()
+
+	for _, k := range keys {
+		c.mu.RLock()
+		entry, ok := c.revisionDbs[k]
+		c.mu.RUnlock()
+		if !ok {
+			continue
+		}
+
+		if !fn(entry.db) {
+			return
+		}
+	}
+}
+
+// countRevisionDbs returns the number of cached revision databases for the base database named. Callers must hold the
+// lock.
+func (c *DatabaseCache) countRevisionDbs(baseName string) int {
+	count := 0
+	for k := range c.revisionDbs {
+		if k.baseName() == baseName {
+			count++
 		}
 	}
+	return count
+}
 
-	c.revisionDbs[revisionDbCacheKey{
-		dbName:        strings.ToLower(database.RevisionQualifiedName()),
-		requestedName: database.RequestedName(),
-	}] = database
+// evictLeastRecentlyUsedRevisionDb removes the least recently used entry from revisionDbs for the base database named,
+// or across all databases if |baseName| is empty. Callers must hold the write lock.
+func (c *DatabaseCache) evictLeastRecentlyUsedRevisionDb(baseName string) {
+	var lruKey revisionDbCacheKey
+	var lruTime uint64
+	found := false
+	for k, entry := range c.revisionDbs {
+		if baseName != "" && k.baseName() != baseName {
+			continue
+		}
+		if t := entry.lastUsed.Load(); !found || t < lruTime {
+			lruKey, lruTime, found = k, t, true
+		}
+	}
+	if found {
+		delete(c.revisionDbs, lruKey)
+		c.counters.capacityEvictions.Add(1)
+	}
 }
 
 // GetCachedInitialDbState returns the cached initial state for the revision database named, and whether the cache
@@ -276,8 +1463,28 @@ func (c *DatabaseCache) GetCachedInitialDbState(key doltdb.DataCacheKey, revisio
 		return InitialDbState{}, false
 	}
 
-	db, ok := dbsForKey[revisionDbName]
-	return db, ok
+	entry, ok := dbsForKey[revisionDbName]
+	return entry.state, ok
+}
+
+// GetCachedInitialDbStateWithMeta is like GetCachedInitialDbState, but also returns the time the entry was cached, for
+// diagnosing cache coherency problems.
+func (c *DatabaseCache) GetCachedInitialDbStateWithMeta(key doltdb.DataCacheKey, revisionDbName string) (InitialDbState, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.initialDbStates[key][revisionDbName]
+	if !ok {
+		return InitialDbState{}, time.Time{}, false
+	}
+	return entry.state, entry.cachedAt, true
+}
+
+// cachedInitialDbState is an entry in the initial db state cache
+type cachedInitialDbState struct {
+	state InitialDbState
+	// cachedAt is when the entry was cached
+	cachedAt time.Time
 }
 
 // CacheInitialDbState caches the initials state for the revision database named
@@ -286,10 +1493,37 @@ func (c *DatabaseCache) CacheInitialDbState(key doltdb.DataCacheKey, revisionDbN
 	defer c.mu.Unlock()
 
 	if c.initialDbStates == nil {
-		c.initialDbStates = make(map[doltdb.DataCacheKey]map[string]InitialDbState)
+		c.initialDbStates = make(map[doltdb.DataCacheKey]map[string]cachedInitialDbState)
+	}
+
+	if len(c.initialDbStates) > capacityOrDefault(c.config.InitialDbStates) {
+		c.counters.capacityEvictions.Add(uint64(len(c.initialDbStates)))
+		for k := range c.initialDbStates {
+			delete(c.initialDbStates, k)
+		}
+	}
+
+	dbsForKey, ok := c.initialDbStates[key]
+	if !ok {
+		dbsForKey = make(map[string]cachedInitialDbState)
+		c.initialDbStates[key] = dbsForKey
+	}
+
+	dbsForKey[revisionDbName] = cachedInitialDbState{state: state, cachedAt: time.Now()}
+}
+
+// CacheInitialDbStates caches the initial states for all the revision databases named in |states|, which must all
+// be for the same root. This is equivalent to calling CacheInitialDbState for each entry, but takes the lock once.
+func (c *DatabaseCache) CacheInitialDbStates(key doltdb.DataCacheKey, states map[string]InitialDbState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.initialDbStates == nil {
+		c.initialDbStates = make(map[doltdb.DataCacheKey]map[string]cachedInitialDbState)
 	}
 
-	if len(c.initialDbStates) > maxCachedKeys {
+	if len(c.initialDbStates) > capacityOrDefault(c.config.InitialDbStates) {
+		c.counters.capacityEvictions.Add(uint64(len(c.initialDbStates)))
 		for k := range c.initialDbStates {
 			delete(c.initialDbStates, k)
 		}
@@ -297,11 +1531,14 @@ func (c *DatabaseCache) CacheInitialDbState(key doltdb.DataCacheKey, revisionDbN
 
 	dbsForKey, ok := c.initialDbStates[key]
 	if !ok {
-		dbsForKey = make(map[string]InitialDbState)
+		dbsForKey = make(map[string]cachedInitialDbState, len(states))
 		c.initialDbStates[key] = dbsForKey
 	}
 
-	dbsForKey[revisionDbName] = state
+	cachedAt := time.Now()
+	for revisionDbName, state := range states {
+		dbsForKey[revisionDbName] = cachedInitialDbState{state: state, cachedAt: cachedAt}
+	}
 }
 
 // CacheSessionVars updates the session var cache for the given branch state and transaction and returns whether it
@@ -328,10 +1565,214 @@ func (c *DatabaseCache) CacheSessionVars(branchState *branchState, transaction *
 	return !found || existingKey != newKey
 }
 
+// InvalidateSessionVars drops the session var cache entry for the database named, so that the next CacheSessionVars
+// call for it reports a change. This is needed when a branch is deleted or renamed, since a branch recreated with the
+// same name could otherwise match the stale entry. The name is compared case-insensitively, ignoring any revision
+// qualifier.
+func (c *DatabaseCache) InvalidateSessionVars(dbBaseName string) {
+	dbBaseName, _ = SplitRevisionDbName(dbBaseName)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for dbName := range c.sessionVars {
+		if strings.EqualFold(dbName, dbBaseName) {
+			delete(c.sessionVars, dbName)
+		}
+	}
+}
+
+// InvalidateRoot removes all initial db states cached for the root given, as well as any session var cache entries
+// recorded for it, so that session vars are recomputed on next use
+func (c *DatabaseCache) InvalidateRoot(key doltdb.DataCacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters.explicitInvalidations.Add(1)
+	delete(c.initialDbStates, key)
+	for dbName, varsKey := range c.sessionVars {
+		if varsKey.root == key {
+			delete(c.sessionVars, dbName)
+		}
+	}
+}
+
+// InvalidateDatabase removes all revision databases, initial db states, revision roots and session var cache entries
+// for the database with the base name given, e.g. because it was dropped or renamed. Entries for all revisions of the
+// database are removed. Names are compared case-insensitively, and any revision qualifier on |baseName| is ignored.
+func (c *DatabaseCache) InvalidateDatabase(baseName string) {
+	baseName, _ = SplitRevisionDbName(strings.ToLower(baseName))
+	isForDatabase := func(dbName string) bool {
+		dbBaseName, _ := SplitRevisionDbName(strings.ToLower(dbName))
+		return dbBaseName == baseName
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters.explicitInvalidations.Add(1)
+	for k := range c.revisionDbs {
+		if k.baseName() == baseName {
+			delete(c.revisionDbs, k)
+		}
+	}
+	for key, dbsForKey := range c.initialDbStates {
+		for revisionDbName := range dbsForKey {
+			if isForDatabase(revisionDbName) {
+				delete(dbsForKey, revisionDbName)
+			}
+		}
+		if len(dbsForKey) == 0 {
+			delete(c.initialDbStates, key)
+		}
+	}
+	for dbName := range c.sessionVars {
+		if isForDatabase(dbName) {
+			delete(c.sessionVars, dbName)
+		}
+	}
+	for revisionDbName := range c.revisionRoots {
+		if isForDatabase(revisionDbName) {
+			delete(c.revisionRoots, revisionDbName)
+		}
+	}
+	delete(c.collations, baseName)
+}
+
+// IsImmutableRevisionType returns whether a database pinned to a revision of the type given always resolves to the
+// same root, which makes the resolved root safe to cache with CacheRevisionRoot. This is true for commits and tags.
+// Branches move, so the roots of branch databases must always be resolved again. A tag that is deleted and recreated
+// pointing at a different commit will not be seen until the database is invalidated with InvalidateDatabase.
+func IsImmutableRevisionType(revType RevisionType) bool {
+	return revType == RevisionTypeCommit || revType == RevisionTypeTag
+}
+
+// CacheRevisionRoot caches the root that the revision database named resolves to, if its revision type is one that
+// IsImmutableRevisionType allows. Returns whether the root was cached.
+func (c *DatabaseCache) CacheRevisionRoot(revisionDbName string, revType RevisionType, key doltdb.DataCacheKey) bool {
+	if !IsImmutableRevisionType(revType) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.revisionRoots == nil {
+		c.revisionRoots = make(map[string]doltdb.DataCacheKey)
+	}
+	if len(c.revisionRoots) > capacityOrDefault(c.config.RevisionRoots) {
+		c.counters.capacityEvictions.Add(uint64(len(c.revisionRoots)))
+		for k := range c.revisionRoots {
+			delete(c.revisionRoots, k)
+		}
+	}
+
+	c.revisionRoots[strings.ToLower(revisionDbName)] = key
+	return true
+}
+
+// GetCachedRevisionRoot returns the cached root for the revision database named, and whether the cache was present.
+// The name is matched case-insensitively.
+func (c *DatabaseCache) GetCachedRevisionRoot(revisionDbName string) (doltdb.DataCacheKey, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key, ok := c.revisionRoots[strings.ToLower(revisionDbName)]
+	return key, ok
+}
+
+// CacheDatabaseCollation caches the resolved default collation of the database named. Revision databases share the
+// collation of their base database.
+func (c *DatabaseCache) CacheDatabaseCollation(baseName string, collation sql.CollationID) {
+	baseName, _ = SplitRevisionDbName(strings.ToLower(baseName))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.collations == nil {
+		c.collations = make(map[string]sql.CollationID)
+	}
+	if len(c.collations) > capacityOrDefault(c.config.Collations) {
+		c.counters.capacityEvictions.Add(uint64(len(c.collations)))
+		for k := range c.collations {
+			delete(c.collations, k)
+		}
+	}
+
+	c.collations[baseName] = collation
+}
+
+// GetCachedDatabaseCollation returns the cached default collation of the database named, and whether the cache was
+// present. The name is matched case-insensitively. The entry is removed by InvalidateDatabase, which must be called
+// when the database's collation changes.
+func (c *DatabaseCache) GetCachedDatabaseCollation(baseName string) (sql.CollationID, bool) {
+	baseName, _ = SplitRevisionDbName(strings.ToLower(baseName))
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	collation, ok := c.collations[baseName]
+	return collation, ok
+}
+
+// Stats returns counts of the entries removed from this cache, and why
+func (c *DatabaseCache) Stats() CacheStats {
+	return c.counters.stats()
+}
+
+// CloneInto replaces the contents of |dst| with a copy of the revision databases, initial db states, revision roots and
+// session var cache entries in this cache, so that a session forked from this one starts with the same resolved state.
+func (c *DatabaseCache) CloneInto(dst *DatabaseCache) {
+	if c == dst {
+		return
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	dst.revisionDbs = make(map[revisionDbCacheKey]*revisionDbCacheEntry, len(c.revisionDbs))
+	for k, v := range c.revisionDbs {
+		entry := &revisionDbCacheEntry{db: v.db}
+		entry.lastUsed.Store(v.lastUsed.Load())
+		dst.revisionDbs[k] = entry
+	}
+	dst.revisionDbClock.Store(c.revisionDbClock.Load())
+
+	// initial db states are immutable for a given root, but the per-root maps are still written to, so copy those
+	dst.initialDbStates = make(map[doltdb.DataCacheKey]map[string]cachedInitialDbState, len(c.initialDbStates))
+	for key, dbsForKey := range c.initialDbStates {
+		copied := make(map[string]cachedInitialDbState, len(dbsForKey))
+		for k, v := range dbsForKey {
+			copied[k] = v
+		}
+		dst.initialDbStates[key] = copied
+	}
+
+	dst.sessionVars = make(map[string]sessionVarCacheKey, len(c.sessionVars))
+	for k, v := range c.sessionVars {
+		dst.sessionVars[k] = v
+	}
+
+	dst.revisionRoots = make(map[string]doltdb.DataCacheKey, len(c.revisionRoots))
+	for k, v := range c.revisionRoots {
+		dst.revisionRoots[k] = v
+	}
+
+	dst.collations = make(map[string]sql.CollationID, len(c.collations))
+	for k, v := range c.collations {
+		dst.collations[k] = v
+	}
+}
+
 func (c *DatabaseCache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.counters.explicitInvalidations.Add(1)
 	c.sessionVars = make(map[string]sessionVarCacheKey)
-	c.revisionDbs = make(map[revisionDbCacheKey]SqlDatabase)
-	c.initialDbStates = make(map[doltdb.DataCacheKey]map[string]InitialDbState)
+	c.revisionDbs = make(map[revisionDbCacheKey]*revisionDbCacheEntry)
+	c.initialDbStates = make(map[doltdb.DataCacheKey]map[string]cachedInitialDbState)
+	c.revisionRoots = make(map[string]doltdb.DataCacheKey)
+	c.collations = make(map[string]sql.CollationID)
 }
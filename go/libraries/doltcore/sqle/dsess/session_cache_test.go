@@ -0,0 +1,970 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dsess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/expression"
+	gmstypes "github.com/dolthub/go-mysql-server/sql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var (
+	staleKey = doltdb.DataCacheKey{Hash: hash.Of([]byte("stale"))}
+	freshKey = doltdb.DataCacheKey{Hash: hash.Of([]byte("fresh"))}
+)
+
+func TestSessionCacheInvalidateRoot(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+	for _, key := range []doltdb.DataCacheKey{staleKey, freshKey} {
+		c.CacheTable(key, "t1", nil)
+		c.CacheTableIndexes(key, "t1", []sql.Index{})
+		c.CacheViews(key, []sql.ViewDefinition{{Name: "v1"}})
+	}
+
+	c.InvalidateRoot(staleKey)
+
+	_, ok := c.GetCachedTable(staleKey, "t1")
+	assert.False(t, ok)
+	_, ok = c.GetTableIndexesCache(staleKey, "t1")
+	assert.False(t, ok)
+	_, ok = c.GetCachedViewDefinition(staleKey, "v1")
+	assert.False(t, ok)
+	assert.False(t, c.ViewsCached(staleKey))
+
+	_, ok = c.GetCachedTable(freshKey, "t1")
+	assert.True(t, ok)
+	_, ok = c.GetTableIndexesCache(freshKey, "t1")
+	assert.True(t, ok)
+	_, ok = c.GetCachedViewDefinition(freshKey, "v1")
+	assert.True(t, ok)
+}
+
+func TestDatabaseCacheInvalidateRoot(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+	c.CacheInitialDbState(staleKey, "mydb", InitialDbState{})
+	c.CacheInitialDbState(freshKey, "mydb", InitialDbState{})
+	c.sessionVars["stale"] = sessionVarCacheKey{root: staleKey, head: "main"}
+	c.sessionVars["fresh"] = sessionVarCacheKey{root: freshKey, head: "main"}
+
+	c.InvalidateRoot(staleKey)
+
+	_, ok := c.GetCachedInitialDbState(staleKey, "mydb")
+	assert.False(t, ok)
+	_, ok = c.GetCachedInitialDbState(freshKey, "mydb")
+	assert.True(t, ok)
+
+	_, ok = c.sessionVars["stale"]
+	assert.False(t, ok)
+	_, ok = c.sessionVars["fresh"]
+	assert.True(t, ok)
+}
+
+func TestDatabaseCacheCloneInto(t *testing.T) {
+	src := newDatabaseCache(CacheConfig{})
+	src.CacheInitialDbState(freshKey, "mydb", InitialDbState{})
+	src.sessionVars["mydb"] = sessionVarCacheKey{root: freshKey, head: "main"}
+
+	dst := newDatabaseCache(CacheConfig{})
+	dst.CacheInitialDbState(staleKey, "otherdb", InitialDbState{})
+	src.CloneInto(dst)
+
+	_, ok := dst.GetCachedInitialDbState(freshKey, "mydb")
+	assert.True(t, ok)
+	_, ok = dst.GetCachedInitialDbState(staleKey, "otherdb")
+	assert.False(t, ok)
+	assert.Equal(t, src.sessionVars, dst.sessionVars)
+
+	// changes to the clone must not be visible in the original
+	dst.CacheInitialDbState(freshKey, "mydb/branch", InitialDbState{})
+	delete(dst.sessionVars, "mydb")
+	_, ok = src.GetCachedInitialDbState(freshKey, "mydb/branch")
+	assert.False(t, ok)
+	_, ok = src.sessionVars["mydb"]
+	assert.True(t, ok)
+}
+
+// testRevisionDb is a SqlDatabase that implements only the methods used by DatabaseCache
+type testRevisionDb struct {
+	SqlDatabase
+	revisionQualifiedName string
+	requestedName         string
+}
+
+func (db testRevisionDb) RevisionQualifiedName() string {
+	return db.revisionQualifiedName
+}
+
+func (db testRevisionDb) RequestedName() string {
+	return db.requestedName
+}
+
+func TestDatabaseCacheRevisionDbCasing(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+	c.CacheRevisionDb(testRevisionDb{revisionQualifiedName: "MyDb/main", requestedName: "mydb/main"})
+
+	db, ok := c.GetCachedRevisionDb("MYDB/main", "mydb/main")
+	assert.True(t, ok)
+	assert.Equal(t, "mydb/main", db.RequestedName())
+
+	_, ok = c.GetCachedRevisionDb("mydb/main", "mydb/main")
+	assert.True(t, ok)
+
+	// the requested name is the name reported back to the client, so it must match exactly
+	_, ok = c.GetCachedRevisionDb("mydb/main", "MyDb/main")
+	assert.False(t, ok)
+}
+
+func TestDatabaseCacheRevisionDbRetainsRecentlyUsed(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+	c.CacheRevisionDb(testRevisionDb{revisionQualifiedName: "mydb/hot", requestedName: "mydb/hot"})
+
+	for i := 0; i < maxCachedKeys*4; i++ {
+		name := fmt.Sprintf("mydb/branch%d", i)
+		c.CacheRevisionDb(testRevisionDb{revisionQualifiedName: name, requestedName: name})
+
+		_, ok := c.GetCachedRevisionDb("mydb/hot", "mydb/hot")
+		require.True(t, ok, "hot database evicted after %d inserts", i+1)
+	}
+
+	assert.LessOrEqual(t, len(c.revisionDbs), maxCachedKeys)
+	_, ok := c.GetCachedRevisionDb("mydb/branch0", "mydb/branch0")
+	assert.False(t, ok)
+}
+
+func TestDatabaseCacheRevisionDbPerDatabaseCap(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+	c.CacheRevisionDb(testRevisionDb{revisionQualifiedName: "quiet/main", requestedName: "quiet/main"})
+
+	for i := 0; i < maxCachedKeys*2; i++ {
+		name := fmt.Sprintf("noisy/branch%d", i)
+		c.CacheRevisionDb(testRevisionDb{revisionQualifiedName: name, requestedName: name})
+	}
+
+	assert.Equal(t, MaxCachedRevisionDbsPerDatabase, c.countRevisionDbs("noisy"))
+	_, ok := c.GetCachedRevisionDb("quiet/main", "quiet/main")
+	assert.True(t, ok, "revision dbs for one database must not evict those of another")
+}
+
+func TestCacheStats(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+	for i := 0; i <= maxCachedKeys+1; i++ {
+		c.CacheTable(doltdb.DataCacheKey{Hash: hash.Of([]byte(fmt.Sprintf("root%d", i)))}, "t1", nil)
+	}
+	c.InvalidateRoot(freshKey)
+	c.ClearTableCache()
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(maxCachedKeys+1), stats.CapacityEvictions)
+	assert.Equal(t, uint64(2), stats.ExplicitInvalidations)
+
+	dbc := newDatabaseCache(CacheConfig{})
+	for i := 0; i < MaxCachedRevisionDbsPerDatabase+3; i++ {
+		name := fmt.Sprintf("mydb/branch%d", i)
+		dbc.CacheRevisionDb(testRevisionDb{revisionQualifiedName: name, requestedName: name})
+	}
+	dbc.Clear()
+
+	stats = dbc.Stats()
+	assert.Equal(t, uint64(3), stats.CapacityEvictions)
+	assert.Equal(t, uint64(1), stats.ExplicitInvalidations)
+}
+
+func TestSessionCacheLazyTable(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+
+	loads := 0
+	c.CacheLazyTable(freshKey, "t1", func() (sql.Table, error) {
+		loads++
+		return nil, nil
+	})
+	c.CacheLazyTable(freshKey, "broken", func() (sql.Table, error) {
+		return nil, fmt.Errorf("cannot load table")
+	})
+	assert.Equal(t, 0, loads)
+
+	for i := 0; i < 3; i++ {
+		_, ok := c.GetCachedTable(freshKey, "T1")
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 1, loads)
+
+	_, ok := c.GetCachedTable(freshKey, "broken")
+	assert.False(t, ok)
+	assert.NotContains(t, c.GetCachedTablesForKey(freshKey), "broken")
+	assert.Contains(t, c.GetCachedTablesForKey(freshKey), "t1")
+}
+
+func TestInvalidateCachedTableAcrossSessions(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("invalidate across sessions"))}
+	c1, c2 := newSessionCache(CacheConfig{}), newSessionCache(CacheConfig{})
+	for _, c := range []*SessionCache{c1, c2} {
+		c.CacheTable(key, "t1", nil)
+		c.CacheTable(key, "t2", nil)
+		c.CacheTableIndexes(key, "t1", nil)
+	}
+
+	InvalidateCachedTable(key, "T1")
+
+	for _, c := range []*SessionCache{c1, c2} {
+		_, ok := c.GetCachedTable(key, "t1")
+		assert.False(t, ok)
+		_, ok = c.GetTableIndexesCache(key, "t1")
+		assert.False(t, ok)
+		_, ok = c.GetCachedTable(key, "t2")
+		assert.True(t, ok)
+	}
+
+	// entries cached after the invalidation are unaffected by it
+	c1.CacheTable(key, "t1", nil)
+	_, ok := c1.GetCachedTable(key, "t1")
+	assert.True(t, ok)
+}
+
+func TestSessionCacheCheckConstraints(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("check constraints"))}
+	c := newSessionCache(CacheConfig{})
+	checks := []sql.CheckConstraint{{Name: "chk1", Enforced: true}}
+
+	_, ok := c.GetCheckConstraintsCache(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheCheckConstraints(key, "T1", checks)
+	cached, ok := c.GetCheckConstraintsCache(key, "t1")
+	require.True(t, ok)
+	assert.Equal(t, checks, cached)
+
+	InvalidateCachedTable(key, "t1")
+	_, ok = c.GetCheckConstraintsCache(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheCheckConstraints(key, "t1", checks)
+	c.InvalidateRoot(key)
+	_, ok = c.GetCheckConstraintsCache(key, "t1")
+	assert.False(t, ok)
+}
+
+func TestDatabaseCacheInvalidateDatabase(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+	for _, name := range []string{"mydb/main", "MyDb/feature", "otherdb/main"} {
+		c.CacheRevisionDb(testRevisionDb{revisionQualifiedName: name, requestedName: name})
+	}
+	c.CacheInitialDbStates(freshKey, map[string]InitialDbState{
+		"mydb":         {},
+		"mydb/feature": {},
+		"otherdb":      {},
+	})
+	c.CacheInitialDbState(staleKey, "mydb/main", InitialDbState{})
+	c.sessionVars["mydb"] = sessionVarCacheKey{root: freshKey, head: "main"}
+	c.sessionVars["otherdb"] = sessionVarCacheKey{root: freshKey, head: "main"}
+
+	c.InvalidateDatabase("MYDB")
+
+	_, ok := c.GetCachedRevisionDb("mydb/main", "mydb/main")
+	assert.False(t, ok)
+	_, ok = c.GetCachedRevisionDb("mydb/feature", "MyDb/feature")
+	assert.False(t, ok)
+	_, ok = c.GetCachedRevisionDb("otherdb/main", "otherdb/main")
+	assert.True(t, ok)
+
+	for _, name := range []string{"mydb", "mydb/feature"} {
+		_, ok = c.GetCachedInitialDbState(freshKey, name)
+		assert.False(t, ok, name)
+	}
+	_, ok = c.GetCachedInitialDbState(freshKey, "otherdb")
+	assert.True(t, ok)
+	assert.NotContains(t, c.initialDbStates, staleKey)
+
+	assert.NotContains(t, c.sessionVars, "mydb")
+	assert.Contains(t, c.sessionVars, "otherdb")
+}
+
+func TestSessionCacheCacheTables(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+	c.CacheTable(freshKey, "existing", nil)
+	c.CacheTables(freshKey, map[string]sql.Table{"T1": nil, "t2": nil})
+
+	for _, name := range []string{"existing", "t1", "T2"} {
+		_, ok := c.GetCachedTable(freshKey, name)
+		assert.True(t, ok, name)
+	}
+	assert.Len(t, c.GetCachedTablesForKey(freshKey), 3)
+}
+
+func TestDatabaseCacheInitialDbStateWithMeta(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+	_, _, ok := c.GetCachedInitialDbStateWithMeta(freshKey, "mydb")
+	assert.False(t, ok)
+
+	before := time.Now()
+	c.CacheInitialDbState(freshKey, "mydb", InitialDbState{})
+	after := time.Now()
+
+	_, cachedAt, ok := c.GetCachedInitialDbStateWithMeta(freshKey, "mydb")
+	require.True(t, ok)
+	assert.False(t, cachedAt.Before(before))
+	assert.False(t, cachedAt.After(after))
+
+	// lookups don't change the recorded time
+	_, ok = c.GetCachedInitialDbState(freshKey, "mydb")
+	assert.True(t, ok)
+	_, again, ok := c.GetCachedInitialDbStateWithMeta(freshKey, "mydb")
+	require.True(t, ok)
+	assert.Equal(t, cachedAt, again)
+}
+
+func TestDatabaseCacheRevisionRoots(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+
+	assert.False(t, c.CacheRevisionRoot("mydb/main", RevisionTypeBranch, freshKey))
+	assert.True(t, c.CacheRevisionRoot("mydb/v1", RevisionTypeTag, freshKey))
+	assert.True(t, c.CacheRevisionRoot("MyDb/abc123", RevisionTypeCommit, staleKey))
+	assert.True(t, c.CacheRevisionRoot("otherdb/abc123", RevisionTypeCommit, staleKey))
+
+	_, ok := c.GetCachedRevisionRoot("mydb/main")
+	assert.False(t, ok)
+	key, ok := c.GetCachedRevisionRoot("mydb/v1")
+	require.True(t, ok)
+	assert.Equal(t, freshKey, key)
+	key, ok = c.GetCachedRevisionRoot("mydb/ABC123")
+	require.True(t, ok)
+	assert.Equal(t, staleKey, key)
+
+	c.InvalidateDatabase("mydb")
+	_, ok = c.GetCachedRevisionRoot("mydb/v1")
+	assert.False(t, ok)
+	_, ok = c.GetCachedRevisionRoot("otherdb/abc123")
+	assert.True(t, ok)
+}
+
+func TestDatabaseCacheCollations(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+
+	_, ok := c.GetCachedDatabaseCollation("mydb")
+	assert.False(t, ok)
+
+	c.CacheDatabaseCollation("MyDb", sql.Collation_utf8mb4_0900_bin)
+	c.CacheDatabaseCollation("otherdb", sql.Collation_utf8mb4_general_ci)
+
+	collation, ok := c.GetCachedDatabaseCollation("mydb")
+	require.True(t, ok)
+	assert.Equal(t, sql.Collation_utf8mb4_0900_bin, collation)
+	collation, ok = c.GetCachedDatabaseCollation("mydb/main")
+	require.True(t, ok)
+	assert.Equal(t, sql.Collation_utf8mb4_0900_bin, collation)
+
+	c.InvalidateDatabase("mydb")
+	_, ok = c.GetCachedDatabaseCollation("mydb")
+	assert.False(t, ok)
+	_, ok = c.GetCachedDatabaseCollation("otherdb")
+	assert.True(t, ok)
+}
+
+func TestDatabaseCacheInvalidateSessionVars(t *testing.T) {
+	c := newDatabaseCache(CacheConfig{})
+	c.sessionVars["mydb"] = sessionVarCacheKey{root: freshKey, head: "main"}
+	c.sessionVars["otherdb"] = sessionVarCacheKey{root: freshKey, head: "main"}
+
+	c.InvalidateSessionVars("MyDb/feature")
+
+	assert.NotContains(t, c.sessionVars, "mydb")
+	assert.Contains(t, c.sessionVars, "otherdb")
+}
+
+func TestSessionCacheGetAllCachedViews(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+	_, ok := c.GetAllCachedViews(freshKey)
+	assert.False(t, ok)
+
+	c.CacheViews(freshKey, nil)
+	views, ok := c.GetAllCachedViews(freshKey)
+	assert.True(t, ok, "an empty set of views is still cached")
+	assert.Empty(t, views)
+
+	c.CacheViews(freshKey, []sql.ViewDefinition{{Name: "V1"}, {Name: "v2"}})
+	views, ok = c.GetAllCachedViews(freshKey)
+	require.True(t, ok)
+	assert.Len(t, views, 2)
+	assert.Contains(t, views, "v1")
+
+	// the result is a copy
+	delete(views, "v1")
+	_, ok = c.GetCachedViewDefinition(freshKey, "v1")
+	assert.True(t, ok)
+}
+
+func TestSessionCacheGetAllCachedIndexes(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("all cached indexes"))}
+	c := newSessionCache(CacheConfig{})
+	_, ok := c.GetAllCachedIndexes(key)
+	assert.False(t, ok)
+
+	c.CacheTableIndexes(key, "T1", []sql.Index{nil, nil})
+	c.CacheTableIndexes(key, "t2", nil)
+	c.CacheTableIndexes(key, "t3", nil)
+	InvalidateCachedTable(key, "t3")
+
+	indexes, ok := c.GetAllCachedIndexes(key)
+	require.True(t, ok)
+	assert.Len(t, indexes, 2)
+	assert.Len(t, indexes["t1"], 2)
+	assert.Contains(t, indexes, "t2")
+
+	// the result is a copy
+	delete(indexes, "t1")
+	_, ok = c.GetTableIndexesCache(key, "t1")
+	assert.True(t, ok)
+}
+
+// fakeIndex is a sql.Index with only the methods needed to derive its columns
+type fakeIndex struct {
+	sql.Index
+	id, table string
+	exprs     []string
+}
+
+func (i fakeIndex) ID() string            { return i.id }
+func (i fakeIndex) Table() string         { return i.table }
+func (i fakeIndex) Expressions() []string { return i.exprs }
+
+func TestSessionCacheIndexColumns(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("index columns"))}
+	c := newSessionCache(CacheConfig{})
+	_, ok := c.GetIndexColumns(key, "t", "idx")
+	assert.False(t, ok)
+
+	c.CacheTableIndexes(key, "t", []sql.Index{
+		fakeIndex{id: "PRIMARY", table: "t", exprs: []string{"t.pk"}},
+		fakeIndex{id: "idx_ab", table: "t", exprs: []string{"t.b", "t.a"}},
+	})
+
+	cols, ok := c.GetIndexColumns(key, "T", "IDX_AB")
+	require.True(t, ok)
+	assert.Equal(t, []string{"b", "a"}, cols)
+	cols, ok = c.GetIndexColumns(key, "t", "primary")
+	require.True(t, ok)
+	assert.Equal(t, []string{"pk"}, cols)
+	_, ok = c.GetIndexColumns(key, "t", "missing")
+	assert.False(t, ok)
+
+	// the result is a copy
+	cols[0] = "changed"
+	cols, _ = c.GetIndexColumns(key, "t", "primary")
+	assert.Equal(t, []string{"pk"}, cols)
+
+	InvalidateCachedTable(key, "t")
+	_, ok = c.GetIndexColumns(key, "t", "idx_ab")
+	assert.False(t, ok)
+}
+
+func TestSessionCacheVerification(t *testing.T) {
+	defer SetVerifySessionCache(VerifyingSessionCache())
+
+	recomputeCount := func(table string) func() (uint64, bool, error) {
+		return func() (uint64, bool, error) {
+			return 5, table != "missing", nil
+		}
+	}
+	recomputeView := func() (sql.ViewDefinition, bool, error) {
+		return sql.ViewDefinition{Name: "v1", TextDefinition: "select 2"}, true, nil
+	}
+
+	// nothing is verified unless verification is on
+	SetVerifySessionCache(false)
+	assert.NotPanics(t, func() { VerifyCachedRowCount("t2", 6, recomputeCount("t2")) })
+
+	SetVerifySessionCache(true)
+	assert.NotPanics(t, func() { VerifyCachedRowCount("t1", 5, recomputeCount("t1")) })
+	assert.Panics(t, func() { VerifyCachedRowCount("t2", 6, recomputeCount("t2")) })
+	assert.Panics(t, func() { VerifyCachedRowCount("missing", 5, recomputeCount("missing")) })
+	assert.Panics(t, func() {
+		VerifyCachedView("v1", sql.ViewDefinition{Name: "v1", TextDefinition: "select 1"}, recomputeView)
+	})
+
+	// values that can't be recomputed aren't verified
+	assert.NotPanics(t, func() {
+		VerifyCachedRowCount("t2", 6, func() (uint64, bool, error) {
+			return 0, false, errors.New("unavailable")
+		})
+	})
+}
+
+func TestSessionCacheEvictsRootsTogether(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+	assertSameRoots := func() {
+		tableRoots := make(map[doltdb.DataCacheKey]struct{})
+		for k := range c.tables {
+			tableRoots[k] = struct{}{}
+		}
+		for k := range c.indexes {
+			assert.Contains(t, tableRoots, k)
+		}
+		for k := range c.views {
+			assert.Contains(t, tableRoots, k)
+		}
+		assert.Len(t, c.indexes, len(c.tables))
+		assert.Len(t, c.views, len(c.tables))
+	}
+
+	for i := 0; i < maxCachedKeys*3; i++ {
+		key := doltdb.DataCacheKey{Hash: hash.Of([]byte(fmt.Sprintf("root%d", i)))}
+		c.CacheTable(key, "t1", nil)
+		c.CacheTableIndexes(key, "t1", nil)
+		c.CacheViews(key, nil)
+		assertSameRoots()
+		assert.LessOrEqual(t, len(c.tables), maxCachedKeys+1)
+	}
+
+	// filling one of the caches evicts the others too
+	c = newSessionCache(CacheConfig{})
+	first := doltdb.DataCacheKey{Hash: hash.Of([]byte("first"))}
+	c.CacheTable(first, "t1", nil)
+	c.CacheTableIndexes(first, "t1", nil)
+	for i := 0; i <= maxCachedKeys+1; i++ {
+		c.CacheRowCount(doltdb.DataCacheKey{Hash: hash.Of([]byte(fmt.Sprintf("count%d", i)))}, "t1", 1)
+	}
+	_, ok := c.GetCachedTable(first, "t1")
+	assert.False(t, ok)
+	_, ok = c.GetTableIndexesCache(first, "t1")
+	assert.False(t, ok)
+	assertSameRoots()
+}
+
+func TestSessionCacheTableSchemaHash(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("schema hash"))}
+	schHash := hash.Of([]byte("schema"))
+	c := newSessionCache(CacheConfig{})
+
+	c.CacheTableWithSchemaHash(key, "T1", nil, schHash)
+	c.CacheTable(key, "t2", nil)
+
+	_, h, ok := c.GetCachedTableWithSchemaHash(key, "t1")
+	require.True(t, ok)
+	assert.Equal(t, schHash, h)
+	_, ok = c.GetCachedTable(key, "t1")
+	assert.True(t, ok)
+
+	_, h, ok = c.GetCachedTableWithSchemaHash(key, "t2")
+	require.True(t, ok)
+	assert.True(t, h.IsEmpty())
+
+	_, _, ok = c.GetCachedTableWithSchemaHash(key, "t3")
+	assert.False(t, ok)
+}
+
+type testPartition string
+
+func (p testPartition) Key() []byte {
+	return []byte(p)
+}
+
+func TestSessionCacheTablePartitions(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("partitions"))}
+	c := newSessionCache(CacheConfig{})
+	parts := []sql.Partition{testPartition("p1"), testPartition("p2")}
+
+	_, ok := c.GetTablePartitionsCache(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheTablePartitions(key, "T1", parts)
+	cached, ok := c.GetTablePartitionsCache(key, "t1")
+	require.True(t, ok)
+	assert.Equal(t, parts, cached)
+
+	InvalidateCachedTable(key, "t1")
+	_, ok = c.GetTablePartitionsCache(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheTablePartitions(key, "t1", parts)
+	c.InvalidateRoot(key)
+	_, ok = c.GetTablePartitionsCache(key, "t1")
+	assert.False(t, ok)
+}
+
+func TestSessionCacheGeneratedColumns(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("generated columns"))}
+	c := newSessionCache(CacheConfig{})
+	exprs := map[string]sql.Expression{"c2": expression.NewLiteral(int64(1), gmstypes.Int64)}
+
+	_, ok := c.GetGeneratedColumnsCache(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheGeneratedColumns(key, "T1", exprs)
+	cached, ok := c.GetGeneratedColumnsCache(key, "t1")
+	require.True(t, ok)
+	assert.Equal(t, exprs, cached)
+
+	InvalidateCachedTable(key, "t1")
+	_, ok = c.GetGeneratedColumnsCache(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheGeneratedColumns(key, "t1", exprs)
+	c.InvalidateRoot(key)
+	_, ok = c.GetGeneratedColumnsCache(key, "t1")
+	assert.False(t, ok)
+}
+
+func TestSessionCacheColumnDefaults(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("column defaults"))}
+	c := newSessionCache(CacheConfig{})
+	defaults := map[string]*sql.ColumnDefaultValue{"c2": {}}
+
+	_, ok := c.GetCachedColumnDefaults(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheColumnDefaults(key, "T1", defaults)
+	cached, ok := c.GetCachedColumnDefaults(key, "t1")
+	require.True(t, ok)
+	assert.Equal(t, defaults, cached)
+
+	InvalidateCachedTable(key, "t1")
+	_, ok = c.GetCachedColumnDefaults(key, "t1")
+	assert.False(t, ok)
+
+	c.CacheColumnDefaults(key, "t1", defaults)
+	c.ClearTableCache()
+	_, ok = c.GetCachedColumnDefaults(key, "t1")
+	assert.False(t, ok)
+}
+
+func TestSessionCachePinnedRootsAreNotEvicted(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+	active := doltdb.DataCacheKey{Hash: hash.Of([]byte("active"))}
+	require.True(t, c.Pin(active))
+	c.CacheTable(active, "t1", nil)
+	c.CacheTableIndexes(active, "t1", nil)
+
+	for i := 0; i <= maxCachedKeys*2; i++ {
+		c.CacheTable(doltdb.DataCacheKey{Hash: hash.Of([]byte(fmt.Sprintf("root%d", i)))}, "t1", nil)
+	}
+	_, ok := c.GetCachedTable(active, "t1")
+	assert.True(t, ok)
+	_, ok = c.GetTableIndexesCache(active, "t1")
+	assert.True(t, ok)
+	assert.LessOrEqual(t, len(c.tables), maxCachedKeys+1)
+
+	c.Unpin(active)
+	for i := 0; i <= maxCachedKeys; i++ {
+		c.CacheTable(doltdb.DataCacheKey{Hash: hash.Of([]byte(fmt.Sprintf("other%d", i)))}, "t1", nil)
+	}
+	_, ok = c.GetCachedTable(active, "t1")
+	assert.False(t, ok)
+
+	// explicit invalidation still applies to pinned roots
+	require.True(t, c.Pin(active))
+	c.CacheTable(active, "t1", nil)
+	c.InvalidateRoot(active)
+	_, ok = c.GetCachedTable(active, "t1")
+	assert.False(t, ok)
+
+	// the number of pinned roots is bounded
+	for i := 1; i < maxPinnedRoots; i++ {
+		require.True(t, c.Pin(doltdb.DataCacheKey{Hash: hash.Of([]byte(fmt.Sprintf("pinned%d", i)))}))
+	}
+	assert.True(t, c.Pin(active))
+	extra := doltdb.DataCacheKey{Hash: hash.Of([]byte("extra"))}
+	assert.False(t, c.Pin(extra))
+	c.Unpin(active)
+	assert.True(t, c.Pin(extra))
+}
+
+func TestSessionCacheSystemTableSchemas(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("system tables"))}
+	otherKey := doltdb.DataCacheKey{Hash: hash.Of([]byte("other system tables"))}
+	c := newSessionCache(CacheConfig{})
+	sch := sql.Schema{{Name: "commit_hash", Type: gmstypes.Text, Source: "dolt_log"}}
+
+	_, ok := c.GetSystemTableSchemaCache(key, "dolt_log")
+	assert.False(t, ok)
+
+	c.CacheSystemTableSchema(key, "DOLT_LOG", sch)
+	cached, ok := c.GetSystemTableSchemaCache(key, "dolt_log")
+	require.True(t, ok)
+	assert.Equal(t, sch, cached)
+	_, ok = c.GetSystemTableSchemaCache(otherKey, "dolt_log")
+	assert.False(t, ok)
+
+	c.InvalidateRoot(key)
+	_, ok = c.GetSystemTableSchemaCache(key, "dolt_log")
+	assert.False(t, ok)
+}
+
+func TestSessionCacheSeedFrom(t *testing.T) {
+	current := doltdb.DataCacheKey{Hash: hash.Of([]byte("current"))}
+	stale := doltdb.DataCacheKey{Hash: hash.Of([]byte("stale"))}
+	view := sql.ViewDefinition{Name: "v1", TextDefinition: "select 1"}
+
+	prev := newSessionCache(CacheConfig{})
+	for _, key := range []doltdb.DataCacheKey{current, stale} {
+		prev.CacheTable(key, "t1", nil)
+		prev.CacheTableIndexes(key, "t1", nil)
+		prev.CacheViews(key, []sql.ViewDefinition{view})
+		prev.CacheRowCount(key, "t1", 10)
+	}
+
+	c := newSessionCache(CacheConfig{})
+	c.CacheRowCount(current, "t1", 20)
+	c.SeedFrom(prev, map[doltdb.DataCacheKey]bool{current: true})
+
+	_, ok := c.GetCachedTable(current, "t1")
+	assert.True(t, ok)
+	_, ok = c.GetTableIndexesCache(current, "t1")
+	assert.True(t, ok)
+	cachedView, ok := c.GetCachedViewDefinition(current, "v1")
+	require.True(t, ok)
+	assert.Equal(t, view, cachedView)
+	// entries already in the cache win
+	count, ok := c.GetRowCountCache(current, "t1")
+	require.True(t, ok)
+	assert.Equal(t, uint64(20), count)
+
+	_, ok = c.GetCachedTable(stale, "t1")
+	assert.False(t, ok)
+	_, ok = c.GetTableIndexesCache(stale, "t1")
+	assert.False(t, ok)
+
+	// the seeded cache doesn't share its per-root maps with the source
+	prev.CacheTable(current, "t2", nil)
+	_, ok = c.GetCachedTable(current, "t2")
+	assert.False(t, ok)
+}
+
+func TestSessionCacheCachedKeys(t *testing.T) {
+	c := newSessionCache(CacheConfig{})
+	assert.Empty(t, c.CachedKeys())
+
+	tables := doltdb.DataCacheKey{Hash: hash.Of([]byte("tables"))}
+	indexes := doltdb.DataCacheKey{Hash: hash.Of([]byte("indexes"))}
+	views := doltdb.DataCacheKey{Hash: hash.Of([]byte("views"))}
+	c.CacheTable(tables, "t1", nil)
+	c.CacheTableIndexes(tables, "t1", nil)
+	c.CacheTableIndexes(indexes, "t1", nil)
+	c.CacheViews(views, nil)
+
+	assert.ElementsMatch(t, []doltdb.DataCacheKey{tables, indexes, views}, c.CachedKeys())
+
+	c.InvalidateRoot(indexes)
+	assert.ElementsMatch(t, []doltdb.DataCacheKey{tables, views}, c.CachedKeys())
+}
+
+func TestSessionCacheEvictTablesWhere(t *testing.T) {
+	key1 := doltdb.DataCacheKey{Hash: hash.Of([]byte("evict where 1"))}
+	key2 := doltdb.DataCacheKey{Hash: hash.Of([]byte("evict where 2"))}
+	c := newSessionCache(CacheConfig{})
+	for _, key := range []doltdb.DataCacheKey{key1, key2} {
+		c.CacheTable(key, "Tmp_A", nil)
+		c.CacheTable(key, "keep", nil)
+		c.CacheTableIndexes(key, "tmp_a", nil)
+		c.CacheRowCount(key, "tmp_a", 1)
+		c.CacheRowCount(key, "keep", 1)
+	}
+
+	evicted := c.EvictTablesWhere(func(key doltdb.DataCacheKey, table string) bool {
+		return strings.HasPrefix(table, "tmp_") && key == key1
+	})
+	assert.Equal(t, 3, evicted)
+	assert.Equal(t, uint64(1), c.Stats().ExplicitInvalidations)
+
+	_, ok := c.GetCachedTable(key1, "tmp_a")
+	assert.False(t, ok)
+	_, ok = c.GetTableIndexesCache(key1, "tmp_a")
+	assert.False(t, ok)
+	_, ok = c.GetRowCountCache(key1, "tmp_a")
+	assert.False(t, ok)
+	_, ok = c.GetCachedTable(key1, "keep")
+	assert.True(t, ok)
+	_, ok = c.GetCachedTable(key2, "tmp_a")
+	assert.True(t, ok)
+
+	// nothing matching, nothing counted
+	assert.Equal(t, 0, c.EvictTablesWhere(func(doltdb.DataCacheKey, string) bool { return false }))
+	assert.Equal(t, uint64(1), c.Stats().ExplicitInvalidations)
+}
+
+func TestSessionCacheAutoIncrement(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("auto increment"))}
+	c := newSessionCache(CacheConfig{})
+	_, ok := c.GetCachedAutoIncrement(key, "t")
+	assert.False(t, ok)
+
+	c.CacheAutoIncrement(key, "T", 10)
+	value, ok := c.GetCachedAutoIncrement(key, "t")
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), value)
+
+	c.InvalidateAutoIncrement(key, "t")
+	_, ok = c.GetCachedAutoIncrement(key, "t")
+	assert.False(t, ok)
+	// invalidating a table with no entry does nothing
+	c.InvalidateAutoIncrement(key, "missing")
+
+	c.CacheAutoIncrement(key, "t", 11)
+	InvalidateCachedTable(key, "t")
+	_, ok = c.GetCachedAutoIncrement(key, "t")
+	assert.False(t, ok)
+}
+
+// fakePKTable is a sql.PrimaryKeyTable with only the methods needed to derive its primary key ordinals
+type fakePKTable struct {
+	sql.Table
+	ordinals []int
+}
+
+func (t fakePKTable) PrimaryKeySchema() sql.PrimaryKeySchema {
+	return sql.PrimaryKeySchema{PkOrdinals: t.ordinals}
+}
+
+func TestSessionCachePKOrdinals(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("pk ordinals"))}
+	c := newSessionCache(CacheConfig{})
+	_, ok := c.GetCachedPKOrdinals(key, "t")
+	assert.False(t, ok)
+
+	c.CachePKOrdinals(key, "T", []int{2, 0})
+	ordinals, ok := c.GetCachedPKOrdinals(key, "t")
+	require.True(t, ok)
+	assert.Equal(t, []int{2, 0}, ordinals)
+	// the slice returned is a copy
+	ordinals[0] = 5
+	ordinals, _ = c.GetCachedPKOrdinals(key, "t")
+	assert.Equal(t, []int{2, 0}, ordinals)
+
+	// caching a table caches its primary key ordinals
+	c.CacheTable(key, "t2", fakePKTable{ordinals: []int{1}})
+	c.CacheTables(key, map[string]sql.Table{"T3": fakePKTable{ordinals: []int{0, 1}}})
+	ordinals, ok = c.GetCachedPKOrdinals(key, "t2")
+	require.True(t, ok)
+	assert.Equal(t, []int{1}, ordinals)
+	ordinals, ok = c.GetCachedPKOrdinals(key, "t3")
+	require.True(t, ok)
+	assert.Equal(t, []int{0, 1}, ordinals)
+
+	// and they're invalidated with the table
+	InvalidateCachedTable(key, "t2")
+	_, ok = c.GetCachedPKOrdinals(key, "t2")
+	assert.False(t, ok)
+	c.ClearTableCache()
+	_, ok = c.GetCachedPKOrdinals(key, "t3")
+	assert.False(t, ok)
+}
+
+func TestCacheCapacity(t *testing.T) {
+	rootKey := func(i int) doltdb.DataCacheKey {
+		return doltdb.DataCacheKey{Hash: hash.Of([]byte(fmt.Sprintf("capacity %d", i)))}
+	}
+
+	c := newSessionCache(CacheConfig{Views: 256, Tables: 2})
+	for i := 0; i < 100; i++ {
+		c.CacheViews(rootKey(i), nil)
+	}
+	assert.Len(t, c.views, 100)
+	assert.Zero(t, c.Stats().CapacityEvictions)
+
+	// the table limit is much lower, and evicts every root when it's exceeded
+	for i := 0; i < 3; i++ {
+		c.CacheTable(rootKey(i), "t", nil)
+	}
+	assert.Len(t, c.tables, 3)
+	c.CacheTable(rootKey(3), "t", nil)
+	assert.Len(t, c.tables, 1)
+	assert.Empty(t, c.views)
+
+	dc := newDatabaseCache(CacheConfig{Collations: 2})
+	for i := 0; i < 3; i++ {
+		dc.CacheDatabaseCollation(fmt.Sprintf("db%d", i), sql.Collation_utf8mb4_0900_bin)
+	}
+	assert.Len(t, dc.collations, 3)
+	dc.CacheDatabaseCollation("db3", sql.Collation_utf8mb4_0900_bin)
+	assert.Len(t, dc.collations, 1)
+
+	// a capacity that isn't set is the default
+	c = newSessionCache(CacheConfig{})
+	for i := 0; i <= maxCachedKeys; i++ {
+		c.CacheTable(rootKey(i), "t", nil)
+	}
+	assert.Len(t, c.tables, maxCachedKeys+1)
+}
+
+func TestBranchStateCacheConfig(t *testing.T) {
+	config := CacheConfig{Tables: 2, IdleExpiry: time.Minute}
+	dbState := newEmptyDatabaseSessionState(config)
+	assert.Equal(t, config, dbState.NewEmptyBranchState("main").SessionCache().config)
+}
+
+func TestSessionCacheIdleExpiry(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("idle expiry"))}
+
+	c := newSessionCache(CacheConfig{IdleExpiry: time.Minute})
+	c.CacheRowCount(key, "t", 1)
+	c.CacheViews(key, nil)
+	_, ok := c.GetRowCountCache(key, "t")
+	require.True(t, ok)
+
+	// used within the expiry
+	c.lastUsed.Store(time.Now().Add(-30 * time.Second).UnixNano())
+	_, ok = c.GetRowCountCache(key, "t")
+	require.True(t, ok)
+
+	// idle for longer than the expiry
+	c.lastUsed.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	_, ok = c.GetRowCountCache(key, "t")
+	assert.False(t, ok)
+	assert.False(t, c.ViewsCached(key))
+	assert.Equal(t, uint64(1), c.Stats().CapacityEvictions)
+
+	// without an expiry, entries are kept however long the cache is idle
+	c = newSessionCache(CacheConfig{})
+	c.CacheRowCount(key, "t", 1)
+	c.lastUsed.Store(time.Now().Add(-24 * time.Hour).UnixNano())
+	_, ok = c.GetRowCountCache(key, "t")
+	assert.True(t, ok)
+}
+
+func TestBumpCacheGeneration(t *testing.T) {
+	key := doltdb.DataCacheKey{Hash: hash.Of([]byte("cache generation"))}
+
+	c := newSessionCache(CacheConfig{})
+	c.CacheRowCount(key, "t", 1)
+	c.CacheViews(key, nil)
+
+	BumpCacheGeneration()
+	_, ok := c.GetRowCountCache(key, "t")
+	assert.False(t, ok)
+	assert.False(t, c.ViewsCached(key))
+	assert.Equal(t, uint64(1), c.Stats().ExplicitInvalidations)
+
+	// entries cached after the flush are kept until the next bump
+	c.CacheRowCount(key, "t", 2)
+	count, ok := c.GetRowCountCache(key, "t")
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), count)
+
+	// a cache created after a bump starts at the current generation
+	c = newSessionCache(CacheConfig{})
+	c.CacheRowCount(key, "t", 3)
+	_, ok = c.GetRowCountCache(key, "t")
+	assert.True(t, ok)
+	assert.Zero(t, c.Stats().ExplicitInvalidations)
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/dolthub/go-mysql-server/sql"
 
@@ -34,6 +35,9 @@ type SessionStateAdapter struct {
 	remotes  map[string]env.Remote
 	backups  map[string]env.Remote
 	branches map[string]env.BranchConfig
+	// descriptions are the branch descriptions loaded with the database, kept up to date with the ones set through this
+	// adapter
+	descriptions map[string]string
 }
 
 func (s SessionStateAdapter) SetCWBHeadRef(_ context.Context, _ ref.MarshalableRef) error {
@@ -43,12 +47,49 @@ func (s SessionStateAdapter) SetCWBHeadRef(_ context.Context, _ ref.MarshalableR
 var _ env.RepoStateReader = SessionStateAdapter{}
 var _ env.RepoStateWriter = SessionStateAdapter{}
 var _ env.RootsProvider = SessionStateAdapter{}
+var _ env.BranchConfigRemover = SessionStateAdapter{}
+var _ env.BranchDescriber = SessionStateAdapter{}
+var _ env.DefaultBranchReader = SessionStateAdapter{}
 
-func NewSessionStateAdapter(session *DoltSession, dbName string, remotes map[string]env.Remote, branches map[string]env.BranchConfig, backups map[string]env.Remote) SessionStateAdapter {
+func NewSessionStateAdapter(session *DoltSession, dbName string, remotes map[string]env.Remote, branches map[string]env.BranchConfig, backups map[string]env.Remote, descriptions map[string]string) SessionStateAdapter {
 	if branches == nil {
 		branches = make(map[string]env.BranchConfig)
 	}
-	return SessionStateAdapter{session: session, dbName: dbName, remotes: remotes, branches: branches, backups: backups}
+	// the descriptions may be shared with other sessions that loaded the same database, so each adapter updates its own
+	// copy
+	descriptionsCopy := make(map[string]string, len(descriptions))
+	for name, description := range descriptions {
+		descriptionsCopy[name] = description
+	}
+	return SessionStateAdapter{session: session, dbName: dbName, remotes: remotes, branches: branches, backups: backups, descriptions: descriptionsCopy}
+}
+
+// repoStateMu serializes the load-modify-save updates of repo state files made by SessionStateAdapter. Without it,
+// two sessions updating the same repo_state.json at once could each load it before the other saved, and one update
+// would be lost. It only coordinates the sessions of this process: a CLI command writing the file while a server runs
+// can still race with it.
+var repoStateMu sync.Mutex
+
+// updateRepoState loads the repo state of the database named, applies |update| to it and saves it if |update| returns
+// true, all while holding repoStateMu.
+func (s SessionStateAdapter) updateRepoState(update func(repoState *env.RepoState) bool) error {
+	fs, err := s.session.Provider().FileSystemForDatabase(s.dbName)
+	if err != nil {
+		return err
+	}
+
+	repoStateMu.Lock()
+	defer repoStateMu.Unlock()
+
+	repoState, err := env.LoadRepoState(fs)
+	if err != nil {
+		return err
+	}
+	if !update(repoState) {
+		return nil
+	}
+
+	return repoState.Save(fs)
 }
 
 func (s SessionStateAdapter) GetRoots(ctx context.Context) (doltdb.Roots, error) {
@@ -102,18 +143,51 @@ func (s SessionStateAdapter) GetBranches() (map[string]env.BranchConfig, error)
 func (s SessionStateAdapter) UpdateBranch(name string, new env.BranchConfig) error {
 	s.branches[name] = new
 
-	fs, err := s.session.Provider().FileSystemForDatabase(s.dbName)
+	return s.updateRepoState(func(repoState *env.RepoState) bool {
+		repoState.Branches[name] = new
+		return true
+	})
+}
+
+// RemoveBranchConfig implements env.BranchConfigRemover
+func (s SessionStateAdapter) RemoveBranchConfig(name string) error {
+	delete(s.branches, name)
+
+	return s.updateRepoState(func(repoState *env.RepoState) bool {
+		delete(repoState.Branches, name)
+		return true
+	})
+}
+
+// GetBranchDescriptions implements env.BranchDescriber. The descriptions are the ones loaded with the database,
+// updated with those set through this adapter, so reading them never touches the repo state file. Descriptions set by
+// other sessions are seen once the database is loaded again, as in a new session.
+func (s SessionStateAdapter) GetBranchDescriptions() (map[string]string, error) {
+	return s.descriptions, nil
+}
+
+// SetBranchDescription implements env.BranchDescriber
+func (s SessionStateAdapter) SetBranchDescription(name, description string) error {
+	err := s.updateRepoState(func(repoState *env.RepoState) bool {
+		return repoState.SetBranchDescription(name, description)
+	})
 	if err != nil {
 		return err
 	}
 
-	repoState, err := env.LoadRepoState(fs)
-	if err != nil {
-		return err
+	if description == "" {
+		delete(s.descriptions, name)
+	} else {
+		s.descriptions[name] = description
 	}
-	repoState.Branches[name] = new
+	return nil
+}
 
-	return repoState.Save(fs)
+// GetDefaultBranch implements env.DefaultBranchReader. The default branch is the one set with the
+// @@<db>_default_branch system variable.
+func (s SessionStateAdapter) GetDefaultBranch() (string, error) {
+	baseName, _ := SplitRevisionDbName(s.dbName)
+	return defaultBranchFromSystemVar(baseName), nil
 }
 
 func (s SessionStateAdapter) AddRemote(remote env.Remote) error {
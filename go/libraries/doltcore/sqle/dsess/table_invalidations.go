@@ -0,0 +1,91 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dsess
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+)
+
+// tableInvalidations is shared by all sessions in the process. SessionCache consults it on lookup so that a table
+// invalidated by one session is dropped from the caches of every other session at the same root.
+var tableInvalidations = &tableInvalidationRegistry{}
+
+// InvalidateCachedTable marks the table named at the root identified by |key| as dirty in the caches of all sessions.
+// Each session drops everything it cached for the pair on its next lookup of it.
+func InvalidateCachedTable(key doltdb.DataCacheKey, tableName string) {
+	tableInvalidations.invalidate(key, tableName)
+}
+
+// tableInvalidationRegistry records the sequence number of the most recent invalidation of each (root, table) pair.
+// Cache entries record the sequence number current when they were cached, and are stale if the pair they belong to
+// has been invalidated since.
+type tableInvalidationRegistry struct {
+	mu sync.RWMutex
+	// seq is incremented on every invalidation
+	seq uint64
+	// floor is the sequence number when the registry was last pruned. Since pruning forgets which pairs were
+	// invalidated, entries cached before it are all considered stale.
+	floor       uint64
+	invalidated map[doltdb.DataCacheKey]map[string]uint64
+}
+
+// current returns the sequence number to record for a cache entry being added now
+func (r *tableInvalidationRegistry) current() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.seq
+}
+
+func (r *tableInvalidationRegistry) invalidate(key doltdb.DataCacheKey, tableName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.invalidated == nil {
+		r.invalidated = make(map[doltdb.DataCacheKey]map[string]uint64)
+	}
+
+	r.seq++
+	if len(r.invalidated) > maxCachedKeys {
+		for k := range r.invalidated {
+			delete(r.invalidated, k)
+		}
+		r.floor = r.seq
+	}
+
+	tablesForKey, ok := r.invalidated[key]
+	if !ok {
+		tablesForKey = make(map[string]uint64)
+		r.invalidated[key] = tablesForKey
+	}
+	tablesForKey[strings.ToLower(tableName)] = r.seq
+}
+
+// isStale returns whether an entry for the table named, cached when the sequence number was |cachedAt|, has since
+// been invalidated. |tableName| must already be lower case.
+func (r *tableInvalidationRegistry) isStale(key doltdb.DataCacheKey, tableName string, cachedAt uint64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if cachedAt == r.seq {
+		return false
+	}
+	if cachedAt < r.floor {
+		return true
+	}
+	return r.invalidated[key][tableName] > cachedAt
+}
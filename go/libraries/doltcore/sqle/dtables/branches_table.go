@@ -22,6 +22,7 @@ import (
 	"github.com/dolthub/go-mysql-server/sql/types"
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
 	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/index"
@@ -85,6 +86,7 @@ func (bt *BranchesTable) Schema() sql.Schema {
 	if !bt.remote {
 		columns = append(columns, &sql.Column{Name: "remote", Type: types.Text, Source: tableName, PrimaryKey: false, Nullable: true})
 		columns = append(columns, &sql.Column{Name: "branch", Type: types.Text, Source: tableName, PrimaryKey: false, Nullable: true})
+		columns = append(columns, &sql.Column{Name: "description", Type: types.Text, Source: tableName, PrimaryKey: false, Nullable: true})
 	}
 	return columns
 }
@@ -106,10 +108,11 @@ func (bt *BranchesTable) PartitionRows(sqlCtx *sql.Context, part sql.Partition)
 
 // BranchItr is a sql.RowItr implementation which iterates over each commit as if it's a row in the table.
 type BranchItr struct {
-	table    *BranchesTable
-	branches []string
-	commits  []*doltdb.Commit
-	idx      int
+	table        *BranchesTable
+	branches     []string
+	commits      []*doltdb.Commit
+	descriptions map[string]string
+	idx          int
 }
 
 // NewBranchItr creates a BranchItr from the current environment.
@@ -138,6 +141,14 @@ func NewBranchItr(ctx *sql.Context, table *BranchesTable) (*BranchItr, error) {
 		}
 	}
 
+	// branch descriptions are optional, so a repo state that can't provide them is read as having none
+	var descriptions map[string]string
+	if describer, ok := db.DbData().Rsr.(env.BranchDescriber); ok && !remote {
+		if descriptions, err = describer.GetBranchDescriptions(); err != nil {
+			descriptions = nil
+		}
+	}
+
 	branchNames := make([]string, len(branchRefs))
 	commits := make([]*doltdb.Commit, len(branchRefs))
 	for i, branch := range branchRefs {
@@ -157,10 +168,11 @@ func NewBranchItr(ctx *sql.Context, table *BranchesTable) (*BranchItr, error) {
 	}
 
 	return &BranchItr{
-		table:    table,
-		branches: branchNames,
-		commits:  commits,
-		idx:      0,
+		table:        table,
+		branches:     branchNames,
+		commits:      commits,
+		descriptions: descriptions,
+		idx:          0,
 	}, nil
 }
 
@@ -206,7 +218,7 @@ func (itr *BranchItr) Next(ctx *sql.Context) (sql.Row, error) {
 			remoteName = branch.Remote
 			branchName = branch.Merge.Ref.GetPath()
 		}
-		return sql.NewRow(name, h.String(), meta.Name, meta.Email, meta.Time(), meta.Description, remoteName, branchName), nil
+		return sql.NewRow(name, h.String(), meta.Name, meta.Email, meta.Time(), meta.Description, remoteName, branchName, itr.descriptions[name]), nil
 	}
 }
 
@@ -20,6 +20,7 @@ import (
 	"strings"
 	"testing"
 
+	sqle "github.com/dolthub/go-mysql-server"
 	"github.com/dolthub/go-mysql-server/sql"
 	gmstypes "github.com/dolthub/go-mysql-server/sql/types"
 	"github.com/dolthub/vitess/go/sqltypes"
@@ -31,8 +32,10 @@ import (
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
 	"github.com/dolthub/dolt/go/libraries/doltcore/row"
 	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dtables"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlutil"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
 	"github.com/dolthub/dolt/go/store/types"
 )
 
@@ -1353,3 +1356,66 @@ func assertSucceeds(t *testing.T, dEnv *env.DoltEnv, query string) {
 	_, err := ExecuteSql(dEnv, root, query)
 	assert.NoError(t, err, query)
 }
+
+func TestSchemaChangeInvalidatesOtherSessionCaches(t *testing.T) {
+	dEnv := dtestutils.CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	tmpDir, err := dEnv.TempTableFilesDir()
+	require.NoError(t, err)
+	opts := editor.Options{Deaf: dEnv.DbEaFactory(), Tempdir: tmpDir}
+	db, err := NewDatabase(context.Background(), "dolt", dEnv.DbData(), opts)
+	require.NoError(t, err)
+	pro, err := NewDoltDatabaseProviderWithDatabase(env.GetDefaultInitBranch(dEnv.Config), dEnv.FS, db, dEnv.FS)
+	require.NoError(t, err)
+	engine := sqle.NewDefault(pro)
+
+	// ctx runs the schema changes, while otherCtx only reads from its cache
+	ctx := NewTestSQLCtxWithProvider(context.Background(), pro)
+	otherCtx := NewTestSQLCtxWithProvider(context.Background(), pro)
+	otherState, ok, err := dsess.DSessFromSess(otherCtx.Session).LookupDbState(otherCtx, db.RevisionQualifiedName())
+	require.NoError(t, err)
+	require.True(t, ok)
+	otherCache := otherState.SessionCache()
+
+	_, iter, err := engine.Query(ctx, "create table t (id int primary key, v int)")
+	require.NoError(t, err)
+	require.NoError(t, drainIter(ctx, iter))
+
+	tests := []struct {
+		query  string
+		tables []string
+	}{
+		{"alter table t add column w int", []string{"t"}},
+		{"alter table t modify column w bigint", []string{"t"}},
+		{"create index idx on t (v)", []string{"t"}},
+		{"alter table t add constraint chk check (v > 0)", []string{"t"}},
+		{"create table t2 (id int primary key)", []string{"t2"}},
+		{"rename table t to t3", []string{"t", "t3"}},
+		{"drop table t3", []string{"t3"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			root, err := db.GetRoot(ctx)
+			require.NoError(t, err)
+			key, err := doltdb.NewDataCacheKey(root)
+			require.NoError(t, err)
+
+			for _, table := range test.tables {
+				otherCache.CachePKOrdinals(key, table, []int{0})
+				_, ok := otherCache.GetCachedPKOrdinals(key, table)
+				require.True(t, ok)
+			}
+
+			_, iter, err := engine.Query(ctx, test.query)
+			require.NoError(t, err)
+			require.NoError(t, drainIter(ctx, iter))
+
+			for _, table := range test.tables {
+				_, ok := otherCache.GetCachedPKOrdinals(key, table)
+				assert.False(t, ok, "schema change must invalidate the other session's cache for %s", table)
+			}
+		})
+	}
+}
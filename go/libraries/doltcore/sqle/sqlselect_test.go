@@ -785,6 +785,7 @@ func BasicSelectTests() []SelectTest {
 					"Initialize data repository",
 					"",
 					"",
+					"",
 				},
 			},
 			ExpectedSqlSchema: sql.Schema{
@@ -796,6 +797,7 @@ func BasicSelectTests() []SelectTest {
 				&sql.Column{Name: "latest_commit_message", Type: gmstypes.Text},
 				&sql.Column{Name: "remote", Type: gmstypes.Text},
 				&sql.Column{Name: "branch", Type: gmstypes.Text},
+				&sql.Column{Name: "description", Type: gmstypes.Text},
 			},
 		},
 	}
@@ -254,6 +254,14 @@ func (t *DoltTable) GetIndexes(ctx *sql.Context) ([]sql.Index, error) {
 
 	indexes, ok := dbState.SessionCache().GetTableIndexesCache(key, t.Name())
 	if ok {
+		dsess.VerifyCachedIndexes(t.Name(), indexes, func() ([]sql.Index, bool, error) {
+			tbl, err := t.DoltTable(ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			indexes, err := index.DoltIndexesFromTable(ctx, t.db.Name(), t.tableName, tbl)
+			return indexes, true, err
+		})
 		return indexes, nil
 	}
 
@@ -321,6 +329,45 @@ func (t *DoltTable) DebugString() string {
 
 // NumRows returns the unfiltered count of rows contained in the table
 func (t *DoltTable) numRows(ctx *sql.Context) (uint64, error) {
+	key, tableIsCacheable, err := t.DataCacheKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if !tableIsCacheable {
+		return t.countRows(ctx)
+	}
+
+	sess := dsess.DSessFromSess(ctx.Session)
+	dbState, ok, err := sess.LookupDbState(ctx, t.db.RevisionQualifiedName())
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		return 0, fmt.Errorf("couldn't find db state for database %s", t.db.Name())
+	}
+
+	count, ok := dbState.SessionCache().GetRowCountCache(key, t.Name())
+	if ok {
+		dsess.VerifyCachedRowCount(t.Name(), count, func() (uint64, bool, error) {
+			count, err := t.countRows(ctx)
+			return count, true, err
+		})
+		return count, nil
+	}
+
+	count, err = t.countRows(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	dbState.SessionCache().CacheRowCount(key, t.Name(), count)
+	return count, nil
+}
+
+// countRows returns the unfiltered count of rows contained in the table, read from storage
+func (t *DoltTable) countRows(ctx *sql.Context) (uint64, error) {
 	table, err := t.DoltTable(ctx)
 	if err != nil {
 		return 0, err
@@ -478,8 +525,10 @@ type doltTableInterface interface {
 	sql.ProjectedTable
 }
 
+// setRoot sets the working root after a change to this table's schema or data, notifying other sessions that their
+// cached metadata for this table at the root being replaced is dirty.
 func (t *WritableDoltTable) setRoot(ctx *sql.Context, newRoot *doltdb.RootValue) error {
-	return t.db.SetRoot(ctx, newRoot)
+	return t.db.setRootForSchemaChange(ctx, newRoot, t.tableName)
 }
 
 func (t *WritableDoltTable) IndexedAccess(lookup sql.IndexLookup) sql.IndexedTable {
@@ -515,6 +564,10 @@ func (t *WritableDoltTable) getTableEditor(ctx *sql.Context) (ed writer.TableWri
 		return nil, err
 	}
 
+	if err = t.invalidateCachedAutoIncrement(ctx, state); err != nil {
+		return nil, err
+	}
+
 	setter := ds.SetRoot
 	ed, err = state.WriteSession().GetTableWriter(ctx, t.tableName, t.db.RevisionQualifiedName(), setter)
 
@@ -525,6 +578,20 @@ func (t *WritableDoltTable) getTableEditor(ctx *sql.Context) (ed writer.TableWri
 	return ed, nil
 }
 
+// invalidateCachedAutoIncrement removes the table's cached auto increment value. Writes advance the value, so the
+// cached one must not be used once they start, and this must be called before every write. See
+// dsess.SessionCache.CacheAutoIncrement.
+func (t *WritableDoltTable) invalidateCachedAutoIncrement(ctx *sql.Context, state dsess.SessionState) error {
+	key, cacheable, err := t.DataCacheKey(ctx)
+	if err != nil {
+		return err
+	}
+	if cacheable {
+		state.SessionCache().InvalidateAutoIncrement(key, t.tableName)
+	}
+	return nil
+}
+
 // Deleter implements sql.DeletableTable
 func (t *WritableDoltTable) Deleter(ctx *sql.Context) sql.RowDeleter {
 	if err := dsess.CheckAccessForDb(ctx, t.db, branch_control.Permissions_Write); err != nil {
@@ -575,6 +642,15 @@ func (t *WritableDoltTable) Truncate(ctx *sql.Context) (int, error) {
 	numOfRows := int(c)
 
 	sess := dsess.DSessFromSess(ctx.Session)
+	state, _, err := sess.LookupDbState(ctx, t.db.RevisionQualifiedName())
+	if err != nil {
+		return 0, err
+	}
+	// truncating resets the auto increment value
+	if err = t.invalidateCachedAutoIncrement(ctx, state); err != nil {
+		return 0, err
+	}
+
 	newTable, err := t.truncate(ctx, table, sch, sess)
 	if err != nil {
 		return 0, err
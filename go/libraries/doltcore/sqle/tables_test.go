@@ -15,12 +15,68 @@
 package sqle
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
 )
 
 func TestMinRowsPerPartitionInTests(t *testing.T) {
 	// If this fails then the method for determining if we are running in a test doesn't work all the time.
 	assert.Equal(t, uint64(2), MinRowsPerPartition)
 }
+
+func TestWriteInvalidatesCachedAutoIncrement(t *testing.T) {
+	dEnv := CreateTestEnv()
+	defer dEnv.DoltDB.Close()
+
+	tmpDir, err := dEnv.TempTableFilesDir()
+	require.NoError(t, err)
+	opts := editor.Options{Deaf: dEnv.DbEaFactory(), Tempdir: tmpDir}
+	db, err := NewDatabase(context.Background(), "dolt", dEnv.DbData(), opts)
+	require.NoError(t, err)
+	engine, ctx, err := NewTestEngine(dEnv, context.Background(), db)
+	require.NoError(t, err)
+
+	query := func(q string) {
+		_, iter, err := engine.Query(ctx, q)
+		require.NoError(t, err)
+		require.NoError(t, drainIter(ctx, iter))
+	}
+	query("create table t (id int primary key auto_increment, v int)")
+
+	tbl, ok, err := db.GetTableInsensitive(ctx, "t")
+	require.NoError(t, err)
+	require.True(t, ok)
+	key, cacheable, err := tbl.(*AlterableDoltTable).DataCacheKey(ctx)
+	require.NoError(t, err)
+	require.True(t, cacheable)
+
+	state, ok, err := dsess.DSessFromSess(ctx.Session).LookupDbState(ctx, db.RevisionQualifiedName())
+	require.NoError(t, err)
+	require.True(t, ok)
+	cache := state.SessionCache()
+	cache.CacheAutoIncrement(key, "t", 1)
+	_, ok = cache.GetCachedAutoIncrement(key, "T")
+	require.True(t, ok)
+
+	query("insert into t (v) values (1)")
+	_, ok = cache.GetCachedAutoIncrement(key, "t")
+	assert.False(t, ok, "a write must invalidate the cached auto increment value")
+
+	tbl, ok, err = db.GetTableInsensitive(ctx, "t")
+	require.NoError(t, err)
+	require.True(t, ok)
+	key, cacheable, err = tbl.(*AlterableDoltTable).DataCacheKey(ctx)
+	require.NoError(t, err)
+	require.True(t, cacheable)
+	cache.CacheAutoIncrement(key, "t", 2)
+
+	query("truncate table t")
+	_, ok = cache.GetCachedAutoIncrement(key, "t")
+	assert.False(t, ok, "truncating must invalidate the cached auto increment value")
+}
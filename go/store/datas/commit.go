@@ -341,6 +341,9 @@ func LoadCommitAddr(ctx context.Context, vr types.ValueReader, addr hash.Hash) (
 func findCommonAncestorUsingParentsList(ctx context.Context, c1, c2 *Commit, vr1, vr2 types.ValueReader, ns1, ns2 tree.NodeStore) (hash.Hash, bool, error) {
 	c1Q, c2Q := CommitByHeightHeap{c1}, CommitByHeightHeap{c2}
 	for !c1Q.Empty() && !c2Q.Empty() {
+		if err := ctx.Err(); err != nil {
+			return hash.Hash{}, false, err
+		}
 		c1Ht, c2Ht := c1Q.MaxHeight(), c2Q.MaxHeight()
 		if c1Ht == c2Ht {
 			c1Parents, c2Parents := c1Q.PopCommitsOfHeight(c1Ht), c2Q.PopCommitsOfHeight(c2Ht)
@@ -379,6 +382,9 @@ func findCommonAncestorUsingParentsList(ctx context.Context, c1, c2 *Commit, vr1
 // This implementation makes use of the parents_closure field on the commit
 // struct.  If the commit does not have a materialized parents_closure, this
 // implementation delegates to findCommonAncestorUsingParentsList.
+//
+// Both implementations check |ctx| as they walk history and return its error
+// if it is canceled.
 func FindCommonAncestor(ctx context.Context, c1, c2 *Commit, vr1, vr2 types.ValueReader, ns1, ns2 tree.NodeStore) (hash.Hash, bool, error) {
 	pi1, err := newParentsClosureIterator(ctx, c1, vr1, ns1)
 	if err != nil {
@@ -397,6 +403,9 @@ func FindCommonAncestor(ctx context.Context, c1, c2 *Commit, vr1, vr2 types.Valu
 	}
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return hash.Hash{}, false, err
+		}
 		h1, h2 := pi1.Hash(), pi2.Hash()
 		if h1 == h2 {
 			if err := firstError(pi1.Err(), pi2.Err()); err != nil {
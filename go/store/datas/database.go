@@ -127,6 +127,13 @@ type Database interface {
 	// Delete returns an 'ErrMergeNeeded' error.
 	Delete(ctx context.Context, ds Dataset) (Dataset, error)
 
+	// DeleteDatasets removes every Dataset named in |heads| from the map at
+	// the root of the Database in a single update, so either all of them are
+	// removed or none are. Each Dataset must still point at the address
+	// given for it in |heads|, or none is removed and 'ErrMergeNeeded' is
+	// returned.
+	DeleteDatasets(ctx context.Context, heads map[string]hash.Hash) error
+
 	// SetHead ignores any lineage constraints (e.g. the current head being
 	// an ancestor of the new Commit) and force-sets a mapping from
 	// datasetID: addr in this database. addr can point to a Commit or a
@@ -890,6 +890,37 @@ func (db *database) doDelete(ctx context.Context, datasetIDstr string) error {
 	})
 }
 
+func (db *database) DeleteDatasets(ctx context.Context, heads map[string]hash.Hash) error {
+	return db.update(ctx, func(ctx context.Context, datasets types.Map) (types.Map, error) {
+		edit := datasets.Edit()
+		for id, head := range heads {
+			curr, ok, err := datasets.MaybeGet(ctx, types.String(id))
+			if err != nil {
+				return types.Map{}, err
+			} else if !ok || curr.(types.Ref).TargetHash() != head {
+				return types.Map{}, ErrMergeNeeded
+			}
+			edit.Remove(types.String(id))
+		}
+		return edit.Map(ctx)
+	}, func(ctx context.Context, am prolly.AddressMap) (prolly.AddressMap, error) {
+		ae := am.Editor()
+		for id, head := range heads {
+			curr, err := am.Get(ctx, id)
+			if err != nil {
+				return prolly.AddressMap{}, err
+			} else if curr == (hash.Hash{}) || curr != head {
+				return prolly.AddressMap{}, ErrMergeNeeded
+			}
+			err = ae.Delete(ctx, id)
+			if err != nil {
+				return prolly.AddressMap{}, err
+			}
+		}
+		return ae.Flush(ctx)
+	})
+}
+
 // GC traverses the database starting at the Root and removes all unreferenced data from persistent storage.
 func (db *database) GC(ctx context.Context, oldGenRefs, newGenRefs hash.HashSet, safepointF func() error) error {
 	return db.ValueStore.GC(ctx, oldGenRefs, newGenRefs, safepointF)
@@ -412,6 +412,40 @@ func (suite *DatabaseSuite) TestDatabaseDelete() {
 	suite.True(present, "Dataset %s should be present", datasetID2)
 }
 
+func (suite *DatabaseSuite) TestDatabaseDeleteDatasets() {
+	ctx := context.Background()
+	heads := make(map[string]hash.Hash)
+	for _, id := range []string{"ds1", "ds2", "ds3"} {
+		ds, err := suite.db.GetDataset(ctx, id)
+		suite.Require().NoError(err)
+		ds, err = CommitValue(ctx, suite.db, ds, types.String(id))
+		suite.Require().NoError(err)
+		heads[id], _ = ds.MaybeHeadAddr()
+	}
+
+	// a head that doesn't match leaves every dataset in place
+	err := suite.db.DeleteDatasets(ctx, map[string]hash.Hash{"ds1": heads["ds1"], "ds2": heads["ds1"]})
+	suite.Equal(ErrMergeNeeded, err)
+	err = suite.db.DeleteDatasets(ctx, map[string]hash.Hash{"ds1": heads["ds1"], "missing": heads["ds1"]})
+	suite.Equal(ErrMergeNeeded, err)
+	datasets, err := suite.db.Datasets(ctx)
+	suite.Require().NoError(err)
+	l, err := datasets.Len()
+	suite.NoError(err)
+	suite.Equal(uint64(3), l)
+
+	err = suite.db.DeleteDatasets(ctx, map[string]hash.Hash{"ds1": heads["ds1"], "ds2": heads["ds2"]})
+	suite.NoError(err)
+	for _, id := range []string{"ds1", "ds2"} {
+		ds, err := suite.db.GetDataset(ctx, id)
+		suite.NoError(err)
+		suite.False(ds.HasHead(), "Dataset %s should not be present", id)
+	}
+	ds3, err := suite.db.GetDataset(ctx, "ds3")
+	suite.NoError(err)
+	suite.True(ds3.HasHead(), "Dataset ds3 should be present")
+}
+
 func (suite *DatabaseSuite) TestCommitWithConcurrentChunkStoreUse() {
 	datasetID := "ds1"
 	ds1, err := suite.db.GetDataset(context.Background(), datasetID)
@@ -143,4 +143,75 @@ teardown() {
     run dolt branch -D main
     [ "$status" -ne 0 ]
     [[ "$output" =~ "Cannot delete checked out branch 'main'" ]] || false
-}
\ No newline at end of file
+}
+
+@test "branch: --edit-description sets a description that follows the branch" {
+    cat > "$BATS_TMPDIR/describe-branch.sh" <<'SCRIPT'
+#!/bin/sh
+echo "adds the widgets table" > "$1"
+SCRIPT
+    chmod +x "$BATS_TMPDIR/describe-branch.sh"
+    export EDITOR="$BATS_TMPDIR/describe-branch.sh"
+    export DOLT_TEST_FORCE_OPEN_EDITOR="1"
+
+    dolt branch b1
+    dolt branch --edit-description b1
+    run dolt sql -r csv -q "select name, description from dolt_branches where name = 'b1'"
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "b1,adds the widgets table" ]] || false
+
+    dolt branch -m b1 b2
+    run dolt sql -r csv -q "select name, description from dolt_branches where description != ''"
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "b2,adds the widgets table" ]] || false
+    [[ ! "$output" =~ "b1" ]] || false
+
+    dolt branch -d b2
+    dolt branch b2
+    run dolt sql -r csv -q "select name, description from dolt_branches where name = 'b2'"
+    [ "$status" -eq 0 ]
+    [[ ! "$output" =~ "widgets" ]] || false
+
+    run dolt branch --edit-description nonexistent
+    [ "$status" -ne 0 ]
+    [[ "$output" =~ "branch 'nonexistent' not found" ]] || false
+}
+
+@test "branch: --prune-stale deletes merged branches older than their expiry age" {
+    run dolt branch --prune-stale
+    [ "$status" -ne 0 ]
+    [[ "$output" =~ "no branch expiry age is configured" ]] || false
+
+    dolt checkout -b old-merged
+    dolt commit --allow-empty -m "old merged commit" --date "2020-01-01T00:00:00Z"
+    dolt checkout main
+    dolt merge old-merged
+    dolt checkout -b old-unmerged
+    dolt commit --allow-empty -m "old unmerged commit" --date "2020-01-01T00:00:00Z"
+    dolt checkout main
+    dolt branch old-kept old-merged
+    dolt branch old-sql old-merged
+    dolt commit --allow-empty -m "recent commit"
+    dolt branch recent
+
+    dolt config --local --add branch.expireafter 30d
+    dolt config --local --add branch.old-kept.expireafter never
+    dolt config --local --add branch.old-sql.expireafter never
+    run dolt branch --prune-stale
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "Deleted stale branch old-merged" ]] || false
+    [[ ! "$output" =~ "old-unmerged" ]] || false
+
+    run dolt branch
+    [ "$status" -eq 0 ]
+    [[ ! "$output" =~ "old-merged" ]] || false
+    [[ "$output" =~ "old-unmerged" ]] || false
+    [[ "$output" =~ "old-kept" ]] || false
+    [[ "$output" =~ "recent" ]] || false
+
+    dolt config --local --unset branch.old-sql.expireafter
+    run dolt sql -r csv -q "call dolt_branch_prune()"
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "old-sql" ]] || false
+    [[ ! "$output" =~ "old-kept" ]] || false
+}
@@ -56,6 +56,7 @@ export const branchTests = [
         latest_commit_message: "Initialize data repository",
         remote: "",
         branch: "",
+        description: "",
       },
       {
         name: "mybranch",
@@ -66,6 +67,7 @@ export const branchTests = [
         latest_commit_message: "Create table test",
         remote: "",
         branch: "",
+        description: "",
       },
     ],
     matcher: branchesMatcher,